@@ -0,0 +1,43 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"net/url"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Article is the main content of the page reported by the "article" extractor.
+type Article struct {
+	Text      string `json:"text"`
+	HTML      string `json:"html"`
+	Byline    string `json:"byline,omitempty"`
+	Published string `json:"published,omitempty"`
+	Image     string `json:"image,omitempty"`
+}
+
+// newArticle converts the main content to its JSON representation,
+// the lead image is resolved against the URL of the page.
+func newArticle(a *inspect.Article, base *url.URL) *Article {
+	if a == nil {
+		return nil
+	}
+
+	out := &Article{
+		Text:      a.Text,
+		HTML:      a.HTML,
+		Byline:    a.Byline,
+		Published: a.Published,
+		Image:     a.Image,
+	}
+
+	if out.Image != "" {
+		if u, err := base.Parse(out.Image); err == nil {
+			out.Image = u.String()
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,277 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Error codes reported for the URLs of a batch that can't be inspected
+// for other reasons than the ones reported by the single page endpoint.
+const (
+	ErrCodeInvalidURL = "invalid_url"
+	ErrCodeFetch      = "fetch_failed"
+	ErrCodeParse      = "parse_failed"
+)
+
+// Batch limits
+const (
+	// MaxBatchURLs is the maximum number of URLs of a single batch.
+	MaxBatchURLs = 1000
+
+	// DefaultBatchParallelism is the default number of pages inspected concurrently.
+	DefaultBatchParallelism = 8
+
+	// DefaultMaxBatchParallelism is the default number of pages inspected
+	// concurrently across all batches.
+	DefaultMaxBatchParallelism = 32
+)
+
+type BatchRequest struct {
+	URLs []string `json:"urls"`
+
+	// Maximum number of pages inspected concurrently, DefaultBatchParallelism if 0.
+	// It is capped by the server-wide limit.
+	Parallelism int `json:"parallelism"`
+
+	InspectOptions
+	FetchSettings
+}
+
+type BatchResponse struct {
+	Results map[string]*ParseHTMLResponse `json:"results"`
+	Errors  map[string]*PageError         `json:"errors"`
+}
+
+// inspectBatch returns a handler post spec. At most maxParallelism pages
+// are inspected concurrently across all batches.
+func inspectBatch(maxParallelism int) http.HandlerFunc {
+	if maxParallelism <= 0 {
+		maxParallelism = DefaultMaxBatchParallelism
+	}
+
+	slots := make(chan struct{}, maxParallelism)
+
+	// This method will extract general information from every HTML page of
+	// the batch. The URLs are read from a JSON payload or from a text/plain
+	// body with one URL per line, in which case the options are read from
+	// the query parameters.
+	//
+	// Responses:
+	//	200: BatchResponse.
+	//	400: Invalid Request payload.
+	//	500: Server failure.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := BatchRequest{}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type")); mediaType == "text/plain" {
+			if err := decodeBatchUpload(r, &payload); err != nil {
+				log.Printf("failed to read batch upload: %v", err)
+				JSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		urls := dedupe(payload.URLs)
+
+		if len(urls) == 0 {
+			log.Printf("no URLs in payload")
+			JSONError(w, "no URLs in payload", http.StatusBadRequest)
+			return
+		}
+
+		if len(urls) > MaxBatchURLs {
+			JSONError(w, fmt.Sprintf("batch exceeds the maximum of %v URLs", MaxBatchURLs), http.StatusBadRequest)
+			return
+		}
+
+		if err := payload.validate(); err != nil {
+			log.Printf("invalid inspect options: %v", err)
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		parallelism := payload.Parallelism
+		if parallelism <= 0 {
+			parallelism = DefaultBatchParallelism
+		}
+
+		if parallelism > maxParallelism {
+			parallelism = maxParallelism
+		}
+
+		var (
+			out = BatchResponse{
+				Results: make(map[string]*ParseHTMLResponse),
+				Errors:  make(map[string]*PageError),
+			}
+
+			fetcher = payload.fetcher()
+			sem     = make(chan struct{}, parallelism)
+			mu      = new(sync.Mutex)
+			wg      = new(sync.WaitGroup)
+
+			// URLs not started before the request was canceled.
+			skipped []string
+			skipErr error
+		)
+
+		for i, link := range urls {
+			if err := acquire(r.Context(), sem); err != nil {
+				skipped, skipErr = urls[i:], err
+				break
+			}
+
+			if err := acquire(r.Context(), slots); err != nil {
+				<-sem
+				skipped, skipErr = urls[i:], err
+				break
+			}
+
+			wg.Add(1)
+
+			go func(link string) {
+				defer wg.Done()
+				defer func() { <-slots; <-sem }()
+
+				result, err := inspectURL(r.Context(), link, payload.InspectOptions, fetcher, nil)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					log.Printf("failed to inspect url:%v: %v", link, err)
+					out.Errors[link] = err
+					return
+				}
+
+				out.Results[link] = result
+			}(link)
+		}
+
+		wg.Wait()
+
+		for _, link := range skipped {
+			out.Errors[link] = &PageError{Err: skipErr.Error(), Code: ErrCodeFetch}
+		}
+
+		JSON(w, &out, http.StatusOK)
+	}
+}
+
+// acquire takes a slot of the semaphore unless the context is done first.
+func acquire(ctx context.Context, sem chan struct{}) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case sem <- struct{}{}:
+		return nil
+	}
+}
+
+// inspectURL inspects a single URL of a batch or a job. Every failure
+// is reported as a *PageError so it can be keyed by the URL.
+func inspectURL(ctx context.Context, link string, opts InspectOptions, f *inspect.Fetcher, progress inspect.LinkProgress) (*ParseHTMLResponse, *PageError) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, &PageError{Err: fmt.Sprintf("invalid URL %q", link), Code: ErrCodeInvalidURL}
+	}
+
+	out, err := inspectPage(ctx, u, opts, f, progress)
+	if err == nil {
+		return out, nil
+	}
+
+	var (
+		pageErr  *PageError
+		parseErr *parseError
+	)
+
+	switch {
+	case errors.As(err, &pageErr):
+		return nil, pageErr
+	case errors.As(err, &parseErr):
+		return nil, &PageError{Err: err.Error(), Code: ErrCodeParse}
+	default:
+		return nil, &PageError{Err: err.Error(), Code: ErrCodeFetch}
+	}
+}
+
+// decodeBatchUpload reads the URLs of a newline-delimited upload, skipping
+// blank lines and lines starting with #. The options are decoded from the
+// query parameters, whose values are interpreted as JSON if possible.
+func decodeBatchUpload(r *http.Request, payload *BatchRequest) error {
+	options := make(map[string]json.RawMessage)
+
+	for key, values := range r.URL.Query() {
+		v := values[len(values)-1]
+
+		if json.Valid([]byte(v)) {
+			options[key] = json.RawMessage(v)
+			continue
+		}
+
+		quoted, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		options[key] = quoted
+	}
+
+	delete(options, "urls")
+
+	b, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, payload); err != nil {
+		return fmt.Errorf("invalid query parameters: %w", err)
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			payload.URLs = append(payload.URLs, line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// dedupe removes the blank and repeated values keeping the order of the first occurrences.
+func dedupe(values []string) []string {
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInspectBatch(t *testing.T) {
+	externalMockServer := mockExternalServer()
+	defer externalMockServer.Close()
+
+	mockServer := httptest.NewServer(inspectBatch(DefaultMaxBatchParallelism))
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name           string
+		Query          string
+		Body           string
+		ContentType    string
+		wantStatusCode int
+		wantBody       string
+
+		// Titles of the inspected pages keyed by the URL.
+		wantTitles map[string]string
+		wantErrors map[string]*PageError
+	}{
+		{
+			Name:           "fail-invalid-content-type",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"Invalid content-type"}`,
+		},
+		{
+			Name:           "fail-empty",
+			Body:           `{"urls": [" ", ""]}`,
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"no URLs in payload"}`,
+		},
+		{
+			Name:           "fail-invalid-query",
+			Query:          "?parallelism=many",
+			Body:           externalMockServer.URL + "/blog",
+			ContentType:    "text/plain",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"invalid query parameters: json: cannot unmarshal string into Go struct field BatchRequest.parallelism of type int"}`,
+		},
+		{
+			Name: "ok",
+			Body: fmt.Sprintf(`{"urls": ["%[1]v/blog", "%[1]v/app", "%[1]v/blog", "%[1]v/private", "%[1]v/document.pdf", "%[1]v/missing", "mailto:someone@example.com"], "parallelism": 2}`,
+				externalMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusOK,
+			wantTitles: map[string]string{
+				externalMockServer.URL + "/blog": "Blog",
+				externalMockServer.URL + "/app":  "App",
+			},
+			wantErrors: map[string]*PageError{
+				externalMockServer.URL + "/private": {
+					Err:  `target is disallowed by robots.txt for user-agent "htmlinspect"`,
+					Code: ErrCodeDisallowed,
+				},
+				externalMockServer.URL + "/document.pdf": {
+					Err:         "target is not a HTML page: application/pdf",
+					Code:        ErrCodeNotHTML,
+					StatusCode:  http.StatusOK,
+					ContentType: "application/pdf",
+				},
+				externalMockServer.URL + "/missing": {
+					Err:        "target responded with code: 404",
+					Code:       ErrCodeHTTPStatus,
+					StatusCode: http.StatusNotFound,
+				},
+				"mailto:someone@example.com": {
+					Err:  `invalid URL "mailto:someone@example.com"`,
+					Code: ErrCodeInvalidURL,
+				},
+			},
+		},
+		{
+			Name:           "ok-upload",
+			Query:          "?ignore_robots=true&parallelism=1&user_agent=batch",
+			Body:           fmt.Sprintf("# pages to inspect\n%[1]v/private\n\n  %[1]v/missing  \n%[1]v/private\n", externalMockServer.URL),
+			ContentType:    "text/plain; charset=utf-8",
+			wantStatusCode: http.StatusOK,
+			wantTitles: map[string]string{
+				externalMockServer.URL + "/private": "Private",
+			},
+			wantErrors: map[string]*PageError{
+				externalMockServer.URL + "/missing": {
+					Err:        "target responded with code: 404",
+					Code:       ErrCodeHTTPStatus,
+					StatusCode: http.StatusNotFound,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, mockServer.URL+tt.Query, strings.NewReader(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.ContentType != "" {
+				req.Header.Set("content-type", tt.ContentType)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("inspectBatch() status code = %v, want: %v", resp.StatusCode, tt.wantStatusCode)
+				return
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantBody != "" {
+				if diff := cmp.Diff(string(b), tt.wantBody); diff != "" {
+					t.Error(diff)
+				}
+
+				return
+			}
+
+			have := new(BatchResponse)
+			if err := json.Unmarshal(b, have); err != nil {
+				t.Fatal(err)
+			}
+
+			titles := make(map[string]string)
+			for link, result := range have.Results {
+				titles[link] = result.Title
+			}
+
+			if diff := cmp.Diff(titles, tt.wantTitles); diff != "" {
+				t.Error(diff)
+			}
+
+			if diff := cmp.Diff(have.Errors, tt.wantErrors); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+type (
+	CrawledPage struct {
+		URL           string    `json:"url"`
+		Depth         int       `json:"depth"`
+		StatusCode    int       `json:"status_code"`
+		Version       string    `json:"version,omitempty"`
+		Title         string    `json:"title,omitempty"`
+		LoginForm     bool      `json:"login_form"`
+		Headings      []Heading `json:"headings"`
+		InternalLinks int       `json:"internal_links"`
+		ExternalLinks int       `json:"external_links"`
+		Truncated     bool      `json:"truncated,omitempty"`
+		Error         string    `json:"error,omitempty"`
+	}
+
+	CrawlSummary struct {
+		Pages         int            `json:"pages"`
+		Errors        int            `json:"errors"`
+		StatusCodes   map[int]int    `json:"status_codes"`
+		Versions      map[string]int `json:"versions"`
+		InternalLinks int            `json:"internal_links"`
+		ExternalLinks int            `json:"external_links"`
+		MaxDepth      int            `json:"max_depth"`
+		Truncated     bool           `json:"truncated"`
+	}
+
+	SitemapReport struct {
+		Sitemaps       []SitemapInfo  `json:"sitemaps"`
+		URLs           int            `json:"urls"`
+		Unchecked      int            `json:"unchecked"`
+		Broken         []SitemapIssue `json:"broken"`
+		Missing        []string       `json:"missing"`
+		InvalidLastMod []SitemapIssue `json:"invalid_lastmod"`
+		NonCanonical   []SitemapIssue `json:"non_canonical"`
+	}
+
+	SitemapInfo struct {
+		URL      string `json:"url"`
+		Index    bool   `json:"index"`
+		URLs     int    `json:"urls"`
+		Sitemaps int    `json:"sitemaps"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	LinkMetrics struct {
+		Pages    []PageLinkMetrics `json:"pages"`
+		Orphans  []string          `json:"orphans"`
+		DeadEnds []string          `json:"dead_ends"`
+	}
+
+	PageLinkMetrics struct {
+		URL           string  `json:"url"`
+		Inlinks       int     `json:"inlinks"`
+		UniqueInlinks int     `json:"unique_inlinks"`
+		Outlinks      int     `json:"outlinks"`
+		ClickDepth    int     `json:"click_depth"`
+		PageRank      float64 `json:"pagerank"`
+	}
+
+	PageFindings struct {
+		URL      string    `json:"url"`
+		Findings []Finding `json:"findings"`
+	}
+
+	SitemapIssue struct {
+		URL     string `json:"url"`
+		Sitemap string `json:"sitemap"`
+		Reason  string `json:"reason"`
+	}
+)
+
+// Crawl limits
+const (
+	// MaxCrawlPages is the maximum number of pages of a single crawl.
+	MaxCrawlPages = 1000
+
+	// DefaultCrawlConcurrency is the default number of concurrent requests of a crawl.
+	DefaultCrawlConcurrency = 4
+
+	// MaxCrawlConcurrency is the maximum number of concurrent requests of a crawl.
+	MaxCrawlConcurrency = 16
+)
+
+// StatusClientClosedRequest is reported when the client went away before the
+// response was written.
+const StatusClientClosedRequest = 499
+
+type CrawlRequest struct {
+	URL string `json:"url"`
+
+	// Maximum number of links followed from the seed.
+	MaxDepth int `json:"max_depth"`
+
+	// Maximum number of pages fetched, at most MaxCrawlPages.
+	MaxPages int `json:"max_pages"`
+
+	// Regular expressions of the URLs that are followed.
+	Include []string `json:"include"`
+
+	// Regular expressions of the URLs that are not followed.
+	Exclude []string `json:"exclude"`
+
+	// CheckSitemaps cross-checks the sitemaps of the site against the crawled pages.
+	CheckSitemaps bool `json:"check_sitemaps"`
+
+	// LinkMetrics computes the internal link metrics of the crawled pages.
+	LinkMetrics bool `json:"link_metrics"`
+
+	// CheckLinkElements validates the canonical and hreflang link elements of the crawled pages.
+	CheckLinkElements bool `json:"check_link_elements"`
+
+	FetchSettings
+}
+
+type CrawlResponse struct {
+	Seed    string        `json:"seed"`
+	Summary CrawlSummary  `json:"summary"`
+	Pages   []CrawledPage `json:"pages"`
+
+	Sitemaps    *SitemapReport `json:"sitemaps,omitempty"`
+	LinkMetrics *LinkMetrics   `json:"link_metrics,omitempty"`
+
+	LinkElements []PageFindings `json:"link_elements,omitempty"`
+}
+
+// fetcher creates an *inspect.Fetcher from the fetch settings of the crawl.
+// The concurrency defaults to DefaultCrawlConcurrency and is capped by
+// MaxCrawlConcurrency.
+func (c *CrawlRequest) fetcher() *inspect.Fetcher {
+	settings := c.FetchSettings
+
+	switch {
+	case settings.Concurrency <= 0:
+		settings.Concurrency = DefaultCrawlConcurrency
+	case settings.Concurrency > MaxCrawlConcurrency:
+		settings.Concurrency = MaxCrawlConcurrency
+	}
+
+	return settings.fetcher()
+}
+
+// crawlSite returns a handler post spec.
+func crawlSite() http.HandlerFunc {
+	// This method will crawl the site from the given seed URL and extract
+	// general information from every visited page.
+	//
+	// Responses:
+	//	200: CrawlResponse.
+	//	400: Invalid Request payload.
+	//	499: Client closed the request.
+	//	500: Server failure.
+	//	504: Crawl timed out.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := CrawlRequest{}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		fetcher := payload.fetcher()
+
+		site, ok := crawl(w, r, &payload, fetcher)
+		if !ok {
+			return
+		}
+
+		out := CrawlResponse{
+			Seed:    site.Seed,
+			Summary: newCrawlSummary(site.Summary()),
+		}
+
+		for _, page := range site.Pages {
+			out.Pages = append(out.Pages, newCrawledPage(page))
+		}
+
+		var sitemaps []*inspect.Sitemap
+
+		if payload.CheckSitemaps {
+			report := site.CheckSitemaps(r.Context(), fetcher)
+			out.Sitemaps, sitemaps = newSitemapReport(report), report.Sitemaps
+		}
+
+		if payload.LinkMetrics {
+			if !payload.CheckSitemaps {
+				if seed, err := url.Parse(site.Seed); err == nil {
+					sitemaps = inspect.FetchSitemaps(r.Context(), fetcher, *seed)
+				}
+			}
+
+			out.LinkMetrics = newLinkMetrics(site.LinkAnalytics(sitemaps))
+		}
+
+		if payload.CheckLinkElements {
+			for _, page := range site.CheckLinkElements(r.Context(), fetcher) {
+				out.LinkElements = append(out.LinkElements, PageFindings{
+					URL:      page.URL,
+					Findings: newFindings(page.Findings),
+				})
+			}
+		}
+
+		JSON(w, &out, http.StatusOK)
+	}
+}
+
+// crawl validates the crawl payload and crawls the site through the fetcher.
+// On failure the error response is written and false is returned. Failed pages
+// are reported by the site, the crawl itself only fails if the request is
+// canceled or times out.
+func crawl(w http.ResponseWriter, r *http.Request, payload *CrawlRequest, f *inspect.Fetcher) (*inspect.Site, bool) {
+	if payload.URL == "" {
+		log.Printf("empty URL in payload")
+		JSONError(w, "empty URL in payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	u, err := url.Parse(payload.URL)
+	if err != nil {
+		log.Printf("failed to parse url")
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		log.Printf("unsupported seed url: %v", payload.URL)
+		JSONError(w, fmt.Sprintf("unsupported seed URL %q", payload.URL), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if payload.MaxPages > MaxCrawlPages {
+		JSONError(w, fmt.Sprintf("crawl exceeds the maximum of %v pages", MaxCrawlPages), http.StatusBadRequest)
+		return nil, false
+	}
+
+	opts := inspect.CrawlOptions{
+		MaxDepth:    payload.MaxDepth,
+		MaxPages:    payload.MaxPages,
+		MaxPageSize: inspect.DefaultMaxPageSize,
+		Parallelism: f.Options().Concurrency,
+	}
+
+	if opts.Include, err = compilePatterns(payload.Include); err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if opts.Exclude, err = compilePatterns(payload.Exclude); err != nil {
+		JSONError(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	site, err := inspect.Crawl(r.Context(), *u, f, opts)
+	if err != nil {
+		log.Printf("failed to crawl site for url:%v: %v", payload.URL, err)
+
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, context.Canceled):
+			status = StatusClientClosedRequest
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+
+		JSONError(w, err.Error(), status)
+		return nil, false
+	}
+
+	return site, true
+}
+
+// compilePatterns compiles the regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, re)
+	}
+
+	return out, nil
+}
+
+// newCrawledPage converts the crawled page to its JSON representation.
+func newCrawledPage(page *inspect.CrawledPage) CrawledPage {
+	out := CrawledPage{
+		URL:           page.URL,
+		Depth:         page.Depth,
+		StatusCode:    page.StatusCode,
+		InternalLinks: len(page.Links),
+		Error:         page.Error,
+	}
+
+	if page.Contents == nil {
+		return out
+	}
+
+	out.Version = page.Contents.Version
+	out.Title = page.Contents.Title
+	out.LoginForm = page.Contents.LoginForm
+	out.Truncated = page.Contents.Truncated
+
+	for level, count := range page.Contents.Headings {
+		out.Headings = append(out.Headings, Heading{
+			Level: level,
+			Total: count,
+		})
+	}
+
+	sort.Slice(out.Headings, func(i, j int) bool { return out.Headings[i].Level < out.Headings[j].Level })
+
+	if u, err := url.Parse(page.FinalURL); err == nil {
+		for domain, links := range page.Contents.Links {
+			if domain != "" && !strings.EqualFold(domain, u.Hostname()) {
+				out.ExternalLinks += len(links)
+			}
+		}
+	}
+
+	return out
+}
+
+// newCrawlSummary converts the summary of the crawl to its JSON representation.
+func newCrawlSummary(summary inspect.SiteSummary) CrawlSummary {
+	return CrawlSummary{
+		Pages:         summary.Pages,
+		Errors:        summary.Errors,
+		StatusCodes:   summary.StatusCodes,
+		Versions:      summary.Versions,
+		InternalLinks: summary.InternalLinks,
+		ExternalLinks: summary.ExternalLinks,
+		MaxDepth:      summary.MaxDepth,
+		Truncated:     summary.Truncated,
+	}
+}
+
+// newSitemapReport converts the sitemap report to its JSON representation.
+func newSitemapReport(report *inspect.SitemapReport) *SitemapReport {
+	out := &SitemapReport{
+		URLs:           report.URLs,
+		Unchecked:      report.Unchecked,
+		Broken:         newSitemapIssues(report.Broken),
+		Missing:        report.Missing,
+		InvalidLastMod: newSitemapIssues(report.InvalidLastMod),
+		NonCanonical:   newSitemapIssues(report.NonCanonical),
+	}
+
+	for _, sitemap := range report.Sitemaps {
+		out.Sitemaps = append(out.Sitemaps, SitemapInfo{
+			URL:      sitemap.URL,
+			Index:    sitemap.Index,
+			URLs:     len(sitemap.URLs),
+			Sitemaps: len(sitemap.Sitemaps),
+			Error:    sitemap.Error,
+		})
+	}
+
+	return out
+}
+
+func newSitemapIssues(issues []inspect.SitemapIssue) []SitemapIssue {
+	var out []SitemapIssue
+
+	for _, issue := range issues {
+		out = append(out, SitemapIssue{
+			URL:     issue.URL,
+			Sitemap: issue.Sitemap,
+			Reason:  issue.Reason,
+		})
+	}
+
+	return out
+}
+
+// newLinkMetrics converts the link analytics to their JSON representation.
+// PageRank scores are rounded to 6 decimal places.
+func newLinkMetrics(analytics *inspect.LinkAnalytics) *LinkMetrics {
+	out := &LinkMetrics{
+		Orphans:  analytics.Orphans,
+		DeadEnds: analytics.DeadEnds,
+	}
+
+	for _, page := range analytics.Pages {
+		out.Pages = append(out.Pages, PageLinkMetrics{
+			URL:           page.URL,
+			Inlinks:       page.Inlinks,
+			UniqueInlinks: page.UniqueInlinks,
+			Outlinks:      page.Outlinks,
+			ClickDepth:    page.ClickDepth,
+			PageRank:      math.Round(page.PageRank*1e6) / 1e6,
+		})
+	}
+
+	return out
+}
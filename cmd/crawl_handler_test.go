@@ -0,0 +1,269 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockSiteServer() *httptest.Server {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Home</title></head><body>
+			<h1>Home</h1>
+			<a href="/about">about</a>
+			<a href="/missing">missing</a>
+			<a href="https://www.facebook.com">facebook</a>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/about", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>About</title></head><body>
+			<h2>Team</h2>
+			<a href="/">home</a>
+			<a href="/about/team">team</a>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/about/team", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Team</title><link rel="alternate" hreflang="en" href="/about/team"></head><body></body></html>`))
+	})
+
+	r.HandleFunc("/sitemap.xml", func(rw http.ResponseWriter, r *http.Request) {
+		loc := "http://" + r.Host
+
+		rw.Header().Set("Content-Type", "application/xml")
+		rw.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+			<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>` + loc + `/</loc><lastmod>2021-05-01</lastmod></url>
+				<url><loc>` + loc + `/about</loc><lastmod>01.05.2021</lastmod></url>
+				<url><loc>` + loc + `/gone</loc></url>
+			</urlset>`))
+	})
+
+	return httptest.NewServer(r)
+}
+
+func TestCrawlSite(t *testing.T) {
+	siteMockServer := mockSiteServer()
+	defer siteMockServer.Close()
+
+	mockServer := httptest.NewServer(crawlSite())
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name           string
+		Request        *http.Request
+		wantErr        bool
+		wantStatusCode int
+		wantBody       []byte
+	}{
+		{
+			Name: "fail-invalid-content-type",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"Invalid content-type"}`),
+		},
+		{
+			Name: "fail-empty",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(`{"url": ""}`))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"empty URL in payload"}`),
+		},
+		{
+			Name: "fail-invalid-pattern",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v", "exclude": ["("]}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"error parsing regexp: missing closing ): ` + "`(`" + `"}`),
+		},
+		{
+			Name: "fail-unsupported-scheme",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(`{"url": "ftp://example.com"}`))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"unsupported seed URL \"ftp://example.com\""}`),
+		},
+		{
+			Name: "fail-max-pages",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "max_pages": 1001}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"crawl exceeds the maximum of 1000 pages"}`),
+		},
+		{
+			Name: "ok",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "max_depth": 2, "concurrency": 2, "delay_ms": 1}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"seed":"%[1]v/","summary":{"pages":4,"errors":1,"status_codes":{"200":3,"404":1},"versions":{"5":3},"internal_links":4,"external_links":1,"max_depth":2,"truncated":false},"pages":[{"url":"%[1]v/","depth":0,"status_code":200,"version":"5","title":"Home","login_form":false,"headings":[{"level":"h1","total":1}],"internal_links":2,"external_links":1},{"url":"%[1]v/about","depth":1,"status_code":200,"version":"5","title":"About","login_form":false,"headings":[{"level":"h2","total":1}],"internal_links":2,"external_links":0},{"url":"%[1]v/missing","depth":1,"status_code":404,"login_form":false,"headings":null,"internal_links":0,"external_links":0,"error":"endpoint responded with code: 404"},{"url":"%[1]v/about/team","depth":2,"status_code":200,"version":"5","title":"Team","login_form":false,"headings":null,"internal_links":0,"external_links":0}]}`, siteMockServer.URL)),
+		},
+		{
+			Name: "ok-check-sitemaps",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "max_depth": 1, "exclude": ["/missing"], "check_sitemaps": true}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"seed":"%[1]v/","summary":{"pages":2,"errors":0,"status_codes":{"200":2},"versions":{"5":2},"internal_links":4,"external_links":1,"max_depth":1,"truncated":false},"pages":[{"url":"%[1]v/","depth":0,"status_code":200,"version":"5","title":"Home","login_form":false,"headings":[{"level":"h1","total":1}],"internal_links":2,"external_links":1},{"url":"%[1]v/about","depth":1,"status_code":200,"version":"5","title":"About","login_form":false,"headings":[{"level":"h2","total":1}],"internal_links":2,"external_links":0}],"sitemaps":{"sitemaps":[{"url":"%[1]v/sitemap.xml","index":false,"urls":3,"sitemaps":0}],"urls":3,"unchecked":0,"broken":[{"url":"%[1]v/gone","sitemap":"%[1]v/sitemap.xml","reason":"endpoint responded with code: 404"}],"missing":null,"invalid_lastmod":[{"url":"%[1]v/about","sitemap":"%[1]v/sitemap.xml","reason":"lastmod \"01.05.2021\" is not a W3C Datetime"}],"non_canonical":null}}`, siteMockServer.URL)),
+		},
+		{
+			Name: "ok-link-metrics",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "max_depth": 2, "link_metrics": true}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"seed":"%[1]v/","summary":{"pages":4,"errors":1,"status_codes":{"200":3,"404":1},"versions":{"5":3},"internal_links":4,"external_links":1,"max_depth":2,"truncated":false},"pages":[{"url":"%[1]v/","depth":0,"status_code":200,"version":"5","title":"Home","login_form":false,"headings":[{"level":"h1","total":1}],"internal_links":2,"external_links":1},{"url":"%[1]v/about","depth":1,"status_code":200,"version":"5","title":"About","login_form":false,"headings":[{"level":"h2","total":1}],"internal_links":2,"external_links":0},{"url":"%[1]v/missing","depth":1,"status_code":404,"login_form":false,"headings":null,"internal_links":0,"external_links":0,"error":"endpoint responded with code: 404"},{"url":"%[1]v/about/team","depth":2,"status_code":200,"version":"5","title":"Team","login_form":false,"headings":null,"internal_links":0,"external_links":0}],"link_metrics":{"pages":[{"url":"%[1]v/","inlinks":1,"unique_inlinks":1,"outlinks":2,"click_depth":0,"pagerank":0.25},{"url":"%[1]v/about","inlinks":1,"unique_inlinks":1,"outlinks":2,"click_depth":1,"pagerank":0.25},{"url":"%[1]v/missing","inlinks":1,"unique_inlinks":1,"outlinks":0,"click_depth":1,"pagerank":0.25},{"url":"%[1]v/about/team","inlinks":1,"unique_inlinks":1,"outlinks":0,"click_depth":2,"pagerank":0.25}],"orphans":["%[1]v/gone"],"dead_ends":["%[1]v/about/team"]}}`, siteMockServer.URL)),
+		},
+		{
+			Name: "ok-check-link-elements",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "max_depth": 2, "check_link_elements": true}`, siteMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"seed":"%[1]v/","summary":{"pages":4,"errors":1,"status_codes":{"200":3,"404":1},"versions":{"5":3},"internal_links":4,"external_links":1,"max_depth":2,"truncated":false},"pages":[{"url":"%[1]v/","depth":0,"status_code":200,"version":"5","title":"Home","login_form":false,"headings":[{"level":"h1","total":1}],"internal_links":2,"external_links":1},{"url":"%[1]v/about","depth":1,"status_code":200,"version":"5","title":"About","login_form":false,"headings":[{"level":"h2","total":1}],"internal_links":2,"external_links":0},{"url":"%[1]v/missing","depth":1,"status_code":404,"login_form":false,"headings":null,"internal_links":0,"external_links":0,"error":"endpoint responded with code: 404"},{"url":"%[1]v/about/team","depth":2,"status_code":200,"version":"5","title":"Team","login_form":false,"headings":null,"internal_links":0,"external_links":0}],"link_elements":[{"url":"%[1]v/about/team","findings":[{"severity":"low","check":"hreflang","message":"hreflang annotations don't declare an x-default alternate"}]}]}`, siteMockServer.URL)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(tt.Request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("http request for crawlSite() err = %v, want: %v", err, tt.wantErr)
+				return
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("crawlSite() status code = %v, want: %v", resp.StatusCode, tt.wantStatusCode)
+				return
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("io.ReadAll() err = %v, want: %v", err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(string(b), string(tt.wantBody)); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
+
+func TestCrawlSiteCanceled(t *testing.T) {
+	siteMockServer := mockSiteServer()
+	defer siteMockServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/crawl", strings.NewReader(fmt.Sprintf(`{"url": "%v/"}`, siteMockServer.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("content-type", "application/json")
+
+	rec := httptest.NewRecorder()
+	crawlSite()(rec, req)
+
+	if rec.Code != StatusClientClosedRequest {
+		t.Errorf("crawlSite() status code = %v, want: %v", rec.Code, StatusClientClosedRequest)
+	}
+}
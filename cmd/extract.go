@@ -0,0 +1,56 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// ExtractRule selects the values extracted from the elements matching a CSS
+// selector or from the result of an XPath expression. A JSON string is a
+// shorthand for the selector of a text rule.
+type ExtractRule struct {
+	Selector string `json:"selector"`
+	XPath    string `json:"xpath"`
+
+	// One of "text", "html" or "attr", "text" if empty.
+	Mode string `json:"mode"`
+
+	// Attribute extracted in the "attr" mode.
+	Attr string `json:"attr"`
+}
+
+func (r *ExtractRule) UnmarshalJSON(b []byte) error {
+	var selector string
+	if err := json.Unmarshal(b, &selector); err == nil {
+		*r = ExtractRule{Selector: selector}
+		return nil
+	}
+
+	type rule ExtractRule
+
+	return json.Unmarshal(b, (*rule)(r))
+}
+
+// extraction compiles the extract rules of the options, nil if there are none.
+func (o InspectOptions) extraction() (*inspect.Extraction, error) {
+	if len(o.Extract) == 0 {
+		return nil, nil
+	}
+
+	rules := make(map[string]inspect.ExtractRule)
+
+	for name, r := range o.Extract {
+		rules[name] = inspect.ExtractRule{
+			Selector: r.Selector,
+			XPath:    r.XPath,
+			Mode:     r.Mode,
+			Attr:     r.Attr,
+		}
+	}
+
+	return inspect.NewExtraction(rules)
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractRule(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Payload string
+		want    map[string]ExtractRule
+		wantErr bool
+	}{
+		{
+			Name:    "ok-shorthand",
+			Payload: `{"price": ".price"}`,
+			want:    map[string]ExtractRule{"price": {Selector: ".price"}},
+		},
+		{
+			Name:    "ok-rule",
+			Payload: `{"sku": {"selector": "[data-sku]", "mode": "attr", "attr": "data-sku"}}`,
+			want:    map[string]ExtractRule{"sku": {Selector: "[data-sku]", Mode: "attr", Attr: "data-sku"}},
+		},
+		{
+			Name:    "ok-xpath",
+			Payload: `{"links": {"xpath": "//a/@href"}}`,
+			want:    map[string]ExtractRule{"links": {XPath: "//a/@href"}},
+		},
+		{
+			Name:    "fail-type",
+			Payload: `{"price": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var have map[string]ExtractRule
+
+			if err := json.Unmarshal([]byte(tt.Payload), &have); (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
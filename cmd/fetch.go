@@ -0,0 +1,199 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Error codes reported when the fetched target can't be inspected.
+const (
+	ErrCodeNotHTML    = "not_html"
+	ErrCodeHTTPStatus = "http_status"
+	ErrCodeTooLarge   = "too_large"
+	ErrCodeDisallowed = "disallowed"
+)
+
+// DefaultUserAgent is sent with the requests and matched against the robots.txt
+// rules if no user-agent is set. Targets disallowed for it, or for all user-agents,
+// are rejected unless robots.txt is ignored.
+const DefaultUserAgent = "htmlinspect"
+
+// tlsProbeTimeout is the timeout for connecting to external hosts when probing TLS.
+const tlsProbeTimeout = 5 * time.Second
+
+// FetchSettings configures the requests issued by the link checker and the crawler.
+type FetchSettings struct {
+	// Maximum number of concurrent requests, unlimited if 0.
+	Concurrency int `json:"concurrency"`
+
+	// Minimum delay in milliseconds between two requests to the same host.
+	DelayMS int `json:"delay_ms"`
+
+	// User-Agent header sent with every request, DefaultUserAgent if empty.
+	UserAgent string `json:"user_agent"`
+
+	// User-agent the robots.txt rules are matched against, UserAgent if empty.
+	RobotsUserAgent string `json:"robots_user_agent"`
+
+	// IgnoreRobots fetches URLs disallowed by robots.txt.
+	IgnoreRobots bool `json:"ignore_robots"`
+}
+
+// fetcher creates an *inspect.Fetcher from the settings.
+func (s FetchSettings) fetcher() *inspect.Fetcher {
+	if s.UserAgent == "" {
+		s.UserAgent = DefaultUserAgent
+	}
+
+	return inspect.NewFetcher(inspect.FetchOptions{
+		UserAgent:       s.UserAgent,
+		Concurrency:     s.Concurrency,
+		Delay:           time.Duration(s.DelayMS) * time.Millisecond,
+		RespectRobots:   !s.IgnoreRobots,
+		RobotsUserAgent: s.RobotsUserAgent,
+	})
+}
+
+// PageError describes why the fetched target was rejected
+// together with the values observed on the response.
+type PageError struct {
+	Err         string `json:"err"`
+	Code        string `json:"code"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	MaxSize     int64  `json:"max_size,omitempty"`
+}
+
+func (e *PageError) Error() string { return e.Err }
+
+// fetchPage fetches the page at the given url through the fetcher and reads its body.
+// If the response is not an inspectable HTML page a *PageError is returned.
+// Responses with a non 2xx status code are rejected unless inspectErrorPages is set.
+func fetchPage(ctx context.Context, f *inspect.Fetcher, u *url.URL, inspectErrorPages bool) (*http.Response, []byte, error) {
+	resp, err := openPage(ctx, f, u, inspectErrorPages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("content-type")
+
+	if resp.ContentLength > inspect.DefaultMaxPageSize {
+		return nil, nil, tooLarge(resp.StatusCode, resp.ContentLength)
+	}
+
+	// read one byte past the limit to detect oversized bodies without a content-length.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, inspect.DefaultMaxPageSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if int64(len(body)) > inspect.DefaultMaxPageSize {
+		return nil, nil, tooLarge(resp.StatusCode, int64(len(body)))
+	}
+
+	// without a content-type header fall back to sniffing the body.
+	if contentType == "" {
+		if sniffed := http.DetectContentType(body); !inspect.IsHTML(sniffed) {
+			return nil, nil, notHTML(resp.StatusCode, sniffed)
+		}
+	}
+
+	return resp, body, nil
+}
+
+// streamPage fetches the page at the given url through the fetcher and extracts its
+// contents while the body is read, without buffering the whole page. Pages exceeding
+// inspect.DefaultMaxPageSize are inspected partially instead of being rejected. The
+// other responses that are not an inspectable HTML page are rejected like by fetchPage.
+func streamPage(ctx context.Context, f *inspect.Fetcher, u *url.URL, inspectErrorPages bool, extractors []inspect.Extractor) (*http.Response, *inspect.PageContents, error) {
+	resp, err := openPage(ctx, f, u, inspectErrorPages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+
+	// without a content-type header fall back to sniffing the beginning of the body.
+	if resp.Header.Get("content-type") == "" {
+		start, _ := body.Peek(512)
+		if sniffed := http.DetectContentType(start); !inspect.IsHTML(sniffed) {
+			return nil, nil, notHTML(resp.StatusCode, sniffed)
+		}
+	}
+
+	contents, err := inspect.PageStream(ctx, body, inspect.StreamOptions{MaxSize: inspect.DefaultMaxPageSize, Extractors: extractors})
+	if err != nil {
+		return nil, nil, &parseError{err}
+	}
+
+	return resp, contents, nil
+}
+
+// openPage fetches the page at the given url through the fetcher and checks the
+// status code and the content-type of the response. The caller must close the body.
+func openPage(ctx context.Context, f *inspect.Fetcher, u *url.URL, inspectErrorPages bool) (*http.Response, error) {
+	resp, err := f.Get(ctx, u.String())
+	if errors.Is(err, inspect.ErrDisallowed) {
+		return nil, &PageError{
+			Err:  fmt.Sprintf("target is %v", err),
+			Code: ErrCodeDisallowed,
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !inspectErrorPages && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		resp.Body.Close()
+
+		return nil, &PageError{
+			Err:        fmt.Sprintf("target responded with code: %v", resp.StatusCode),
+			Code:       ErrCodeHTTPStatus,
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	if contentType := resp.Header.Get("content-type"); contentType != "" && !inspect.IsHTML(contentType) {
+		resp.Body.Close()
+
+		return nil, notHTML(resp.StatusCode, contentType)
+	}
+
+	return resp, nil
+}
+
+func notHTML(status int, contentType string) *PageError {
+	return &PageError{
+		Err:         fmt.Sprintf("target is not a HTML page: %v", contentType),
+		Code:        ErrCodeNotHTML,
+		StatusCode:  status,
+		ContentType: contentType,
+	}
+}
+
+func tooLarge(status int, size int64) *PageError {
+	return &PageError{
+		Err:        fmt.Sprintf("target page exceeds the maximum size of %v bytes", inspect.DefaultMaxPageSize),
+		Code:       ErrCodeTooLarge,
+		StatusCode: status,
+		Size:       size,
+		MaxSize:    inspect.DefaultMaxPageSize,
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Link graph export formats
+const (
+	GraphFormatJSON    = "json"
+	GraphFormatDOT     = "dot"
+	GraphFormatGraphML = "graphml"
+)
+
+// graphFormats maps the export formats to their content-type and writer.
+var graphFormats = map[string]struct {
+	contentType string
+	write       func(*inspect.LinkGraph, io.Writer) error
+}{
+	GraphFormatJSON:    {contentType: "application/json", write: (*inspect.LinkGraph).WriteJSON},
+	GraphFormatDOT:     {contentType: "text/vnd.graphviz", write: (*inspect.LinkGraph).WriteDOT},
+	GraphFormatGraphML: {contentType: "application/graphml+xml", write: (*inspect.LinkGraph).WriteGraphML},
+}
+
+type LinkGraphRequest struct {
+	CrawlRequest
+
+	// Format of the exported graph, json if empty.
+	Format string `json:"format"`
+}
+
+// exportLinkGraph returns a handler post spec.
+func exportLinkGraph() http.HandlerFunc {
+	// This method will crawl the site from the given seed URL and export
+	// the internal link graph in the requested format.
+	//
+	// Responses:
+	//	200: Link graph in the requested format.
+	//	400: Invalid Request payload.
+	//	500: Server failure.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := LinkGraphRequest{}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		if payload.Format == "" {
+			payload.Format = GraphFormatJSON
+		}
+
+		format, ok := graphFormats[payload.Format]
+		if !ok {
+			log.Printf("unsupported graph format: %v", payload.Format)
+			JSONError(w, fmt.Sprintf("unsupported graph format: %v", payload.Format), http.StatusBadRequest)
+			return
+		}
+
+		site, ok := crawl(w, r, &payload.CrawlRequest, payload.fetcher())
+		if !ok {
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		if err := format.write(site.LinkGraph(), buf); err != nil {
+			log.Printf("failed to export link graph: %v", err)
+			JSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("content-type", format.contentType)
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.Printf("exportLinkGraph: failed to write response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportLinkGraph(t *testing.T) {
+	siteMockServer := mockSiteServer()
+	defer siteMockServer.Close()
+
+	mockServer := httptest.NewServer(exportLinkGraph())
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name            string
+		Body            string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			Name:            "fail-format",
+			Body:            fmt.Sprintf(`{"url": "%v/", "format": "png"}`, siteMockServer.URL),
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"err":"unsupported graph format: png"}`,
+		},
+		{
+			Name:            "fail-empty",
+			Body:            `{"url": ""}`,
+			wantStatusCode:  http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"err":"empty URL in payload"}`,
+		},
+		{
+			Name:            "ok-json",
+			Body:            fmt.Sprintf(`{"url": "%v/", "max_depth": 1}`, siteMockServer.URL),
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/json",
+			wantBody: fmt.Sprintf(`{"nodes":[{"url":"%[1]v/","title":"Home","depth":0,"status_code":200},{"url":"%[1]v/about","title":"About","depth":1,"status_code":200},{"url":"%[1]v/missing","title":"","depth":1,"status_code":404},{"url":"%[1]v/about/team","title":"","depth":-1,"status_code":0}],"adjacency":{"%[1]v/":[{"to":"%[1]v/about","text":"about","rel":""},{"to":"%[1]v/missing","text":"missing","rel":""}],"%[1]v/about":[{"to":"%[1]v/","text":"home","rel":""},{"to":"%[1]v/about/team","text":"team","rel":""}],"%[1]v/about/team":[],"%[1]v/missing":[]}}
+`, siteMockServer.URL),
+		},
+		{
+			Name:            "ok-dot",
+			Body:            fmt.Sprintf(`{"url": "%v/", "max_pages": 1, "format": "dot"}`, siteMockServer.URL),
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "text/vnd.graphviz",
+			wantBody: fmt.Sprintf(`digraph site {
+  "%[1]v/" [label="Home", depth=0, status_code=200];
+  "%[1]v/about" [label="%[1]v/about", depth=-1, status_code=0];
+  "%[1]v/missing" [label="%[1]v/missing", depth=-1, status_code=0];
+  "%[1]v/" -> "%[1]v/about" [label="about", rel=""];
+  "%[1]v/" -> "%[1]v/missing" [label="missing", rel=""];
+}
+`, siteMockServer.URL),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("content-type", "application/json")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("exportLinkGraph() status code = %v, want: %v", resp.StatusCode, tt.wantStatusCode)
+			}
+
+			if contentType := resp.Header.Get("content-type"); contentType != tt.wantContentType {
+				t.Errorf("exportLinkGraph() content-type = %v, want: %v", contentType, tt.wantContentType)
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(string(b), tt.wantBody); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
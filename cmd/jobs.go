@@ -0,0 +1,305 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Job states
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// Job queue defaults
+const (
+	DefaultJobWorkers   = 4
+	DefaultJobQueueSize = 100
+	DefaultJobRetention = time.Hour
+)
+
+// JobRequest is the payload of a job, a ParseHTMLRequest with
+// an optional callback receiving the report of the finished job.
+type JobRequest struct {
+	ParseHTMLRequest
+
+	// URL the final job status is POSTed to once the job finishes.
+	CallbackURL string `json:"callback_url"`
+
+	// Secret the callback payload is signed with, unsigned if empty.
+	CallbackSecret string `json:"callback_secret"`
+}
+
+var (
+	// ErrJobQueueFull is returned when a job is submitted to a full queue.
+	ErrJobQueueFull = errors.New("job queue is full")
+
+	// ErrJobFinished is returned when a finished job is canceled.
+	ErrJobFinished = errors.New("job already finished")
+)
+
+// Job is a page inspection running in the background.
+type Job struct {
+	id      string
+	request JobRequest
+
+	// guards the fields below.
+	mu sync.Mutex
+
+	state    string
+	checked  int
+	total    int
+	invalid  map[string][]inspect.InvalidLink
+	result   *ParseHTMLResponse
+	err      *PageError
+	callback *callback
+	created  time.Time
+	started  time.Time
+	finished time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// JobQueue runs the submitted jobs with a fixed number of workers.
+// Finished jobs are kept for the retention period.
+type JobQueue struct {
+	retention time.Duration
+	queue     chan *Job
+
+	// client delivers the callbacks, which are attempted up to
+	// callbackAttempts times with an exponential backoff.
+	client           *http.Client
+	callbackAttempts int
+	callbackBackoff  time.Duration
+
+	// now returns the current time.
+	now func() time.Time
+
+	// guards jobs.
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobQueue creates a JobQueue holding at most size queued jobs and
+// starts the workers, which run until the context is canceled.
+func NewJobQueue(ctx context.Context, workers, size int, retention time.Duration) *JobQueue {
+	if workers <= 0 {
+		workers = DefaultJobWorkers
+	}
+
+	if size <= 0 {
+		size = DefaultJobQueueSize
+	}
+
+	if retention <= 0 {
+		retention = DefaultJobRetention
+	}
+
+	q := &JobQueue{
+		retention:        retention,
+		queue:            make(chan *Job, size),
+		client:           &http.Client{Timeout: callbackTimeout},
+		callbackAttempts: DefaultCallbackAttempts,
+		callbackBackoff:  DefaultCallbackBackoff,
+		now:              time.Now,
+		jobs:             make(map[string]*Job),
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+
+	return q
+}
+
+// Submit queues the inspection of the requested page.
+func (q *JobQueue) Submit(request JobRequest) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Job{
+		id:      id,
+		request: request,
+		state:   JobQueued,
+		invalid: make(map[string][]inspect.InvalidLink),
+	}
+
+	if request.CallbackURL != "" {
+		j.callback = &callback{state: CallbackPending}
+	}
+
+	j.ctx, j.cancel = context.WithCancel(context.Background())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.purge()
+
+	j.created = q.now()
+
+	select {
+	case q.queue <- j:
+	default:
+		j.cancel()
+		return nil, ErrJobQueueFull
+	}
+
+	q.jobs[id] = j
+
+	return j, nil
+}
+
+// Get returns the job with the given id.
+func (q *JobQueue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.purge()
+
+	j, ok := q.jobs[id]
+
+	return j, ok
+}
+
+// Cancel cancels the job. A queued job is never started, a running
+// job is stopped and its partial results are kept. The callback of
+// a canceled job is not delivered.
+func (q *JobQueue) Cancel(j *Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch j.state {
+	case JobQueued, JobRunning:
+		j.state, j.finished = JobCanceled, q.now()
+		j.cancel()
+
+		if j.callback != nil {
+			j.callback.state = CallbackSkipped
+		}
+
+		return nil
+	}
+
+	return ErrJobFinished
+}
+
+// work runs the queued jobs until the context is canceled.
+func (q *JobQueue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-q.queue:
+			q.run(ctx, j)
+		}
+	}
+}
+
+// run inspects the page of the job.
+func (q *JobQueue) run(ctx context.Context, j *Job) {
+	j.mu.Lock()
+	if j.state != JobQueued {
+		j.mu.Unlock()
+		return
+	}
+
+	j.state, j.started = JobRunning, q.now()
+	j.mu.Unlock()
+
+	// stop the job if either the job or the queue is canceled.
+	jobCtx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	result, err := inspectURL(jobCtx, j.request.URL, j.request.InspectOptions, j.request.fetcher(), j.progress)
+
+	j.mu.Lock()
+
+	if j.state != JobRunning {
+		j.mu.Unlock()
+		return // canceled.
+	}
+
+	j.finished = q.now()
+	j.cancel()
+
+	if err != nil {
+		log.Printf("job %v failed for url:%v: %v", j.id, j.request.URL, err)
+		j.state, j.err = JobFailed, err
+	} else {
+		j.state, j.result = JobDone, result
+	}
+
+	deliver := j.callback != nil
+	j.mu.Unlock()
+
+	if deliver {
+		go q.deliver(ctx, j)
+	}
+}
+
+// progress records the progress of the link check of a running job.
+func (j *Job) progress(checked, total int, result inspect.LinkResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.state != JobRunning {
+		return
+	}
+
+	j.checked, j.total = checked, total
+
+	if result.Reason != "" {
+		j.invalid[result.Domain] = append(j.invalid[result.Domain], inspect.InvalidLink{URL: result.URL, Reason: result.Reason})
+	}
+}
+
+// purge removes the jobs finished before the retention period.
+// Must be called with q.mu held.
+func (q *JobQueue) purge() {
+	deadline := q.now().Add(-q.retention)
+
+	for id, j := range q.jobs {
+		j.mu.Lock()
+		expired := !j.finished.IsZero() && j.finished.Before(deadline)
+		j.mu.Unlock()
+
+		if expired {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// newJobID generates a random job id.
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Despire/htmlinspect/inspect"
+	"github.com/gorilla/mux"
+)
+
+type (
+	JobStatus struct {
+		ID        string      `json:"id"`
+		URL       string      `json:"url"`
+		State     string      `json:"state"`
+		StatusURL string      `json:"status_url"`
+		Progress  JobProgress `json:"progress"`
+
+		// Inaccessible links found so far, until the job is done.
+		Inaccessible []InvalidLink `json:"inaccessible,omitempty"`
+
+		Result   *ParseHTMLResponse `json:"result,omitempty"`
+		Error    *PageError         `json:"error,omitempty"`
+		Callback *CallbackStatus    `json:"callback,omitempty"`
+
+		CreatedAt  string `json:"created_at"`
+		StartedAt  string `json:"started_at,omitempty"`
+		FinishedAt string `json:"finished_at,omitempty"`
+	}
+
+	JobProgress struct {
+		Checked int `json:"checked"`
+		Total   int `json:"total"`
+	}
+
+	CallbackStatus struct {
+		URL      string            `json:"url"`
+		State    string            `json:"state"`
+		Attempts []CallbackAttempt `json:"attempts"`
+	}
+
+	CallbackAttempt struct {
+		At         string `json:"at"`
+		StatusCode int    `json:"status_code,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+)
+
+// submitJob returns a handler post spec.
+func submitJob(q *JobQueue) http.HandlerFunc {
+	// This method will queue the inspection of a HTML page, the payload is the
+	// same as for the synchronous endpoint. The state of the job is available
+	// at its status URL and is POSTed to the callback URL once the job finishes.
+	//
+	// Responses:
+	//	202: JobStatus.
+	//	400: Invalid Request payload.
+	//	500: Server failure.
+	//	503: The job queue is full.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := JobRequest{}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		if payload.URL == "" {
+			log.Printf("empty URL in payload")
+			JSONError(w, "empty URL in payload", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := url.Parse(payload.URL); err != nil {
+			log.Printf("failed to parse url")
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := payload.validate(); err != nil {
+			log.Printf("invalid inspect options: %v", err)
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if payload.CallbackURL != "" {
+			if u, err := url.Parse(payload.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				log.Printf("invalid callback url")
+				JSONError(w, fmt.Sprintf("invalid callback URL %q", payload.CallbackURL), http.StatusBadRequest)
+				return
+			}
+		}
+
+		j, err := q.Submit(payload)
+		if err != nil {
+			log.Printf("failed to submit job for url:%v: %v", payload.URL, err)
+
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrJobQueueFull) {
+				status = http.StatusServiceUnavailable
+			}
+
+			JSONError(w, err.Error(), status)
+			return
+		}
+
+		out := j.status()
+
+		w.Header().Set("Location", out.StatusURL)
+		JSON(w, out, http.StatusAccepted)
+	}
+}
+
+// jobStatus returns a handler get spec.
+func jobStatus(q *JobQueue) http.HandlerFunc {
+	// This method will report the state, the progress and the
+	// (partial) results of a job.
+	//
+	// Responses:
+	//	200: JobStatus.
+	//	404: Unknown or expired job.
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, ok := q.Get(mux.Vars(r)["id"])
+		if !ok {
+			JSONError(w, "job not found", http.StatusNotFound)
+			return
+		}
+
+		JSON(w, j.status(), http.StatusOK)
+	}
+}
+
+// cancelJob returns a handler delete spec.
+func cancelJob(q *JobQueue) http.HandlerFunc {
+	// This method will cancel a queued or running job.
+	//
+	// Responses:
+	//	200: JobStatus.
+	//	404: Unknown or expired job.
+	//	409: The job already finished.
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, ok := q.Get(mux.Vars(r)["id"])
+		if !ok {
+			JSONError(w, "job not found", http.StatusNotFound)
+			return
+		}
+
+		if err := q.Cancel(j); err != nil {
+			JSONError(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		JSON(w, j.status(), http.StatusOK)
+	}
+}
+
+// status converts the job to its JSON representation.
+func (j *Job) status() *JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	out := &JobStatus{
+		ID:        j.id,
+		URL:       j.request.URL,
+		State:     j.state,
+		StatusURL: "/jobs/" + j.id,
+		Progress:  JobProgress{Checked: j.checked, Total: j.total},
+		Result:    j.result,
+		Error:     j.err,
+		CreatedAt: j.created.Format(time.RFC3339),
+	}
+
+	if !j.started.IsZero() {
+		out.StartedAt = j.started.Format(time.RFC3339)
+	}
+
+	if !j.finished.IsZero() {
+		out.FinishedAt = j.finished.Format(time.RFC3339)
+	}
+
+	if j.callback != nil {
+		out.Callback = &CallbackStatus{
+			URL:   j.request.CallbackURL,
+			State: j.callback.state,
+		}
+
+		for _, a := range j.callback.attempts {
+			out.Callback.Attempts = append(out.Callback.Attempts, CallbackAttempt{
+				At:         a.at.Format(time.RFC3339),
+				StatusCode: a.statusCode,
+				Error:      a.err,
+			})
+		}
+	}
+
+	if j.result != nil {
+		return out
+	}
+
+	for domain, links := range j.invalid {
+		out.Inaccessible = append(out.Inaccessible, InvalidLink{
+			Domain: domain,
+			Links:  append([]inspect.InvalidLink(nil), links...),
+			Total:  len(links),
+		})
+	}
+
+	sort.Slice(out.Inaccessible, func(i, k int) bool { return out.Inaccessible[i].Domain < out.Inaccessible[k].Domain })
+
+	return out
+}
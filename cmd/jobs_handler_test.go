@@ -0,0 +1,251 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Despire/htmlinspect/inspect"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+)
+
+func TestJobs(t *testing.T) {
+	release := make(chan struct{})
+
+	site := http.NewServeMux()
+	site.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		// the links of a domain are checked sequentially, every link uses another one.
+		port := r.Host[strings.LastIndex(r.Host, ":"):]
+
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Slow</title></head><body>
+			<a href="http://127.0.0.1` + port + `/ok">ok</a>
+			<a href="http://localhost` + port + `/broken">broken</a>
+			<a href="/slow">slow</a>
+		</body></html>`))
+	})
+	site.HandleFunc("/ok", func(rw http.ResponseWriter, r *http.Request) {})
+	site.HandleFunc("/broken", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	site.HandleFunc("/slow", func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	siteMockServer := httptest.NewServer(site)
+	defer siteMockServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := NewJobQueue(ctx, 2, 10, time.Minute)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/jobs", submitJob(q)).Methods(http.MethodPost)
+	r.HandleFunc("/jobs/{id}", jobStatus(q)).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/{id}", cancelJob(q)).Methods(http.MethodDelete)
+
+	mockServer := httptest.NewServer(r)
+	defer mockServer.Close()
+
+	do := func(method, path, body string) (int, []byte) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, mockServer.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("content-type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp.StatusCode, b
+	}
+
+	submit := func() *JobStatus {
+		t.Helper()
+
+		status, b := do(http.MethodPost, "/jobs", fmt.Sprintf(`{"url": "%v/"}`, siteMockServer.URL))
+		if status != http.StatusAccepted {
+			t.Fatalf("submitJob() status code = %v, want: %v", status, http.StatusAccepted)
+		}
+
+		out := new(JobStatus)
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatal(err)
+		}
+
+		return out
+	}
+
+	// poll waits until the status of the job satisfies the condition.
+	poll := func(job *JobStatus, cond func(*JobStatus) bool) *JobStatus {
+		t.Helper()
+
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			status, b := do(http.MethodGet, job.StatusURL, "")
+			if status != http.StatusOK {
+				t.Fatalf("jobStatus() status code = %v, want: %v", status, http.StatusOK)
+			}
+
+			out := new(JobStatus)
+			if err := json.Unmarshal(b, out); err != nil {
+				t.Fatal(err)
+			}
+
+			if cond(out) {
+				return out
+			}
+		}
+
+		t.Fatalf("job %v did not reach the expected state", job.ID)
+		return nil
+	}
+
+	if status, b := do(http.MethodPost, "/jobs", `{"url": ""}`); status != http.StatusBadRequest || string(b) != `{"err":"empty URL in payload"}` {
+		t.Errorf("submitJob() = %v %s, want: %v", status, b, http.StatusBadRequest)
+	}
+
+	if status, b := do(http.MethodGet, "/jobs/unknown", ""); status != http.StatusNotFound || string(b) != `{"err":"job not found"}` {
+		t.Errorf("jobStatus() = %v %s, want: %v", status, b, http.StatusNotFound)
+	}
+
+	first, second := submit(), submit()
+
+	if first.State != JobQueued || first.StatusURL != "/jobs/"+first.ID || first.URL != siteMockServer.URL+"/" {
+		t.Errorf("submitJob() = %+v", first)
+	}
+
+	// both jobs block on the slow link after checking the other two links.
+	partial := poll(first, func(s *JobStatus) bool { return s.Progress.Checked == 2 })
+	poll(second, func(s *JobStatus) bool { return s.Progress.Checked == 2 })
+
+	wantPartial := []InvalidLink{{
+		Domain: "localhost",
+		Links:  []inspect.InvalidLink{{URL: strings.Replace(siteMockServer.URL, "127.0.0.1", "localhost", 1) + "/broken", Reason: "endpoint responded with code: 500"}},
+		Total:  1,
+	}}
+
+	if diff := cmp.Diff(partial.Inaccessible, wantPartial); diff != "" {
+		t.Error(diff)
+	}
+
+	if partial.State != JobRunning || partial.Progress.Total != 3 || partial.StartedAt == "" || partial.Result != nil {
+		t.Errorf("jobStatus() = %+v", partial)
+	}
+
+	status, b := do(http.MethodDelete, second.StatusURL, "")
+	if status != http.StatusOK {
+		t.Fatalf("cancelJob() status code = %v, want: %v", status, http.StatusOK)
+	}
+
+	canceled := new(JobStatus)
+	if err := json.Unmarshal(b, canceled); err != nil {
+		t.Fatal(err)
+	}
+
+	if canceled.State != JobCanceled || canceled.FinishedAt == "" || canceled.Progress.Checked != 2 || len(canceled.Inaccessible) != 1 {
+		t.Errorf("cancelJob() = %+v", canceled)
+	}
+
+	close(release)
+
+	done := poll(first, func(s *JobStatus) bool { return s.State != JobRunning })
+
+	if done.State != JobDone || done.Progress.Checked != 3 || done.Inaccessible != nil || done.Result == nil {
+		t.Fatalf("jobStatus() = %+v", done)
+	}
+
+	if diff := cmp.Diff(done.Result.Inaccessible, wantPartial); diff != "" {
+		t.Error(diff)
+	}
+
+	if done.Result.Title != "Slow" {
+		t.Errorf("result title = %q, want %q", done.Result.Title, "Slow")
+	}
+
+	if status, b := do(http.MethodDelete, first.StatusURL, ""); status != http.StatusConflict || string(b) != `{"err":"job already finished"}` {
+		t.Errorf("cancelJob() = %v %s, want: %v", status, b, http.StatusConflict)
+	}
+
+	if after := poll(second, func(*JobStatus) bool { return true }); after.State != JobCanceled {
+		t.Errorf("canceled job state = %v, want: %v", after.State, JobCanceled)
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	now := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// no workers are started so the submitted jobs stay queued.
+	q := &JobQueue{
+		retention: time.Hour,
+		queue:     make(chan *Job, 1),
+		now:       func() time.Time { return now },
+		jobs:      make(map[string]*Job),
+	}
+
+	j, err := q.Submit(JobRequest{ParseHTMLRequest: ParseHTMLRequest{URL: "https://www.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.Submit(JobRequest{ParseHTMLRequest: ParseHTMLRequest{URL: "https://www.example.com"}}); !errors.Is(err, ErrJobQueueFull) {
+		t.Errorf("Submit() error = %v, want: %v", err, ErrJobQueueFull)
+	}
+
+	if err := q.Cancel(j); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Cancel(j); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() error = %v, want: %v", err, ErrJobFinished)
+	}
+
+	// a canceled job is skipped by the workers.
+	q.run(context.Background(), <-q.queue)
+
+	if have := j.status(); have.State != JobCanceled || have.StartedAt != "" {
+		t.Errorf("status() = %+v", have)
+	}
+
+	now = now.Add(time.Hour)
+
+	if _, ok := q.Get(j.id); !ok {
+		t.Error("job expired before the retention period")
+	}
+
+	now = now.Add(time.Second)
+
+	if _, ok := q.Get(j.id); ok {
+		t.Error("job kept after the retention period")
+	}
+}
@@ -4,6 +4,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
 
@@ -17,9 +19,28 @@ func main() {
 }
 
 func run() error {
+	var (
+		workers   = flag.Int("job-workers", DefaultJobWorkers, "number of jobs run concurrently")
+		queueSize = flag.Int("job-queue-size", DefaultJobQueueSize, "maximum number of queued jobs")
+		retention = flag.Duration("job-retention", DefaultJobRetention, "how long finished jobs are kept")
+		batch     = flag.Int("batch-parallelism", DefaultMaxBatchParallelism, "number of batch pages inspected concurrently across all batches")
+	)
+
+	flag.Parse()
+
+	jobs := NewJobQueue(context.Background(), *workers, *queueSize, *retention)
+
 	r := mux.NewRouter()
 
 	r.HandleFunc("/", parseHtml()).Methods(http.MethodPost)
+	r.HandleFunc("/stream", streamInspection()).Methods(http.MethodPost)
+	r.HandleFunc("/batch", inspectBatch(*batch)).Methods(http.MethodPost)
+	r.HandleFunc("/crawl", crawlSite()).Methods(http.MethodPost)
+	r.HandleFunc("/sitemap", generateSitemap()).Methods(http.MethodPost)
+	r.HandleFunc("/graph", exportLinkGraph()).Methods(http.MethodPost)
+	r.HandleFunc("/jobs", submitJob(jobs)).Methods(http.MethodPost)
+	r.HandleFunc("/jobs/{id}", jobStatus(jobs)).Methods(http.MethodGet)
+	r.HandleFunc("/jobs/{id}", cancelJob(jobs)).Methods(http.MethodDelete)
 
 	log.Printf("listening on port: 8080")
 	return http.ListenAndServe(":8080", r)
@@ -5,12 +5,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
+	"time"
 
 	"github.com/Despire/htmlinspect/inspect"
 )
@@ -32,10 +36,290 @@ type (
 		Links  []inspect.InvalidLink `json:"links"`
 		Total  int                   `json:"total"`
 	}
+
+	Response struct {
+		StatusCode    int    `json:"status_code"`
+		ContentType   string `json:"content_type,omitempty"`
+		ContentLength int64  `json:"content_length,omitempty"`
+		Compression   string `json:"compression,omitempty"`
+		CacheControl  string `json:"cache_control,omitempty"`
+		ETag          string `json:"etag,omitempty"`
+		LastModified  string `json:"last_modified,omitempty"`
+		Age           string `json:"age,omitempty"`
+		Server        string `json:"server,omitempty"`
+		PoweredBy     string `json:"powered_by,omitempty"`
+	}
+
+	CSP struct {
+		Policies []string       `json:"policies"`
+		Blocked  []CSPViolation `json:"blocked"`
+		Findings []Finding      `json:"findings"`
+	}
+
+	CSPViolation struct {
+		Directive string `json:"directive"`
+		Kind      string `json:"kind"`
+		Resource  string `json:"resource"`
+	}
+
+	Cookie struct {
+		Name     string    `json:"name"`
+		Domain   string    `json:"domain,omitempty"`
+		Path     string    `json:"path,omitempty"`
+		Expires  string    `json:"expires,omitempty"`
+		MaxAge   int       `json:"max_age,omitempty"`
+		Secure   bool      `json:"secure"`
+		HttpOnly bool      `json:"http_only"`
+		SameSite string    `json:"same_site,omitempty"`
+		Size     int       `json:"size"`
+		Findings []Finding `json:"findings"`
+	}
+
+	TLS struct {
+		Host          string        `json:"host"`
+		Version       string        `json:"version,omitempty"`
+		CipherSuite   string        `json:"cipher_suite,omitempty"`
+		Certificates  []Certificate `json:"certificates,omitempty"`
+		SANs          []string      `json:"sans,omitempty"`
+		ExpiresInDays int           `json:"expires_in_days"`
+		HostnameMatch bool          `json:"hostname_match"`
+		Findings      []Finding     `json:"findings"`
+		Error         string        `json:"error,omitempty"`
+	}
+
+	Certificate struct {
+		Subject   string `json:"subject"`
+		Issuer    string `json:"issuer"`
+		NotBefore string `json:"not_before"`
+		NotAfter  string `json:"not_after"`
+	}
+
+	Robots struct {
+		URL        string           `json:"url"`
+		Sitemaps   []string         `json:"sitemaps"`
+		Disallowed []DisallowedLink `json:"disallowed"`
+	}
+
+	DisallowedLink struct {
+		URL        string   `json:"url"`
+		UserAgents []string `json:"user_agents"`
+	}
+
+	LinkElements struct {
+		Canonicals []string    `json:"canonicals"`
+		Alternates []Alternate `json:"alternates"`
+		Next       string      `json:"next,omitempty"`
+		Prev       string      `json:"prev,omitempty"`
+		AMPHTML    string      `json:"amphtml,omitempty"`
+		Findings   []Finding   `json:"findings"`
+	}
+
+	Alternate struct {
+		HrefLang string `json:"hreflang"`
+		URL      string `json:"url"`
+	}
+
+	Feed struct {
+		URL    string      `json:"url"`
+		Type   string      `json:"type"`
+		Title  string      `json:"title,omitempty"`
+		Report *FeedReport `json:"report,omitempty"`
+	}
+
+	FeedReport struct {
+		Format       string                `json:"format,omitempty"`
+		Title        string                `json:"title,omitempty"`
+		Items        int                   `json:"items"`
+		Newest       string                `json:"newest,omitempty"`
+		InvalidLinks []inspect.InvalidLink `json:"invalid_links"`
+		BrokenLinks  []inspect.InvalidLink `json:"broken_links"`
+		Error        string                `json:"error,omitempty"`
+	}
+
+	App struct {
+		Manifest string    `json:"manifest,omitempty"`
+		Icons    []Icon    `json:"icons"`
+		Audit    *AppAudit `json:"audit,omitempty"`
+	}
+
+	Icon struct {
+		URL   string `json:"url"`
+		Rel   string `json:"rel"`
+		Type  string `json:"type,omitempty"`
+		Sizes string `json:"sizes,omitempty"`
+	}
+
+	AppAudit struct {
+		Manifest *ManifestReport `json:"manifest"`
+		Icons    []IconReport    `json:"icons"`
+		Findings []Finding       `json:"findings"`
+	}
+
+	ManifestReport struct {
+		URL       string    `json:"url"`
+		Name      string    `json:"name,omitempty"`
+		ShortName string    `json:"short_name,omitempty"`
+		StartURL  string    `json:"start_url,omitempty"`
+		Display   string    `json:"display,omitempty"`
+		Icons     int       `json:"icons"`
+		Findings  []Finding `json:"findings"`
+		Error     string    `json:"error,omitempty"`
+	}
+
+	IconReport struct {
+		URL        string    `json:"url"`
+		Source     string    `json:"source"`
+		StatusCode int       `json:"status_code"`
+		Type       string    `json:"type,omitempty"`
+		Sizes      []string  `json:"sizes,omitempty"`
+		Findings   []Finding `json:"findings"`
+	}
+
+	Finding struct {
+		Severity string `json:"severity"`
+		Check    string `json:"check"`
+		Message  string `json:"message"`
+	}
 )
 
 type ParseHTMLRequest struct {
 	URL string `json:"url"`
+
+	InspectOptions
+	FetchSettings
+}
+
+// InspectOptions configures the optional checks of the page inspection.
+type InspectOptions struct {
+	// InspectErrorPages allows inspecting pages that
+	// responded with a non 2xx status code.
+	InspectErrorPages bool `json:"inspect_error_pages"`
+
+	// ProbeExternalTLS collects the TLS details of every
+	// distinct external host linked from the page.
+	ProbeExternalTLS bool `json:"probe_external_tls"`
+
+	// CheckCanonical fetches the canonical URL of the page and
+	// checks that it points to an indexable page.
+	CheckCanonical bool `json:"check_canonical"`
+
+	// CheckFeeds fetches the feeds referenced by the page
+	// and checks the links of their items.
+	CheckFeeds bool `json:"check_feeds"`
+
+	// AuditApp fetches the web app manifest and the icons
+	// declared by the page and validates them.
+	AuditApp bool `json:"audit_app"`
+
+	// Stream extracts the contents of the page while it is downloaded
+	// instead of buffering it. Pages exceeding the maximum size are
+	// inspected partially instead of being rejected.
+	Stream bool `json:"stream"`
+
+	// Extractors are the names of the registered extractors whose
+	// sections are added to the report. The article extractor needs
+	// the whole document, so it can't be combined with Stream.
+	Extractors []string `json:"extractors"`
+
+	// Extract maps names to the rules of the values extracted from the
+	// page, reported in the "extract" section. Needs the whole document,
+	// so it can't be combined with Stream.
+	Extract map[string]ExtractRule `json:"extract"`
+
+	// Records maps names to the templates of the records extracted from
+	// the page, reported in the "records" section. Needs the whole
+	// document, so it can't be combined with Stream.
+	Records map[string]RecordTemplate `json:"records"`
+
+	// TextStats reports the statistics and readability of the visible
+	// text in the "text" section. Needs the whole document, so it can't
+	// be combined with Stream.
+	TextStats bool `json:"text_stats"`
+
+	// Language of the text statistics, the lang of the page if empty.
+	Language string `json:"language"`
+}
+
+// validate checks the options of the request.
+func (o InspectOptions) validate() error {
+	registered := inspect.Extractors()
+
+	for _, name := range o.Extractors {
+		i := sort.SearchStrings(registered, name)
+		if i == len(registered) || registered[i] != name {
+			return fmt.Errorf("unknown extractor %q", name)
+		}
+
+		if name == inspect.ExtractorArticle && o.Stream {
+			return errors.New("the article extractor can't be used in stream mode")
+		}
+	}
+
+	if len(o.Extract) > 0 && o.Stream {
+		return errors.New("extract rules can't be used in stream mode")
+	}
+
+	if len(o.Records) > 0 && o.Stream {
+		return errors.New("record templates can't be used in stream mode")
+	}
+
+	if _, err := o.extraction(); err != nil {
+		return err
+	}
+
+	if _, err := o.recordExtraction(url.URL{}); err != nil {
+		return err
+	}
+
+	if o.TextStats && o.Stream {
+		return errors.New("text statistics can't be used in stream mode")
+	}
+
+	if o.TextStats {
+		if _, err := inspect.NewTextStatsExtractor(o.Language); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractors creates the extractors of the sections selected by the options
+// for the page at u.
+func (o InspectOptions) extractors(u url.URL) ([]inspect.Extractor, error) {
+	extractors, err := inspect.NewExtractors(o.Extractors...)
+	if err != nil {
+		return nil, err
+	}
+
+	extraction, err := o.extraction()
+	if err != nil {
+		return nil, err
+	}
+
+	if extraction != nil {
+		extractors = append(extractors, extraction)
+	}
+
+	records, err := o.recordExtraction(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if records != nil {
+		extractors = append(extractors, records)
+	}
+
+	if o.TextStats {
+		stats, err := inspect.NewTextStatsExtractor(o.Language)
+		if err != nil {
+			return nil, err
+		}
+
+		extractors = append(extractors, stats)
+	}
+
+	return extractors, nil
 }
 
 type ParseHTMLResponse struct {
@@ -47,6 +331,25 @@ type ParseHTMLResponse struct {
 	Internal     *Link         `json:"internal"`
 	External     []Link        `json:"external"`
 	Inaccessible []InvalidLink `json:"inaccessible"`
+
+	Response        *Response `json:"response"`
+	SecurityHeaders []Finding `json:"security_headers"`
+	CSP             *CSP      `json:"csp"`
+	Cookies         []Cookie  `json:"cookies"`
+	TLS             *TLS      `json:"tls"`
+	ExternalTLS     []TLS     `json:"external_tls,omitempty"`
+	Robots          *Robots   `json:"robots"`
+
+	LinkElements *LinkElements `json:"link_elements"`
+	Feeds        []Feed        `json:"feeds"`
+	App          *App          `json:"app"`
+
+	// If only the beginning of the page was inspected.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Sections of the selected extractors, the extract rules, the record templates
+	// and the text statistics by their name.
+	Sections map[string]interface{} `json:"sections,omitempty"`
 }
 
 // parseHTML returns a handler post spec.
@@ -56,24 +359,11 @@ func parseHtml() http.HandlerFunc {
 	// Responses:
 	//	200: ParseHTMLResponse.
 	//	400: Invalid Request payload.
+	//	422: Target is not an inspectable HTML page (PageError).
 	//	500: Server failure.
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("content-type") != "application/json" {
-			JSONError(w, "Invalid content-type", http.StatusBadRequest)
-			return
-		}
-
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("failed to read request body: %v\n", err)
-			JSONError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
 		payload := ParseHTMLRequest{}
-		if err := json.Unmarshal(body, &payload); err != nil {
-			log.Printf("failed to unmarshal request body: %v\n", err)
-			JSONError(w, err.Error(), http.StatusInternalServerError)
+		if !decodeJSON(w, r, &payload) {
 			return
 		}
 
@@ -90,77 +380,224 @@ func parseHtml() http.HandlerFunc {
 			return
 		}
 
-		resp, err := http.Get(u.String())
-		if err != nil {
-			log.Printf("failed to fetch page for url:%v", payload.URL)
-			JSONError(w, err.Error(), http.StatusInternalServerError)
+		if err := payload.validate(); err != nil {
+			log.Printf("invalid inspect options: %v", err)
+			JSONError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		defer resp.Body.Close()
-
-		body, err = ioutil.ReadAll(resp.Body)
+		out, err := inspectPage(r.Context(), u, payload.InspectOptions, payload.fetcher(), nil)
 		if err != nil {
-			log.Printf("failed to read response body: %v\n", err)
-			JSONError(w, err.Error(), http.StatusInternalServerError)
+			var (
+				pageErr  *PageError
+				parseErr *parseError
+			)
+
+			switch {
+			case errors.As(err, &pageErr):
+				log.Printf("rejected page for url:%v: %v", payload.URL, err)
+				JSON(w, pageErr, http.StatusUnprocessableEntity)
+			case errors.As(err, &parseErr):
+				log.Printf("failed to extract page contents: %v", err)
+				JSONError(w, err.Error(), http.StatusBadRequest)
+			default:
+				log.Printf("failed to fetch page for url:%v", payload.URL)
+				JSONError(w, err.Error(), http.StatusInternalServerError)
+			}
+
 			return
 		}
 
-		contents, err := inspect.Page(bytes.NewReader(body))
-		if err != nil {
-			log.Printf("failed to extract page contents: %v", err)
-			JSONError(w, err.Error(), http.StatusBadRequest)
-			return
+		JSON(w, out, http.StatusOK)
+	}
+}
+
+// parseError is returned by inspectPage if the contents of the page can't be extracted.
+type parseError struct{ error }
+
+func (e *parseError) Unwrap() error { return e.error }
+
+// inspectPage fetches the page at u through the fetcher and extracts general information
+// from it. If the target is not an inspectable HTML page a *PageError is returned.
+// The progress of the link check is reported to progress if it is not nil.
+func inspectPage(ctx context.Context, u *url.URL, opts InspectOptions, fetcher *inspect.Fetcher, progress inspect.LinkProgress) (*ParseHTMLResponse, error) {
+	extractors, err := opts.extractors(*u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, contents, err := loadPage(ctx, fetcher, u, opts, extractors)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ParseHTMLResponse{
+		Version:         contents.Version,
+		LoginForm:       contents.LoginForm,
+		Truncated:       contents.Truncated,
+		Sections:        newSections(contents, u),
+		Response:        newResponse(inspect.Response(resp)),
+		SecurityHeaders: newFindings(inspect.SecurityHeaders(resp.Header, resp.TLS != nil)),
+	}
+
+	if contents.Title == "" {
+		contents.Title = u.String() // if there was no title element default to the url of the page.
+	}
+
+	out.Title = contents.Title
+
+	if report := contents.CheckCSP(*u, resp.Header.Values("Content-Security-Policy")); report != nil {
+		out.CSP = &CSP{
+			Policies: report.Policies,
+			Findings: newFindings(report.Findings),
 		}
 
-		out := ParseHTMLResponse{
-			Version:   contents.Version,
-			LoginForm: contents.LoginForm,
+		for _, v := range report.Blocked {
+			out.CSP.Blocked = append(out.CSP.Blocked, CSPViolation{
+				Directive: v.Directive,
+				Kind:      v.Kind,
+				Resource:  v.Resource,
+			})
 		}
+	}
 
-		if contents.Title == "" {
-			contents.Title = u.String() // if there was no title element default to the url of the page.
+	for _, c := range inspect.Cookies(resp.Cookies(), *u) {
+		cookie := Cookie{
+			Name:     c.Name,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			MaxAge:   c.MaxAge,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+			Size:     c.Size,
+			Findings: newFindings(c.Findings),
 		}
 
-		out.Title = contents.Title
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.Format(time.RFC3339)
+		}
 
-		for level, count := range contents.Headings {
-			out.Headings = append(out.Headings, Heading{
-				Level: level,
-				Total: count,
-			})
+		out.Cookies = append(out.Cookies, cookie)
+	}
+
+	if resp.TLS != nil {
+		info := newTLS(inspect.TLS(resp.Request.URL.Hostname(), resp.TLS, nil))
+		out.TLS = &info
+	}
+
+	for level, count := range contents.Headings {
+		out.Headings = append(out.Headings, Heading{
+			Level: level,
+			Total: count,
+		})
+	}
+
+	var canonical []inspect.Finding
+	if opts.CheckCanonical {
+		canonical = contents.CheckCanonical(ctx, fetcher, resp)
+	}
+
+	out.LinkElements = newLinkElements(contents, contents.CheckLinkElements(*resp.Request.URL), canonical)
+
+	for _, l := range contents.Feeds {
+		feed := Feed{
+			URL:   l.FeedURL(*resp.Request.URL),
+			Type:  l.Type,
+			Title: l.Title,
 		}
 
-		for domain, links := range contents.InvalidLinks(*u) {
-			out.Inaccessible = append(out.Inaccessible, InvalidLink{
-				Domain: domain,
-				Links:  links,
-				Total:  len(links),
-			})
+		if opts.CheckFeeds {
+			feed.Report = newFeedReport(inspect.CheckFeed(ctx, fetcher, feed.URL))
 		}
 
-		if internal := consumeInternalLinks(u, contents.Links); len(internal) > 0 {
-			out.Internal = &Link{
-				Domain: u.Hostname(),
-				Links:  internal,
-				Total:  len(internal),
-			}
+		out.Feeds = append(out.Feeds, feed)
+	}
+
+	out.App = newApp(contents, *resp.Request.URL)
+
+	if opts.AuditApp {
+		out.App.Audit = newAppAudit(contents.AuditApp(ctx, fetcher, *resp.Request.URL))
+	}
+
+	out.Robots = newRobots(fetcher.Robots(ctx, *u), *u, contents.InternalLinks(*u))
+
+	for domain, links := range contents.CheckLinksProgress(ctx, *u, fetcher, progress) {
+		out.Inaccessible = append(out.Inaccessible, InvalidLink{
+			Domain: domain,
+			Links:  links,
+			Total:  len(links),
+		})
+	}
+
+	if internal := consumeInternalLinks(u, contents.Links); len(internal) > 0 {
+		out.Internal = &Link{
+			Domain: u.Hostname(),
+			Links:  internal,
+			Total:  len(internal),
 		}
+	}
 
-		// rest of the links are all external.
-		for domain, links := range contents.Links {
-			link := Link{Domain: domain}
+	if opts.ProbeExternalTLS {
+		for _, info := range inspect.ProbeTLSHosts(ctx, secureHosts(contents.Links), nil, tlsProbeTimeout) {
+			out.ExternalTLS = append(out.ExternalTLS, newTLS(&info))
+		}
+	}
 
-			for l := range links {
-				link.Links = append(link.Links, l)
-			}
+	// rest of the links are all external.
+	for domain, links := range contents.Links {
+		link := Link{Domain: domain}
 
-			link.Total = len(link.Links)
-			out.External = append(out.External, link)
+		for l := range links {
+			link.Links = append(link.Links, l)
 		}
 
-		JSON(w, &out, http.StatusOK)
+		link.Total = len(link.Links)
+		out.External = append(out.External, link)
+	}
+
+	return out, nil
+}
+
+// loadPage fetches the page at u through the fetcher and extracts its contents,
+// while the body is read if the options select the stream mode.
+func loadPage(ctx context.Context, fetcher *inspect.Fetcher, u *url.URL, opts InspectOptions, extractors []inspect.Extractor) (*http.Response, *inspect.PageContents, error) {
+	if opts.Stream {
+		return streamPage(ctx, fetcher, u, opts.InspectErrorPages, extractors)
+	}
+
+	return readPage(ctx, fetcher, u, opts.InspectErrorPages, extractors)
+}
+
+// newSections converts the sections of the extractors to their reported form.
+func newSections(contents *inspect.PageContents, u *url.URL) map[string]interface{} {
+	sections := contents.Sections
+
+	if article, ok := sections[inspect.ExtractorArticle].(*inspect.Article); ok {
+		sections[inspect.ExtractorArticle] = newArticle(article, u)
+	}
+
+	if stats, ok := sections[inspect.TextStatsSection].(*inspect.TextStats); ok {
+		sections[inspect.TextStatsSection] = newTextStats(stats, contents.Size)
 	}
+
+	return sections
+}
+
+// readPage fetches the page at u through the fetcher and extracts its contents
+// from the buffered body.
+func readPage(ctx context.Context, fetcher *inspect.Fetcher, u *url.URL, inspectErrorPages bool, extractors []inspect.Extractor) (*http.Response, *inspect.PageContents, error) {
+	resp, body, err := fetchPage(ctx, fetcher, u, inspectErrorPages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := inspect.Page(bytes.NewReader(body), extractors...)
+	if err != nil {
+		return nil, nil, &parseError{err}
+	}
+
+	return resp, contents, nil
 }
 
 // consumeInternalLinks extracts relative links and links for the urls domain.
@@ -184,6 +621,243 @@ func consumeInternalLinks(u *url.URL, links map[string]map[string]struct{}) []st
 	return out
 }
 
+// secureHosts returns the distinct hosts of the https links.
+func secureHosts(links map[string]map[string]struct{}) []string {
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+
+	for _, domain := range links {
+		for l := range domain {
+			u, err := url.Parse(l)
+			if err != nil || u.Scheme != "https" {
+				continue
+			}
+
+			if _, ok := seen[u.Host]; !ok {
+				seen[u.Host] = struct{}{}
+				out = append(out, u.Host)
+			}
+		}
+	}
+
+	return out
+}
+
+// newTLS converts the TLS details to their JSON representation.
+func newTLS(info *inspect.TLSInfo) TLS {
+	out := TLS{
+		Host:          info.Host,
+		Version:       info.Version,
+		CipherSuite:   info.CipherSuite,
+		SANs:          info.SANs,
+		ExpiresInDays: info.ExpiresInDays,
+		HostnameMatch: info.HostnameMatch,
+		Findings:      newFindings(info.Findings),
+		Error:         info.Error,
+	}
+
+	for _, c := range info.Certificates {
+		out.Certificates = append(out.Certificates, Certificate{
+			Subject:   c.Subject,
+			Issuer:    c.Issuer,
+			NotBefore: c.NotBefore.Format(time.RFC3339),
+			NotAfter:  c.NotAfter.Format(time.RFC3339),
+		})
+	}
+
+	return out
+}
+
+// newRobots converts the robots.txt to its JSON representation listing
+// the internal links that are disallowed for the major crawlers.
+func newRobots(robots *inspect.Robots, u url.URL, internal []string) *Robots {
+	out := &Robots{
+		URL:      inspect.RobotsURL(u),
+		Sitemaps: robots.Sitemaps,
+	}
+
+	for _, link := range internal {
+		if agents := robots.DisallowedFor(link, inspect.MajorCrawlers); len(agents) > 0 {
+			out.Disallowed = append(out.Disallowed, DisallowedLink{
+				URL:        link,
+				UserAgents: agents,
+			})
+		}
+	}
+
+	return out
+}
+
+// newLinkElements converts the link elements of the page and their findings
+// to their JSON representation, nil if the page declares none.
+func newLinkElements(contents *inspect.PageContents, findings ...[]inspect.Finding) *LinkElements {
+	if len(contents.Canonicals) == 0 && len(contents.Alternates) == 0 && contents.Next == "" && contents.Prev == "" && contents.AMPHTML == "" {
+		return nil
+	}
+
+	out := &LinkElements{
+		Canonicals: contents.Canonicals,
+		Next:       contents.Next,
+		Prev:       contents.Prev,
+		AMPHTML:    contents.AMPHTML,
+	}
+
+	for _, a := range contents.Alternates {
+		out.Alternates = append(out.Alternates, Alternate{
+			HrefLang: a.HrefLang,
+			URL:      a.Href,
+		})
+	}
+
+	for _, f := range findings {
+		out.Findings = append(out.Findings, newFindings(f)...)
+	}
+
+	return out
+}
+
+// newFeedReport converts the feed report to its JSON representation.
+func newFeedReport(report *inspect.FeedReport) *FeedReport {
+	out := &FeedReport{
+		Format:       report.Feed.Format,
+		Title:        report.Feed.Title,
+		Items:        len(report.Feed.Items),
+		InvalidLinks: report.InvalidLinks,
+		BrokenLinks:  report.BrokenLinks,
+		Error:        report.Feed.Error,
+	}
+
+	if !report.Newest.IsZero() {
+		out.Newest = report.Newest.Format(time.RFC3339)
+	}
+
+	return out
+}
+
+// newApp converts the manifest and icon declarations of the page to their JSON representation.
+func newApp(contents *inspect.PageContents, base url.URL) *App {
+	out := new(App)
+
+	if contents.Manifest != "" {
+		if u, err := base.Parse(contents.Manifest); err == nil {
+			out.Manifest = u.String()
+		}
+	}
+
+	for _, icon := range contents.Icons {
+		u, err := base.Parse(icon.Href)
+		if err != nil {
+			continue
+		}
+
+		out.Icons = append(out.Icons, Icon{
+			URL:   u.String(),
+			Rel:   icon.Rel,
+			Type:  icon.Type,
+			Sizes: icon.Sizes,
+		})
+	}
+
+	return out
+}
+
+// newAppAudit converts the web app manifest and icon audit to its JSON representation.
+func newAppAudit(report *inspect.AppReport) *AppAudit {
+	out := &AppAudit{Findings: newFindings(report.Findings)}
+
+	if m := report.Manifest; m != nil {
+		out.Manifest = &ManifestReport{
+			URL:      m.URL,
+			Findings: newFindings(m.Findings),
+			Error:    m.Error,
+		}
+
+		if m.Manifest != nil {
+			out.Manifest.Name = m.Manifest.Name
+			out.Manifest.ShortName = m.Manifest.ShortName
+			out.Manifest.StartURL = m.Manifest.StartURL
+			out.Manifest.Display = m.Manifest.Display
+			out.Manifest.Icons = len(m.Manifest.Icons)
+		}
+	}
+
+	for _, icon := range report.Icons {
+		out.Icons = append(out.Icons, IconReport{
+			URL:        icon.URL,
+			Source:     icon.Source,
+			StatusCode: icon.StatusCode,
+			Type:       icon.Type,
+			Sizes:      icon.Sizes,
+			Findings:   newFindings(icon.Findings),
+		})
+	}
+
+	return out
+}
+
+// newResponse converts the response metadata to its JSON representation.
+func newResponse(info *inspect.ResponseInfo) *Response {
+	out := &Response{
+		StatusCode:    info.StatusCode,
+		ContentType:   info.ContentType,
+		ContentLength: info.ContentLength,
+		Compression:   info.Compression,
+		CacheControl:  info.CacheControl,
+		ETag:          info.ETag,
+		LastModified:  info.LastModified,
+		Age:           info.Age,
+		Server:        info.Server,
+		PoweredBy:     info.PoweredBy,
+	}
+
+	if out.ContentLength < 0 {
+		out.ContentLength = 0 // unknown length.
+	}
+
+	return out
+}
+
+// newFindings converts the findings to their JSON representation.
+func newFindings(findings []inspect.Finding) []Finding {
+	var out []Finding
+
+	for _, f := range findings {
+		out = append(out, Finding{
+			Severity: string(f.Severity),
+			Check:    f.Check,
+			Message:  f.Message,
+		})
+	}
+
+	return out
+}
+
+// decodeJSON reads the JSON payload of the request into v.
+// On failure the error response is written and false is returned.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Header.Get("content-type") != "application/json" {
+		JSONError(w, "Invalid content-type", http.StatusBadRequest)
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("failed to read request body: %v\n", err)
+		JSONError(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		log.Printf("failed to unmarshal request body: %v\n", err)
+		JSONError(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
 // JSON marshals the payload and writes it to the output.
 func JSON(out http.ResponseWriter, payload interface{}, status int) {
 	b, err := json.Marshal(payload)
@@ -4,16 +4,24 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Despire/htmlinspect/inspect"
 	"github.com/google/go-cmp/cmp"
 )
 
+// mockSecurityHeaders are the security header findings for the mock external server.
+const mockSecurityHeaders = `[{"severity":"medium","check":"content-security-policy","message":"Content-Security-Policy header is missing"},{"severity":"medium","check":"x-frame-options","message":"neither X-Frame-Options nor frame-ancestors is set, the page can be framed"},{"severity":"low","check":"x-content-type-options","message":"X-Content-Type-Options header is missing"},{"severity":"low","check":"referrer-policy","message":"Referrer-Policy header is missing"},{"severity":"low","check":"permissions-policy","message":"Permissions-Policy header is missing"},{"severity":"low","check":"cross-origin-opener-policy","message":"the page does not isolate its browsing context group"},{"severity":"info","check":"cross-origin-embedder-policy","message":"the page is not cross-origin isolated"}]`
+
 func mockExternalServer() *httptest.Server {
 	r := http.NewServeMux()
 
@@ -68,6 +76,100 @@ func mockExternalServer() *httptest.Server {
 		rw.WriteHeader(http.StatusInternalServerError)
 	})
 
+	r.HandleFunc("/document.pdf", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "application/pdf")
+		rw.Write([]byte("%PDF-1.4"))
+	})
+
+	r.HandleFunc("/data", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"some": "json"}`))
+	})
+
+	r.HandleFunc("/missing", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Not found</title></head><body></body></html>`))
+	})
+
+	r.HandleFunc("/csp", func(rw http.ResponseWriter, r *http.Request) {
+		http.SetCookie(rw, &http.Cookie{Name: "sessionid", Value: "abc", Path: "/", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), SameSite: http.SameSiteLaxMode})
+		rw.Header().Set("Content-Security-Policy", "default-src 'self'; object-src 'none'; base-uri 'none'")
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>CSP</title><script src="/app.js"></script></head><body><script>alert(1)</script></body></html>`))
+	})
+
+	r.HandleFunc("/robots.txt", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("User-agent: *\nDisallow: /private\n\nUser-agent: Googlebot\nDisallow: /some/\n\nSitemap: https://www.example.com/sitemap.xml\n"))
+	})
+
+	r.HandleFunc("/private", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Private</title></head><body></body></html>`))
+	})
+
+	r.HandleFunc("/tracked", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Tracked</title><meta name="description" content="Tracked page"><script>gtag('config', 'G-ABC123XYZ');</script></head><body></body></html>`))
+	})
+
+	r.HandleFunc("/products", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Products</title></head><body>
+			<div class="product"><h2>Lamp</h2><span class="price">$1,299.90</span><a href="/lamp">Lamp</a></div>
+			<div class="product"><h2>Chair</h2><span class="price">sold out</span></div>
+			<div class="product"><span class="price">$5</span></div>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/article", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Article</title></head><body>
+			<nav><a href="/">Home</a></nav>
+			<article><p class="byline">By Jane Doe</p><p>A paragraph long enough to be the main content, with a comma.</p><img src="lead.png"></article>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/alternates", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Alternates</title>
+			<link rel="canonical" href="/missing">
+			<link rel="alternate" hreflang="en-UK" href="/alternates">
+			<link rel="alternate" hreflang="de" href="/de">
+			<link rel="next" href="/alternates?page=2">
+			<link rel="amphtml" href="/amp">
+		</head><body></body></html>`))
+	})
+
+	r.HandleFunc("/blog", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Blog</title>
+			<link rel="alternate" type="application/rss+xml" title="Blog" href="/feed.xml">
+		</head><body></body></html>`))
+	})
+
+	r.HandleFunc("/feed.xml", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "application/rss+xml")
+		rw.Write([]byte(`<rss version="2.0"><channel><title>Blog</title>
+			<item><title>First</title><link>/blog</link><pubDate>Sat, 01 May 2021 10:00:00 +0000</pubDate></item>
+			<item><title>Second</title><link>/some/relative/path/</link><pubDate>Sun, 02 May 2021 10:00:00 +0000</pubDate></item>
+			<item><title>Third</title></item>
+		</channel></rss>`))
+	})
+
+	r.HandleFunc("/app", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>App</title>
+			<link rel="manifest" href="/manifest.json">
+			<link rel="apple-touch-icon" href="/apple-touch-icon.png" sizes="180x180">
+		</head><body></body></html>`))
+	})
+
+	r.HandleFunc("/manifest.json", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "application/manifest+json")
+		rw.Write([]byte(`{"name": "App", "short_name": "App", "start_url": "/app", "display": "standalone", "icons": [{"src": "/apple-touch-icon.png", "sizes": "180x180", "type": "image/png"}]}`))
+	})
+
+	r.HandleFunc("/apple-touch-icon.png", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "image/png")
+		png.Encode(rw, image.NewRGBA(image.Rect(0, 0, 180, 180)))
+	})
+
+	r.HandleFunc("/large", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("content-type", "text/html")
+		rw.Write([]byte(strings.Repeat("a", inspect.DefaultMaxPageSize+1)))
+	})
+
 	return httptest.NewServer(r)
 }
 
@@ -147,6 +249,470 @@ func TestParseHTML(t *testing.T) {
 			wantStatusCode: http.StatusInternalServerError,
 			wantBody:       []byte(`{"err":"Get \"http://www.foobar\": dial tcp: lookup www.foobar: no such host"}`),
 		},
+		{
+			Name: "fail-not-html",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/document.pdf"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody:       []byte(`{"err":"target is not a HTML page: application/pdf","code":"not_html","status_code":200,"content_type":"application/pdf"}`),
+		},
+		{
+			Name: "fail-not-html-sniffed",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/data"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody:       []byte(`{"err":"target is not a HTML page: text/plain; charset=utf-8","code":"not_html","status_code":200,"content_type":"text/plain; charset=utf-8"}`),
+		},
+		{
+			Name: "fail-http-status",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/missing"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody:       []byte(`{"err":"target responded with code: 404","code":"http_status","status_code":404}`),
+		},
+		{
+			Name: "fail-too-large",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/large"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody:       []byte(fmt.Sprintf(`{"err":"target page exceeds the maximum size of %[1]v bytes","code":"too_large","status_code":200,"size":%[2]v,"max_size":%[1]v}`, inspect.DefaultMaxPageSize, inspect.DefaultMaxPageSize+1)),
+		},
+		{
+			Name: "ok-stream-truncated",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/large", "stream": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"","title":"%[1]v/large","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html"},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"truncated":true}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "fail-disallowed",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/private"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusUnprocessableEntity,
+			wantBody:       []byte(`{"err":"target is disallowed by robots.txt for user-agent \"htmlinspect\"","code":"disallowed"}`),
+		},
+		{
+			Name: "ok-extractors",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extractors": ["meta", "tracking"]}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Tracked","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":171},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"meta":{"description":"Tracked page"},"tracking":{"google-analytics":["G-ABC123XYZ"]}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-extract",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extract": {"title": "head > title", "description": {"selector": "meta[name=description]", "mode": "attr", "attr": "content"}, "scripts": {"selector": "script", "mode": "html"}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Tracked","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":171},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"extract":{"description":["Tracked page"],"scripts":["\u003cscript\u003egtag('config', 'G-ABC123XYZ');\u003c/script\u003e"],"title":["Tracked"]}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-extract-xpath",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extract": {"description": {"xpath": "//meta[@name='description']/@content"}, "scripts": {"xpath": "count(//script)"}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Tracked","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":171},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"extract":{"description":["Tracked page"],"scripts":["1"]}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "fail-extract-xpath",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extract": {"title": {"selector": "title", "xpath": "//title"}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"inspect.NewExtraction: rule \"title\": both selector and xpath set"}`),
+		},
+		{
+			Name: "ok-records",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/products", "records": {"products": {"selector": ".product", "fields": {"name": {"selector": "h2", "required": true}, "price": {"selector": ".price", "type": "number"}, "url": {"selector": "a@href", "type": "url"}}}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Products","login_form":false,"headings":[{"level":"h2","total":2}],"internal":{"domain":"127.0.0.1","links":["%[1]v/products/lamp"],"total":1},"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":324},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"records":{"products":[{"name":"Lamp","price":1299.9,"url":"%[1]v/lamp"},{"name":"Chair","price":null,"url":null}]}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "fail-records-stream",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/products", "stream": true, "records": {"products": {"selector": ".product", "fields": {"name": "h2"}}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"record templates can't be used in stream mode"}`),
+		},
+		{
+			Name: "fail-records-type",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/products", "records": {"products": {"selector": ".product", "fields": {"name": {"selector": "h2", "type": "int"}}}}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"inspect.NewRecordExtraction: template \"products\": field \"name\": unsupported type \"int\""}`),
+		},
+		{
+			Name: "ok-article",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/article", "extractors": ["article"]}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Article","login_form":false,"headings":null,"internal":{"domain":"127.0.0.1","links":["%[1]v/article/"],"total":1},"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":258},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"article":{"text":"A paragraph long enough to be the main content, with a comma.","html":"\u003cp\u003eA paragraph long enough to be the main content, with a comma.\u003c/p\u003e\u003cimg src=\"lead.png\"\u003e","byline":"Jane Doe","image":"%[1]v/lead.png"}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-text-stats",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/article", "text_stats": true, "language": "en"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Article","login_form":false,"headings":null,"internal":{"domain":"127.0.0.1","links":["%[1]v/article/"],"total":1},"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":258},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null},"sections":{"text":{"language":"en","words":16,"sentences":3,"syllables":21,"words_per_sentence":5.33,"syllables_per_word":1.31,"text_to_html_ratio":0.3023,"reading_ease":90.38,"grade":1.98}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-language-without-text-stats",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/article", "language": "tlh"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Article","login_form":false,"headings":null,"internal":{"domain":"127.0.0.1","links":["%[1]v/article/"],"total":1},"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":258},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "fail-text-stats-language",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/article", "text_stats": true, "language": "tlh"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"inspect.NewTextStatsExtractor: unsupported language \"tlh\""}`),
+		},
+		{
+			Name: "fail-extract-selector",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extract": {"title": "head >"}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"inspect.NewExtraction: rule \"title\": inspect.CompileSelector: invalid selector \"head \u003e\": expected a selector at offset 6"}`),
+		},
+		{
+			Name: "fail-extract-stream",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "stream": true, "extract": {"title": "title"}}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"extract rules can't be used in stream mode"}`),
+		},
+		{
+			Name: "fail-stream-article",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "stream": true, "extractors": ["article"]}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"the article extractor can't be used in stream mode"}`),
+		},
+		{
+			Name: "fail-unknown-extractor",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/tracked", "extractors": ["meta", "footer"]}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       []byte(`{"err":"unknown extractor \"footer\""}`),
+		},
+		{
+			Name: "ok-ignore-robots",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/private", "ignore_robots": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Private","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":76},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-robots-user-agent",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/private", "robots_user_agent": "Googlebot"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Private","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":76},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-inspect-error-page",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/missing", "inspect_error_pages": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Not found","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":404,"content_type":"text/html; charset=utf-8","content_length":78},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-link-elements",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/alternates"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Alternates","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":320},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":{"canonicals":["/missing"],"alternates":[{"hreflang":"en-UK","url":"/alternates"},{"hreflang":"de","url":"/de"}],"next":"/alternates?page=2","amphtml":"/amp","findings":[{"severity":"low","check":"canonical","message":"canonical URL \"/missing\" is relative"},{"severity":"medium","check":"hreflang","message":"invalid hreflang code \"en-UK\" for /alternates"},{"severity":"low","check":"hreflang","message":"hreflang annotations don't declare an x-default alternate"}]},"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-check-canonical",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/alternates", "check_canonical": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Alternates","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":320},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":{"canonicals":["/missing"],"alternates":[{"hreflang":"en-UK","url":"/alternates"},{"hreflang":"de","url":"/de"}],"next":"/alternates?page=2","amphtml":"/amp","findings":[{"severity":"low","check":"canonical","message":"canonical URL \"/missing\" is relative"},{"severity":"medium","check":"hreflang","message":"invalid hreflang code \"en-UK\" for /alternates"},{"severity":"low","check":"hreflang","message":"hreflang annotations don't declare an x-default alternate"},{"severity":"high","check":"canonical","message":"canonical URL %[1]v/missing responded with code: 404"}]},"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-check-feeds",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/blog", "check_feeds": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Blog","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":159},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":[{"url":"%[1]v/feed.xml","type":"application/rss+xml","title":"Blog","report":{"format":"rss","title":"Blog","items":3,"newest":"2021-05-02T10:00:00Z","invalid_links":[{"URL":"","Reason":"item \"Third\" has no link"}],"broken_links":[{"URL":"%[1]v/some/relative/path/","Reason":"endpoint responded with code: 500"}]}}],"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-audit-app",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/app", "audit_app": true}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"App","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":200},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"manifest":"%[1]v/manifest.json","icons":[{"url":"%[1]v/apple-touch-icon.png","rel":"apple-touch-icon","sizes":"180x180"}],"audit":{"manifest":{"url":"%[1]v/manifest.json","name":"App","short_name":"App","start_url":"/app","display":"standalone","icons":1,"findings":[{"severity":"medium","check":"manifest","message":"manifest has no square icon of at least 192x192"},{"severity":"medium","check":"manifest","message":"manifest has no square icon of at least 512x512, no splash screen can be generated"},{"severity":"low","check":"manifest","message":"manifest has no maskable icon"}]},"icons":[{"url":"%[1]v/apple-touch-icon.png","source":"apple-touch-icon","status_code":200,"type":"image/png","sizes":["180x180"],"findings":null},{"url":"%[1]v/apple-touch-icon.png","source":"manifest","status_code":200,"type":"image/png","sizes":["180x180"],"findings":null},{"url":"%[1]v/favicon.ico","source":"favicon.ico","status_code":200,"findings":[{"severity":"high","check":"icons","message":"icon is not an image (content-type: text/html; charset=utf-8)"}]}],"findings":[{"severity":"medium","check":"icons","message":"page has no favicon, neither declared nor at /favicon.ico"}]}}}`, externalMockServer.URL, mockSecurityHeaders)),
+		},
+		{
+			Name: "ok-csp",
+			Request: func() *http.Request {
+				req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/csp"}`, externalMockServer.URL)))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req.Header.Set("content-type", "application/json")
+
+				return req
+			}(),
+			wantErr:        false,
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"CSP","login_form":false,"headings":null,"internal":null,"external":null,"inaccessible":null,"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":128},"security_headers":[{"severity":"info","check":"content-security-policy","message":"Content-Security-Policy is enforced"},{"severity":"medium","check":"x-frame-options","message":"neither X-Frame-Options nor frame-ancestors is set, the page can be framed"},{"severity":"low","check":"x-content-type-options","message":"X-Content-Type-Options header is missing"},{"severity":"low","check":"referrer-policy","message":"Referrer-Policy header is missing"},{"severity":"low","check":"permissions-policy","message":"Permissions-Policy header is missing"},{"severity":"low","check":"cross-origin-opener-policy","message":"the page does not isolate its browsing context group"},{"severity":"info","check":"cross-origin-embedder-policy","message":"the page is not cross-origin isolated"}],"csp":{"policies":["default-src 'self'; object-src 'none'; base-uri 'none'"],"blocked":[{"directive":"default-src","kind":"script","resource":"alert(1)"}],"findings":null},"cookies":[{"name":"sessionid","path":"/","expires":"2030-01-01T00:00:00Z","secure":false,"http_only":false,"same_site":"Lax","size":12,"findings":[{"severity":"high","check":"secure","message":"session cookie sessionid is sent over unencrypted connections"},{"severity":"medium","check":"httponly","message":"session cookie sessionid is readable by scripts"}]}],"tls":null,"robots":{"url":"%v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":null},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL)),
+		},
 		{
 			Name: "ok",
 			Request: func() *http.Request {
@@ -161,7 +727,7 @@ func TestParseHTML(t *testing.T) {
 			}(),
 			wantErr:        false,
 			wantStatusCode: http.StatusOK,
-			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Some title","login_form":true,"headings":[{"level":"h1","total":2},{"level":"h3","total":1}],"internal":{"domain":"127.0.0.1","links":["%[1]v/some/relative/path/"],"total":1},"external":[{"domain":"www.facebook.com","links":["https://www.facebook.com"],"total":1}],"inaccessible":[{"domain":"127.0.0.1","links":[{"URL":"%[1]v/some/relative/path/","Reason":"endpoint responded with code: 500"}],"total":1}]}`, externalMockServer.URL)),
+			wantBody:       []byte(fmt.Sprintf(`{"version":"5","title":"Some title","login_form":true,"headings":[{"level":"h1","total":2},{"level":"h3","total":1}],"internal":{"domain":"127.0.0.1","links":["%[1]v/some/relative/path/"],"total":1},"external":[{"domain":"www.facebook.com","links":["https://www.facebook.com"],"total":1}],"inaccessible":[{"domain":"127.0.0.1","links":[{"URL":"%[1]v/some/relative/path/","Reason":"endpoint responded with code: 500"}],"total":1}],"response":{"status_code":200,"content_type":"text/html; charset=utf-8","content_length":712},"security_headers":%[2]v,"csp":null,"cookies":null,"tls":null,"robots":{"url":"%[1]v/robots.txt","sitemaps":["https://www.example.com/sitemap.xml"],"disallowed":[{"url":"%[1]v/some/relative/path/","user_agents":["Googlebot"]}]},"link_elements":null,"feeds":null,"app":{"icons":null}}`, externalMockServer.URL, mockSecurityHeaders)),
 		},
 	}
 
@@ -193,3 +759,64 @@ func TestParseHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHTMLTLS(t *testing.T) {
+	external := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer external.Close()
+
+	target := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		// the external server is linked by name so it isn't consumed as an internal link.
+		fmt.Fprintf(rw, `<!DOCTYPE html><html><head><title>TLS</title></head><body><a href="%v/">external</a></body></html>`,
+			strings.Replace(external.URL, "127.0.0.1", "localhost", 1))
+	}))
+	defer target.Close()
+
+	// the fetcher uses the default client, trust the certificate of the test servers.
+	defer func(transport http.RoundTripper) { http.DefaultClient.Transport = transport }(http.DefaultClient.Transport)
+	http.DefaultClient.Transport = target.Client().Transport
+
+	mockServer := httptest.NewServer(parseHtml())
+	defer mockServer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(fmt.Sprintf(`{"url": "%v/", "probe_external_tls": true}`, target.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("parseHtml() status code = %v, want: %v", resp.StatusCode, http.StatusOK)
+	}
+
+	var have ParseHTMLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&have); err != nil {
+		t.Fatal(err)
+	}
+
+	if have.TLS == nil {
+		t.Fatal("tls = nil, want the details of the target connection")
+	}
+
+	if have.TLS.Host != "127.0.0.1" || have.TLS.Version == "" || have.TLS.CipherSuite == "" || !have.TLS.HostnameMatch || len(have.TLS.Certificates) == 0 {
+		t.Errorf("tls = %+v, want the details of the target connection", have.TLS)
+	}
+
+	externalHost := strings.TrimPrefix(strings.Replace(external.URL, "127.0.0.1", "localhost", 1), "https://")
+
+	if len(have.ExternalTLS) != 1 || have.ExternalTLS[0].Host != externalHost || have.ExternalTLS[0].Error != "" || len(have.ExternalTLS[0].Certificates) == 0 {
+		t.Errorf("external_tls = %+v, want the details of %v", have.ExternalTLS, externalHost)
+	}
+}
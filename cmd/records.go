@@ -0,0 +1,96 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// RecordTemplate extracts a record from every element matching the CSS
+// selector or selected by the XPath expression.
+type RecordTemplate struct {
+	Selector string                 `json:"selector"`
+	XPath    string                 `json:"xpath"`
+	Fields   map[string]RecordField `json:"fields"`
+}
+
+// RecordField extracts the first value selected by the rule from the element of
+// the record, the element itself if the rule has no selector or expression. A JSON
+// string is a shorthand for the selector of a text field. A selector ending with
+// "@attr" extracts the attribute attr of the selected element.
+type RecordField struct {
+	Selector string `json:"selector"`
+	XPath    string `json:"xpath"`
+
+	// One of "text", "html" or "attr", "text" if empty.
+	Mode string `json:"mode"`
+
+	// Attribute extracted in the "attr" mode.
+	Attr string `json:"attr"`
+
+	// One of "string", "number", "url" or "date", "string" if empty.
+	Type string `json:"type"`
+
+	// Records without a value of a required field are skipped.
+	Required bool `json:"required"`
+}
+
+func (f *RecordField) UnmarshalJSON(b []byte) error {
+	var selector string
+	if err := json.Unmarshal(b, &selector); err == nil {
+		*f = RecordField{Selector: selector}
+	} else {
+		type field RecordField
+
+		if err := json.Unmarshal(b, (*field)(f)); err != nil {
+			return err
+		}
+	}
+
+	// "@" is not valid in selectors outside of strings.
+	if i := strings.LastIndex(f.Selector, "@"); i >= 0 && f.Attr == "" && !strings.ContainsAny(f.Selector[i:], `"']`) {
+		f.Selector, f.Mode, f.Attr = f.Selector[:i], inspect.ExtractAttr, f.Selector[i+1:]
+	}
+
+	return nil
+}
+
+// recordExtraction compiles the record templates of the options, nil if there
+// are none. URL fields are resolved against base.
+func (o InspectOptions) recordExtraction(base url.URL) (*inspect.RecordExtraction, error) {
+	if len(o.Records) == 0 {
+		return nil, nil
+	}
+
+	templates := make(map[string]inspect.RecordTemplate)
+
+	for name, t := range o.Records {
+		fields := make(map[string]inspect.RecordField)
+
+		for field, f := range t.Fields {
+			fields[field] = inspect.RecordField{
+				ExtractRule: inspect.ExtractRule{
+					Selector: f.Selector,
+					XPath:    f.XPath,
+					Mode:     f.Mode,
+					Attr:     f.Attr,
+				},
+				Type:     f.Type,
+				Required: f.Required,
+			}
+		}
+
+		templates[name] = inspect.RecordTemplate{
+			Selector: t.Selector,
+			XPath:    t.XPath,
+			Fields:   fields,
+		}
+	}
+
+	return inspect.NewRecordExtraction(templates, base)
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordField(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Payload string
+		want    map[string]RecordField
+		wantErr bool
+	}{
+		{
+			Name:    "ok-shorthand",
+			Payload: `{"name": "h2"}`,
+			want:    map[string]RecordField{"name": {Selector: "h2"}},
+		},
+		{
+			Name:    "ok-shorthand-attr",
+			Payload: `{"url": "a.details@href"}`,
+			want:    map[string]RecordField{"url": {Selector: "a.details", Mode: "attr", Attr: "href"}},
+		},
+		{
+			Name:    "ok-shorthand-item-attr",
+			Payload: `{"sku": "@data-sku"}`,
+			want:    map[string]RecordField{"sku": {Mode: "attr", Attr: "data-sku"}},
+		},
+		{
+			Name:    "ok-shorthand-at-in-string",
+			Payload: `{"mail": "a[href='mailto:a@example.com']"}`,
+			want:    map[string]RecordField{"mail": {Selector: "a[href='mailto:a@example.com']"}},
+		},
+		{
+			Name:    "ok-field",
+			Payload: `{"price": {"selector": ".price", "type": "number", "required": true}}`,
+			want:    map[string]RecordField{"price": {Selector: ".price", Type: "number", Required: true}},
+		},
+		{
+			Name:    "ok-field-attr",
+			Payload: `{"url": {"selector": "a@href", "type": "url"}}`,
+			want:    map[string]RecordField{"url": {Selector: "a", Mode: "attr", Attr: "href", Type: "url"}},
+		},
+		{
+			Name:    "fail-type",
+			Payload: `{"price": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var have map[string]RecordField
+
+			if err := json.Unmarshal([]byte(tt.Payload), &have); (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+type (
+	SitemapFile struct {
+		Name    string `json:"name"`
+		URLs    int    `json:"urls"`
+		Content string `json:"content"`
+	}
+
+	SitemapExclusion struct {
+		URL    string `json:"url"`
+		Reason string `json:"reason"`
+	}
+)
+
+type GenerateSitemapRequest struct {
+	CrawlRequest
+
+	// URL the generated sitemaps will be published under,
+	// the root of the seed URL if empty.
+	SitemapBaseURL string `json:"sitemap_base_url"`
+}
+
+type GenerateSitemapResponse struct {
+	Seed      string             `json:"seed"`
+	Truncated bool               `json:"truncated"`
+	Index     *SitemapFile       `json:"index"`
+	Sitemaps  []SitemapFile      `json:"sitemaps"`
+	Excluded  []SitemapExclusion `json:"excluded"`
+}
+
+// generateSitemap returns a handler post spec.
+func generateSitemap() http.HandlerFunc {
+	// This method will crawl the site from the given seed URL and generate
+	// a sitemap from the indexable pages that responded with 200.
+	//
+	// Responses:
+	//	200: GenerateSitemapResponse.
+	//	400: Invalid Request payload.
+	//	500: Server failure.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := GenerateSitemapRequest{}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		base, err := url.Parse(payload.SitemapBaseURL)
+		if err != nil || (payload.SitemapBaseURL != "" && !base.IsAbs()) {
+			log.Printf("failed to parse sitemap base url")
+			JSONError(w, "invalid sitemap_base_url in payload", http.StatusBadRequest)
+			return
+		}
+
+		site, ok := crawl(w, r, &payload.CrawlRequest, payload.fetcher())
+		if !ok {
+			return
+		}
+
+		if payload.SitemapBaseURL == "" {
+			seed, _ := url.Parse(site.Seed)
+			base = &url.URL{Scheme: seed.Scheme, Host: seed.Host, Path: "/"}
+		}
+
+		sitemap := site.GenerateSitemap(*base)
+
+		out := GenerateSitemapResponse{
+			Seed:      site.Seed,
+			Truncated: site.Truncated,
+		}
+
+		if sitemap.Index != nil {
+			index := newSitemapFile(sitemap.Index)
+			out.Index = &index
+		}
+
+		for i := range sitemap.Sitemaps {
+			out.Sitemaps = append(out.Sitemaps, newSitemapFile(&sitemap.Sitemaps[i]))
+		}
+
+		for _, e := range sitemap.Excluded {
+			out.Excluded = append(out.Excluded, SitemapExclusion{
+				URL:    e.URL,
+				Reason: e.Reason,
+			})
+		}
+
+		JSON(w, &out, http.StatusOK)
+	}
+}
+
+func newSitemapFile(file *inspect.SitemapFile) SitemapFile {
+	return SitemapFile{
+		Name:    file.Name,
+		URLs:    file.URLs,
+		Content: string(file.Content),
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateSitemap(t *testing.T) {
+	siteMockServer := mockSiteServer()
+	defer siteMockServer.Close()
+
+	mockServer := httptest.NewServer(generateSitemap())
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name           string
+		Body           string
+		ContentType    string
+		wantStatusCode int
+		wantBody       string
+		want           *GenerateSitemapResponse
+	}{
+		{
+			Name:           "fail-invalid-content-type",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"Invalid content-type"}`,
+		},
+		{
+			Name:           "fail-empty",
+			Body:           `{"url": ""}`,
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"empty URL in payload"}`,
+		},
+		{
+			Name:           "fail-invalid-base-url",
+			Body:           fmt.Sprintf(`{"url": "%v/", "sitemap_base_url": "/relative/"}`, siteMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"invalid sitemap_base_url in payload"}`,
+		},
+		{
+			Name:           "ok",
+			Body:           fmt.Sprintf(`{"url": "%v/"}`, siteMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusOK,
+			want: &GenerateSitemapResponse{
+				Seed: siteMockServer.URL + "/",
+				Sitemaps: []SitemapFile{{
+					Name: "sitemap.xml",
+					URLs: 3,
+					Content: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + siteMockServer.URL + `/</loc></url>
+  <url><loc>` + siteMockServer.URL + `/about</loc></url>
+  <url><loc>` + siteMockServer.URL + `/about/team</loc></url>
+</urlset>
+`,
+				}},
+				Excluded: []SitemapExclusion{
+					{URL: siteMockServer.URL + "/missing", Reason: "endpoint responded with code: 404"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.ContentType != "" {
+				req.Header.Set("content-type", tt.ContentType)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("generateSitemap() status code = %v, want: %v", resp.StatusCode, tt.wantStatusCode)
+				return
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == nil {
+				if diff := cmp.Diff(string(b), tt.wantBody); diff != "" {
+					t.Error(diff)
+				}
+
+				return
+			}
+
+			// the sitemap contents are escaped in the JSON body.
+			have := new(GenerateSitemapResponse)
+			if err := json.Unmarshal(b, have); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
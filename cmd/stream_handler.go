@@ -0,0 +1,236 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// Server-Sent Events emitted by the streaming endpoint.
+const (
+	EventFetched  = "fetched"
+	EventParsed   = "parsed"
+	EventLink     = "link"
+	EventComplete = "complete"
+	EventError    = "error"
+)
+
+type (
+	StreamFetched struct {
+		URL string `json:"url"`
+		*Response
+	}
+
+	StreamParsed struct {
+		Version   string    `json:"version"`
+		Title     string    `json:"title"`
+		LoginForm bool      `json:"login_form"`
+		Headings  []Heading `json:"headings"`
+		Links     int       `json:"links"`
+
+		// If only the beginning of the page was inspected.
+		Truncated bool `json:"truncated,omitempty"`
+	}
+
+	StreamLink struct {
+		Domain     string `json:"domain"`
+		URL        string `json:"url"`
+		Accessible bool   `json:"accessible"`
+		Reason     string `json:"reason,omitempty"`
+		Checked    int    `json:"checked"`
+		Total      int    `json:"total"`
+	}
+
+	StreamComplete struct {
+		Links         int `json:"links"`
+		Inaccessible  int `json:"inaccessible"`
+		InternalLinks int `json:"internal_links"`
+		ExternalLinks int `json:"external_links"`
+
+		// Sections of the selected extractors, the extract rules, the record
+		// templates and the text statistics by their name.
+		Sections map[string]interface{} `json:"sections,omitempty"`
+	}
+)
+
+// streamInspection returns a handler post spec.
+func streamInspection() http.HandlerFunc {
+	// This method will extract general information from a HTML page like the
+	// synchronous endpoint, but streams the results as Server-Sent Events as
+	// soon as they are available. Failures after the stream started are sent
+	// as an error event carrying a PageError.
+	//
+	// Responses:
+	//	200: text/event-stream.
+	//	400: Invalid Request payload.
+	//	500: Server failure.
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := ParseHTMLRequest{}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+
+		if payload.URL == "" {
+			log.Printf("empty URL in payload")
+			JSONError(w, "empty URL in payload", http.StatusBadRequest)
+			return
+		}
+
+		u, err := url.Parse(payload.URL)
+		if err != nil {
+			log.Printf("failed to parse url")
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := payload.validate(); err != nil {
+			log.Printf("invalid inspect options: %v", err)
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		extractors, err := payload.extractors(*u)
+		if err != nil {
+			log.Printf("invalid inspect options: %v", err)
+			JSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stream, ok := newEventStream(w)
+		if !ok {
+			JSONError(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		fetcher := payload.fetcher()
+
+		resp, contents, err := loadPage(r.Context(), fetcher, u, payload.InspectOptions, extractors)
+		if err != nil {
+			var (
+				pageErr  *PageError
+				parseErr *parseError
+			)
+
+			switch {
+			case errors.As(err, &pageErr):
+				log.Printf("rejected page for url:%v: %v", payload.URL, err)
+			case errors.As(err, &parseErr):
+				log.Printf("failed to extract page contents: %v", err)
+				pageErr = &PageError{Err: err.Error(), Code: ErrCodeParse}
+			default:
+				log.Printf("failed to fetch page for url:%v: %v", payload.URL, err)
+				pageErr = &PageError{Err: err.Error(), Code: ErrCodeFetch}
+			}
+
+			stream.event(EventError, pageErr)
+			return
+		}
+
+		stream.event(EventFetched, &StreamFetched{URL: u.String(), Response: newResponse(inspect.Response(resp))})
+
+		if contents.Title == "" {
+			contents.Title = u.String() // if there was no title element default to the url of the page.
+		}
+
+		parsed := &StreamParsed{
+			Version:   contents.Version,
+			Title:     contents.Title,
+			LoginForm: contents.LoginForm,
+			Truncated: contents.Truncated,
+		}
+
+		for level, count := range contents.Headings {
+			parsed.Headings = append(parsed.Headings, Heading{
+				Level: level,
+				Total: count,
+			})
+		}
+
+		sort.Slice(parsed.Headings, func(i, j int) bool { return parsed.Headings[i].Level < parsed.Headings[j].Level })
+
+		for _, links := range contents.Links {
+			parsed.Links += len(links)
+		}
+
+		stream.event(EventParsed, parsed)
+
+		out := &StreamComplete{
+			Links:    parsed.Links,
+			Sections: newSections(contents, u),
+		}
+
+		contents.CheckLinksProgress(r.Context(), *u, fetcher, func(checked, total int, result inspect.LinkResult) {
+			if result.Reason != "" {
+				out.Inaccessible++
+			}
+
+			stream.event(EventLink, &StreamLink{
+				Domain:     result.Domain,
+				URL:        result.URL,
+				Accessible: result.Reason == "",
+				Reason:     result.Reason,
+				Checked:    checked,
+				Total:      total,
+			})
+		})
+
+		if r.Context().Err() != nil {
+			return // client disconnected.
+		}
+
+		out.InternalLinks = len(consumeInternalLinks(u, contents.Links))
+
+		for _, links := range contents.Links {
+			out.ExternalLinks += len(links)
+		}
+
+		stream.event(EventComplete, out)
+	}
+}
+
+// eventStream writes Server-Sent Events to the response.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream writes the headers of the event stream, returns false if
+// the response writer can't flush the events as they are written.
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &eventStream{w: w, flusher: flusher}, true
+}
+
+// event marshals the payload and sends it as the data of the named event.
+func (s *eventStream) event(name string, payload interface{}) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("eventStream: failed to marshal %v event: %v", name, err)
+		return
+	}
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		log.Printf("eventStream: failed to write %v event: %v", name, err)
+		return
+	}
+
+	s.flusher.Flush()
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// sseEvent is a parsed Server-Sent Event.
+type sseEvent struct{ Name, Data string }
+
+// parseEvents parses the events of the stream. The link events are sorted
+// by their data as the links are checked concurrently.
+func parseEvents(t *testing.T, stream string) []sseEvent {
+	t.Helper()
+
+	var out []sseEvent
+
+	for _, block := range strings.Split(strings.TrimSpace(stream), "\n\n") {
+		var e sseEvent
+
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.Name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.Data = strings.TrimPrefix(line, "data: ")
+			default:
+				t.Fatalf("unexpected line %q", line)
+			}
+		}
+
+		out = append(out, e)
+	}
+
+	// the link events are consecutive.
+	first, last := len(out), len(out)
+	for i, e := range out {
+		if e.Name == EventLink {
+			if first == len(out) {
+				first = i
+			}
+
+			last = i + 1
+		}
+	}
+
+	links := out[first:last]
+	sort.Slice(links, func(i, j int) bool { return links[i].Data < links[j].Data })
+
+	return out
+}
+
+func TestStreamInspection(t *testing.T) {
+	externalMockServer := mockExternalServer()
+	defer externalMockServer.Close()
+
+	site := http.NewServeMux()
+	site.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Stream</title></head><body>
+			<h2>Links</h2>
+			<h1>Stream</h1>
+			<a href="/ok">ok</a>
+			<a href="/broken">broken</a>
+			<a href="ftp://files.example.com/">external</a>
+		</body></html>`))
+	})
+	site.HandleFunc("/ok", func(rw http.ResponseWriter, r *http.Request) {})
+	site.HandleFunc("/broken", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+
+	siteMockServer := httptest.NewServer(site)
+	defer siteMockServer.Close()
+
+	mockServer := httptest.NewServer(streamInspection())
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name           string
+		Body           string
+		ContentType    string
+		wantStatusCode int
+		wantBody       string
+		wantEvents     []sseEvent
+	}{
+		{
+			Name:           "fail-invalid-content-type",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"Invalid content-type"}`,
+		},
+		{
+			Name:           "fail-empty",
+			Body:           `{"url": ""}`,
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"empty URL in payload"}`,
+		},
+		{
+			Name:           "fail-unknown-extractor",
+			Body:           fmt.Sprintf(`{"url": "%v/", "extractors": ["footer"]}`, siteMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `{"err":"unknown extractor \"footer\""}`,
+		},
+		{
+			Name:           "fail-http-status",
+			Body:           fmt.Sprintf(`{"url": "%v/missing"}`, externalMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusOK,
+			wantEvents: []sseEvent{
+				{Name: EventError, Data: `{"err":"target responded with code: 404","code":"http_status","status_code":404}`},
+			},
+		},
+		{
+			Name:           "ok",
+			Body:           fmt.Sprintf(`{"url": "%v/"}`, siteMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusOK,
+			wantEvents: []sseEvent{
+				{Name: EventFetched, Data: fmt.Sprintf(`{"url":"%v/","status_code":200,"content_type":"text/html; charset=utf-8","content_length":222}`, siteMockServer.URL)},
+				{Name: EventParsed, Data: `{"version":"5","title":"Stream","login_form":false,"headings":[{"level":"h1","total":1},{"level":"h2","total":1}],"links":3}`},
+				{Name: EventLink, Data: fmt.Sprintf(`{"domain":"127.0.0.1","url":"%v/broken","accessible":false,"reason":"endpoint responded with code: 500","checked":%v,"total":3}`, siteMockServer.URL, "%v")},
+				{Name: EventLink, Data: fmt.Sprintf(`{"domain":"127.0.0.1","url":"%v/ok","accessible":true,"checked":%v,"total":3}`, siteMockServer.URL, "%v")},
+				{Name: EventLink, Data: `{"domain":"files.example.com","url":"ftp://files.example.com/","accessible":false,"reason":"Get \"ftp://files.example.com/\": unsupported protocol scheme \"ftp\"","checked":%v,"total":3}`},
+				{Name: EventComplete, Data: `{"links":3,"inaccessible":2,"internal_links":2,"external_links":1}`},
+			},
+		},
+		{
+			Name:           "ok-sections",
+			Body:           fmt.Sprintf(`{"url": "%v/", "extractors": ["meta"]}`, siteMockServer.URL),
+			ContentType:    "application/json",
+			wantStatusCode: http.StatusOK,
+			wantEvents: []sseEvent{
+				{Name: EventFetched, Data: fmt.Sprintf(`{"url":"%v/","status_code":200,"content_type":"text/html; charset=utf-8","content_length":222}`, siteMockServer.URL)},
+				{Name: EventParsed, Data: `{"version":"5","title":"Stream","login_form":false,"headings":[{"level":"h1","total":1},{"level":"h2","total":1}],"links":3}`},
+				{Name: EventLink, Data: fmt.Sprintf(`{"domain":"127.0.0.1","url":"%v/broken","accessible":false,"reason":"endpoint responded with code: 500","checked":%v,"total":3}`, siteMockServer.URL, "%v")},
+				{Name: EventLink, Data: fmt.Sprintf(`{"domain":"127.0.0.1","url":"%v/ok","accessible":true,"checked":%v,"total":3}`, siteMockServer.URL, "%v")},
+				{Name: EventLink, Data: `{"domain":"files.example.com","url":"ftp://files.example.com/","accessible":false,"reason":"Get \"ftp://files.example.com/\": unsupported protocol scheme \"ftp\"","checked":%v,"total":3}`},
+				{Name: EventComplete, Data: `{"links":3,"inaccessible":2,"internal_links":2,"external_links":1,"sections":{"meta":{}}}`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.ContentType != "" {
+				req.Header.Set("content-type", tt.ContentType)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("streamInspection() status code = %v, want: %v", resp.StatusCode, tt.wantStatusCode)
+				return
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantBody != "" {
+				if diff := cmp.Diff(string(b), tt.wantBody); diff != "" {
+					t.Error(diff)
+				}
+
+				return
+			}
+
+			if contentType := resp.Header.Get("content-type"); contentType != "text/event-stream" {
+				t.Errorf("content-type = %v, want: text/event-stream", contentType)
+			}
+
+			have := parseEvents(t, string(b))
+
+			// the links are checked concurrently, the progress counter
+			// of every link event depends on the order of the checks.
+			var checked []string
+
+			for i := range have {
+				if have[i].Name != EventLink {
+					continue
+				}
+
+				start := strings.Index(have[i].Data, `"checked":`) + len(`"checked":`)
+				end := start + strings.Index(have[i].Data[start:], ",")
+
+				checked = append(checked, have[i].Data[start:end])
+				have[i].Data = have[i].Data[:start] + "%v" + have[i].Data[end:]
+			}
+
+			sort.Strings(checked)
+
+			if len(checked) > 0 {
+				if diff := cmp.Diff(checked, []string{"1", "2", "3"}); diff != "" {
+					t.Error(diff)
+				}
+			}
+
+			if diff := cmp.Diff(have, tt.wantEvents); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"math"
+
+	"github.com/Despire/htmlinspect/inspect"
+)
+
+// TextStats are the statistics of the visible text of the page.
+type TextStats struct {
+	Language         string   `json:"language,omitempty"`
+	Words            int      `json:"words"`
+	Sentences        int      `json:"sentences"`
+	Syllables        int      `json:"syllables,omitempty"`
+	WordsPerSentence float64  `json:"words_per_sentence"`
+	SyllablesPerWord float64  `json:"syllables_per_word,omitempty"`
+	TextToHTMLRatio  float64  `json:"text_to_html_ratio"`
+	ReadingEase      *float64 `json:"reading_ease,omitempty"`
+	Grade            *float64 `json:"grade,omitempty"`
+}
+
+// newTextStats converts the text statistics of a page of size bytes to their
+// JSON representation, the averages and scores are rounded to two decimals and
+// the text to HTML ratio, which is small for most pages, to four.
+func newTextStats(s *inspect.TextStats, size int64) *TextStats {
+	if s == nil {
+		return nil
+	}
+
+	round := func(f float64) float64 { return math.Round(f*100) / 100 }
+
+	out := &TextStats{
+		Language:         s.Language,
+		Words:            s.Words,
+		Sentences:        s.Sentences,
+		Syllables:        s.Syllables,
+		WordsPerSentence: round(s.WordsPerSentence),
+		SyllablesPerWord: round(s.SyllablesPerWord),
+	}
+
+	if size > 0 {
+		out.TextToHTMLRatio = math.Round(float64(s.TextLength)/float64(size)*1e4) / 1e4
+	}
+
+	if s.ReadingEase != nil {
+		ease := round(*s.ReadingEase)
+		out.ReadingEase = &ease
+	}
+
+	if s.Grade != nil {
+		grade := round(*s.Grade)
+		out.Grade = &grade
+	}
+
+	return out
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Callback delivery states
+const (
+	CallbackPending    = "pending"
+	CallbackDelivering = "delivering"
+	CallbackDelivered  = "delivered"
+	CallbackFailed     = "failed"
+
+	// CallbackSkipped is the state of the callback of a canceled job.
+	CallbackSkipped = "skipped"
+)
+
+// Callback delivery defaults
+const (
+	DefaultCallbackAttempts = 5
+	DefaultCallbackBackoff  = time.Second
+)
+
+// Headers sent with every callback.
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the payload
+	// prefixed with "sha256=", if the job has a callback secret.
+	SignatureHeader = "X-Htmlinspect-Signature"
+
+	// JobHeader carries the id of the job.
+	JobHeader = "X-Htmlinspect-Job"
+)
+
+// callbackTimeout is the timeout of a single callback request.
+const callbackTimeout = 10 * time.Second
+
+// callback is the delivery state of the callback of a job.
+type callback struct {
+	state    string
+	attempts []callbackAttempt
+}
+
+// callbackAttempt is a single delivery attempt of a callback.
+type callbackAttempt struct {
+	at         time.Time
+	statusCode int
+	err        string
+}
+
+// Sign computes the value of the SignatureHeader for the payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the value of the SignatureHeader of a received callback.
+func VerifySignature(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// deliver POSTs the final status of the job to its callback URL. Failed
+// attempts are retried with an exponential backoff, except for client
+// errors other than 408 and 429, until the context is canceled.
+func (q *JobQueue) deliver(ctx context.Context, j *Job) {
+	status := j.status()
+	status.Callback = nil
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		log.Printf("failed to marshal callback payload of job %v: %v", j.id, err)
+		j.setCallbackState(CallbackFailed)
+		return
+	}
+
+	j.setCallbackState(CallbackDelivering)
+
+	backoff := q.callbackBackoff
+
+	for attempt := 1; ; attempt++ {
+		statusCode, err := q.post(ctx, j, payload)
+
+		j.mu.Lock()
+		a := callbackAttempt{at: q.now(), statusCode: statusCode}
+		if err != nil {
+			a.err = err.Error()
+		}
+
+		j.callback.attempts = append(j.callback.attempts, a)
+		j.mu.Unlock()
+
+		if err == nil {
+			j.setCallbackState(CallbackDelivered)
+			return
+		}
+
+		log.Printf("callback attempt %v of job %v failed: %v", attempt, j.id, err)
+
+		if attempt >= q.callbackAttempts || !retryable(statusCode) {
+			j.setCallbackState(CallbackFailed)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			j.setCallbackState(CallbackFailed)
+			return
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// post sends the payload to the callback URL of the job.
+func (q *JobQueue) post(ctx context.Context, j *Job, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.request.CallbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set(JobHeader, j.id)
+
+	if j.request.CallbackSecret != "" {
+		req.Header.Set(SignatureHeader, Sign(j.request.CallbackSecret, payload))
+	}
+
+	if j.request.UserAgent != "" {
+		req.Header.Set("User-Agent", j.request.UserAgent)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("callback responded with code: %v", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// setCallbackState updates the delivery state of the callback.
+func (j *Job) setCallbackState(state string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.callback.state = state
+}
+
+// retryable checks if a failed callback attempt should be retried,
+// a zero status code denotes a failed request.
+func retryable(statusCode int) bool {
+	switch {
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests:
+		return true
+	case statusCode >= 400 && statusCode < 500:
+		return false
+	}
+
+	return true
+}
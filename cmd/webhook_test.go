@@ -0,0 +1,223 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"1","state":"done"}`)
+
+	tests := []struct {
+		Name      string
+		Secret    string
+		Signature string
+		Want      bool
+	}{
+		{Name: "ok", Secret: "secret", Signature: Sign("secret", payload), Want: true},
+		{Name: "fail-secret", Secret: "other", Signature: Sign("secret", payload)},
+		{Name: "fail-prefix", Secret: "secret", Signature: strings.TrimPrefix(Sign("secret", payload), "sha256=")},
+		{Name: "fail-empty", Secret: "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := VerifySignature(tt.Secret, payload, tt.Signature); have != tt.Want {
+				t.Errorf("VerifySignature() = %v, want %v", have, tt.Want)
+			}
+		})
+	}
+
+	// HMAC-SHA256 test vector of RFC 4231, test case 2.
+	if have := Sign("Jefe", []byte("what do ya want for nothing?")); have != "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		t.Errorf("Sign() = %v", have)
+	}
+}
+
+func TestJobCallback(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Callback</title></head><body></body></html>`))
+	}))
+	defer site.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		Name string
+
+		// Status codes the receiver responds with, 200 once exhausted.
+		Responses []int
+
+		wantState    string
+		wantAttempts []CallbackAttempt
+	}{
+		{
+			Name:         "ok",
+			wantState:    CallbackDelivered,
+			wantAttempts: []CallbackAttempt{{StatusCode: 200}},
+		},
+		{
+			Name:      "ok-retry",
+			Responses: []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			wantState: CallbackDelivered,
+			wantAttempts: []CallbackAttempt{
+				{StatusCode: 500, Error: "callback responded with code: 500"},
+				{StatusCode: 429, Error: "callback responded with code: 429"},
+				{StatusCode: 200},
+			},
+		},
+		{
+			Name:      "fail-attempts",
+			Responses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantState: CallbackFailed,
+			wantAttempts: []CallbackAttempt{
+				{StatusCode: 502, Error: "callback responded with code: 502"},
+				{StatusCode: 502, Error: "callback responded with code: 502"},
+				{StatusCode: 502, Error: "callback responded with code: 502"},
+			},
+		},
+		{
+			Name:         "fail-client-error",
+			Responses:    []int{http.StatusNotFound},
+			wantState:    CallbackFailed,
+			wantAttempts: []CallbackAttempt{{StatusCode: 404, Error: "callback responded with code: 404"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				received []*http.Request
+				payloads [][]byte
+			)
+
+			receiver := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				received, payloads = append(received, r), append(payloads, b)
+
+				if n := len(received); n <= len(tt.Responses) {
+					rw.WriteHeader(tt.Responses[n-1])
+				}
+			}))
+			defer receiver.Close()
+
+			q := NewJobQueue(ctx, 1, 1, time.Minute)
+			q.callbackAttempts, q.callbackBackoff = 3, time.Millisecond
+
+			j, err := q.Submit(JobRequest{
+				ParseHTMLRequest: ParseHTMLRequest{URL: site.URL + "/"},
+				CallbackURL:      receiver.URL + "/hook",
+				CallbackSecret:   "secret",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var have *JobStatus
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+				if have = j.status(); have.Callback.State == CallbackDelivered || have.Callback.State == CallbackFailed {
+					break
+				}
+			}
+
+			if have.Callback.State != tt.wantState || have.Callback.URL != receiver.URL+"/hook" {
+				t.Fatalf("callback = %+v, want state: %v", have.Callback, tt.wantState)
+			}
+
+			for i := range have.Callback.Attempts {
+				if have.Callback.Attempts[i].At == "" {
+					t.Errorf("attempt %v has no time", i)
+				}
+
+				have.Callback.Attempts[i].At = ""
+			}
+
+			if diff := cmp.Diff(have.Callback.Attempts, tt.wantAttempts); diff != "" {
+				t.Error(diff)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for i, r := range received {
+				if r.Header.Get(JobHeader) != have.ID || r.Header.Get("content-type") != "application/json" {
+					t.Errorf("unexpected callback headers: %v", r.Header)
+				}
+
+				if !VerifySignature("secret", payloads[i], r.Header.Get(SignatureHeader)) {
+					t.Errorf("invalid signature %v", r.Header.Get(SignatureHeader))
+				}
+
+				report := new(JobStatus)
+				if err := json.Unmarshal(payloads[i], report); err != nil {
+					t.Fatal(err)
+				}
+
+				if report.State != JobDone || report.Result == nil || report.Result.Title != "Callback" || report.Callback != nil {
+					t.Errorf("callback payload = %s", payloads[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSubmitJobCallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockServer := httptest.NewServer(submitJob(NewJobQueue(ctx, 1, 1, time.Minute)))
+	defer mockServer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, mockServer.URL, strings.NewReader(`{"url": "https://www.example.com", "callback_url": "/hook"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("submitJob() status code = %v, want: %v", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	if diff := cmp.Diff(string(b), `{"err":"invalid callback URL \"/hook\""}`); diff != "" {
+		t.Error(diff)
+	}
+}
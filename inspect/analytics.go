@@ -0,0 +1,223 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// PageRank parameters
+const (
+	PageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+// PageLinkMetrics are the internal link metrics of a crawled page.
+type PageLinkMetrics struct {
+	URL string
+
+	// Number of internal links pointing to the page from other pages.
+	Inlinks int
+
+	// Number of distinct pages linking to the page.
+	UniqueInlinks int
+
+	// Number of internal links from the page to other pages.
+	Outlinks int
+
+	// Minimum number of clicks needed to reach the page from the seed, -1 if unreachable.
+	ClickDepth int
+
+	// Internal PageRank of the page, the scores of all pages sum up to 1.
+	PageRank float64
+}
+
+// LinkAnalytics are the internal link metrics of a crawled site.
+type LinkAnalytics struct {
+	// Metrics of the crawled pages in crawl order.
+	Pages []PageLinkMetrics
+
+	// Sitemap URLs no crawled page links to.
+	Orphans []string
+
+	// Crawled HTML pages without links to other internal pages.
+	DeadEnds []string
+}
+
+// LinkAnalytics computes the internal link metrics of the crawled pages from the
+// link graph of the crawl. The URLs of the sitemaps are used to find the orphan
+// pages. Links with rel="nofollow" are counted but don't pass PageRank.
+func (s *Site) LinkAnalytics(sitemaps []*Sitemap) *LinkAnalytics {
+	var (
+		out   = new(LinkAnalytics)
+		index = make(map[string]int)
+
+		// linked holds the normalized URLs of every link target, including not crawled pages.
+		linked = make(map[string]struct{})
+	)
+
+	for i, page := range s.Pages {
+		index[page.URL] = i
+		out.Pages = append(out.Pages, PageLinkMetrics{URL: page.URL, ClickDepth: -1})
+	}
+
+	var (
+		sources  = make([]map[int]struct{}, len(s.Pages))
+		adjacent = make([][]int, len(s.Pages))
+		ranked   = make([]map[int]struct{}, len(s.Pages))
+	)
+
+	for i := range s.Pages {
+		sources[i], ranked[i] = make(map[int]struct{}), make(map[int]struct{})
+	}
+
+	for i, page := range s.Pages {
+		for _, e := range page.internalEdges() {
+			if e.To == e.From {
+				continue
+			}
+
+			linked[normalizeURL(e.To)] = struct{}{}
+			out.Pages[i].Outlinks++
+
+			j, ok := index[e.To]
+			if !ok {
+				continue
+			}
+
+			adjacent[i] = append(adjacent[i], j)
+			sources[j][i] = struct{}{}
+			out.Pages[j].Inlinks++
+
+			if !hasRel(e.Rel, "nofollow") {
+				ranked[i][j] = struct{}{}
+			}
+		}
+	}
+
+	for j := range s.Pages {
+		out.Pages[j].UniqueInlinks = len(sources[j])
+	}
+
+	if len(s.Pages) > 0 {
+		for i, depth := range clickDepths(adjacent) {
+			out.Pages[i].ClickDepth = depth
+		}
+
+		for i, rank := range pageRank(ranked) {
+			out.Pages[i].PageRank = rank
+		}
+	}
+
+	for i, page := range s.Pages {
+		if page.Contents != nil && page.Error == "" && out.Pages[i].Outlinks == 0 {
+			out.DeadEnds = append(out.DeadEnds, page.URL)
+		}
+	}
+
+	seed := normalizeURL(s.Seed)
+	seen := make(map[string]struct{})
+
+	for _, sitemap := range sitemaps {
+		for _, u := range sitemap.URLs {
+			key := normalizeURL(u.Loc)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			seen[key] = struct{}{}
+
+			if _, ok := linked[key]; !ok && key != seed {
+				out.Orphans = append(out.Orphans, u.Loc)
+			}
+		}
+	}
+
+	sort.Strings(out.Orphans)
+
+	return out
+}
+
+// clickDepths computes the shortest distance from the first node to every node, -1 if unreachable.
+func clickDepths(adjacent [][]int) []int {
+	out := make([]int, len(adjacent))
+	for i := range out {
+		out[i] = -1
+	}
+
+	out[0] = 0
+	queue := []int{0}
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		for _, j := range adjacent[i] {
+			if out[j] < 0 {
+				out[j] = out[i] + 1
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	return out
+}
+
+// pageRank computes the PageRank of the nodes by power iteration. The rank
+// of the nodes without outgoing links is distributed evenly to all nodes.
+func pageRank(links []map[int]struct{}) []float64 {
+	n := float64(len(links))
+
+	rank := make([]float64, len(links))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+
+	for iter := 0; iter < pageRankIterations; iter++ {
+		var dangling float64
+		for i, out := range links {
+			if len(out) == 0 {
+				dangling += rank[i]
+			}
+		}
+
+		next := make([]float64, len(links))
+		for i := range next {
+			next[i] = (1-PageRankDamping)/n + PageRankDamping*dangling/n
+		}
+
+		for i, out := range links {
+			for j := range out {
+				next[j] += PageRankDamping * rank[i] / float64(len(out))
+			}
+		}
+
+		var delta float64
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+
+		rank = next
+
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	return rank
+}
+
+// hasRel checks if the space separated rel value contains the link type.
+func hasRel(rel, linkType string) bool {
+	for _, r := range strings.Fields(rel) {
+		if r == linkType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLinkAnalytics(t *testing.T) {
+	page := func(link string, depth int, anchors ...Anchor) *CrawledPage {
+		return &CrawledPage{URL: link, FinalURL: link, Depth: depth, StatusCode: 200, Contents: &PageContents{Anchors: anchors}}
+	}
+
+	site := &Site{
+		Seed: "https://www.example.com/",
+		Pages: []*CrawledPage{
+			page("https://www.example.com/", 0,
+				Anchor{Href: "/a"},
+				Anchor{Href: "/b"},
+				Anchor{Href: "/b#top"},
+				Anchor{Href: "/"},
+				Anchor{Href: "https://www.google.com"},
+			),
+			page("https://www.example.com/a", 1, Anchor{Href: "/c", Rel: "nofollow"}, Anchor{Href: "/"}),
+			page("https://www.example.com/b", 1, Anchor{Href: "/uncrawled"}),
+			page("https://www.example.com/c", 2),
+			{URL: "https://www.example.com/gone", FinalURL: "https://www.example.com/gone", Depth: 2, StatusCode: 404, Error: "endpoint responded with code: 404"},
+		},
+	}
+
+	sitemaps := []*Sitemap{
+		{URLs: []SitemapURL{
+			{Loc: "https://www.example.com/"},
+			{Loc: "https://www.example.com/a"},
+			{Loc: "https://www.example.com/orphan"},
+			{Loc: "https://www.example.com/orphan"},
+		}},
+		{URLs: []SitemapURL{{Loc: "https://www.example.com/uncrawled"}}},
+	}
+
+	want := &LinkAnalytics{
+		Pages: []PageLinkMetrics{
+			{URL: "https://www.example.com/", Inlinks: 1, UniqueInlinks: 1, Outlinks: 3, ClickDepth: 0},
+			{URL: "https://www.example.com/a", Inlinks: 1, UniqueInlinks: 1, Outlinks: 2, ClickDepth: 1},
+			{URL: "https://www.example.com/b", Inlinks: 2, UniqueInlinks: 1, Outlinks: 1, ClickDepth: 1},
+			{URL: "https://www.example.com/c", Inlinks: 1, UniqueInlinks: 1, ClickDepth: 2},
+			{URL: "https://www.example.com/gone", ClickDepth: -1},
+		},
+		Orphans:  []string{"https://www.example.com/orphan"},
+		DeadEnds: []string{"https://www.example.com/c"},
+	}
+
+	have := site.LinkAnalytics(sitemaps)
+
+	if diff := cmp.Diff(have, want, cmpopts.IgnoreFields(PageLinkMetrics{}, "PageRank")); diff != "" {
+		t.Error(diff)
+	}
+
+	var sum float64
+	for _, p := range have.Pages {
+		sum += p.PageRank
+	}
+
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("LinkAnalytics() PageRank sum = %v, want 1", sum)
+	}
+
+	// the nofollow link from /a does not pass PageRank to /c.
+	if have.Pages[3].PageRank >= have.Pages[2].PageRank {
+		t.Errorf("LinkAnalytics() PageRank of /c = %v, want less than /b %v", have.Pages[3].PageRank, have.Pages[2].PageRank)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Links []map[int]struct{}
+		Want  []float64
+	}{
+		{
+			Name:  "ok-cycle",
+			Links: []map[int]struct{}{{1: {}}, {2: {}}, {0: {}}},
+			Want:  []float64{1.0 / 3, 1.0 / 3, 1.0 / 3},
+		},
+		{
+			Name:  "ok-dangling",
+			Links: []map[int]struct{}{{}, {}},
+			Want:  []float64{0.5, 0.5},
+		},
+		{
+			Name:  "ok-hub",
+			Links: []map[int]struct{}{{1: {}, 2: {}}, {0: {}}, {0: {}}},
+			Want:  []float64{0.9 / 1.85, (1 - 0.9/1.85) / 2, (1 - 0.9/1.85) / 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if diff := cmp.Diff(pageRank(tt.Links), tt.Want, cmpopts.EquateApprox(0, 1e-6)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,674 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Class and id regexes of the main content scoring.
+var (
+	reUnlikely    = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|\bads?\b|\bad-|advert`)
+	reMaybe       = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	rePositive    = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	reNegative    = regexp.MustCompile(`(?i)\bhid(den)?\b|banner|combx|comment|com-|contact|foot|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|\bads?\b|advert`)
+	reByline      = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+	reBylinePrefx = regexp.MustCompile(`(?i)^by\s+`)
+	reSpaces      = regexp.MustCompile(`\s+`)
+)
+
+// boilerplateElements are never part of the main content.
+var boilerplateElements = map[string]bool{
+	"aside": true, "button": true, "canvas": true, "dialog": true, "embed": true,
+	"footer": true, "form": true, "iframe": true, "input": true, "nav": true,
+	"noscript": true, "object": true, "script": true, "select": true, "style": true,
+	"svg": true, "template": true, "textarea": true,
+}
+
+// blockElements separate the paragraphs of the text of the main content.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// articleElements are kept in the simplified HTML, the other elements are
+// replaced by their children.
+var articleElements = map[string]bool{
+	"a": true, "b": true, "blockquote": true, "br": true, "code": true, "dd": true,
+	"dl": true, "dt": true, "em": true, "figcaption": true, "figure": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "i": true,
+	"img": true, "li": true, "ol": true, "p": true, "pre": true, "strong": true,
+	"table": true, "tbody": true, "td": true, "th": true, "thead": true, "tr": true,
+	"ul": true,
+}
+
+// articleAttributes are the attributes kept in the simplified HTML.
+var articleAttributes = map[string]bool{"href": true, "src": true, "alt": true}
+
+// publishedMeta are the <meta> names, properties and itemprops of the
+// publication date in the order of preference.
+var publishedMeta = []string{
+	"article:published_time",
+	"datepublished",
+	"og:published_time",
+	"pubdate",
+	"publish-date",
+	"date",
+	"dc.date",
+	"dcterms.created",
+}
+
+// Article is the main content of a page.
+type Article struct {
+	// Text of the content, paragraphs are separated by an empty line.
+	Text string
+
+	// HTML of the content with only the basic formatting elements and
+	// the href, src and alt attributes.
+	HTML string
+
+	// Author of the article, empty if not detected.
+	Byline string
+
+	// Publication date as RFC 3339 or YYYY-MM-DD if it could be parsed,
+	// empty if not detected.
+	Published string
+
+	// URL of the lead image as it appears in the page, empty if not detected.
+	Image string
+}
+
+// MainContent identifies the main content of the page in the style of the
+// readability algorithms. Paragraphs add a score based on their length and
+// number of commas to their ancestors, which are weighted by their tag, class
+// and id and by the density of the links in their text. The best scoring block
+// and its siblings of a similar score are the content. Navigation, footers,
+// asides, forms, hidden elements and blocks with classes like "sidebar" or
+// "ad" are excluded. Returns nil if the page has no text.
+func MainContent(root *html.Node) *Article {
+	body := root
+	if nodes, err := Select(root, "body"); err == nil && len(nodes) > 0 {
+		body = nodes[0]
+	}
+
+	s := &contentScorer{scores: make(map[*html.Node]float64)}
+	s.score(body)
+
+	var (
+		top      *html.Node
+		topScore = math.Inf(-1)
+	)
+
+	for _, c := range s.candidates {
+		if score := s.scores[c] * (1 - linkDensity(c)); score > topScore {
+			top, topScore = c, score
+		}
+	}
+
+	if top == nil {
+		top, topScore = body, 0
+	}
+
+	content := contentBlocks(s, top, topScore)
+
+	a := &Article{Byline: byline(root)}
+
+	skip := func(n *html.Node) bool {
+		return isBoilerplate(n) || (a.Byline != "" && isByline(n))
+	}
+
+	var (
+		text blockText
+		out  strings.Builder
+	)
+
+	for _, n := range content {
+		text.write(n, skip)
+		out.WriteString(renderArticle(n, skip))
+	}
+
+	text.flush()
+
+	a.Text = strings.Join(text.paragraphs, "\n\n")
+	a.HTML = out.String()
+
+	if a.Text == "" {
+		return nil
+	}
+
+	a.Published = published(root, content)
+	a.Image = leadImage(root, content)
+
+	return a
+}
+
+// contentScorer scores the ancestors of the paragraphs of a page.
+type contentScorer struct {
+	scores map[*html.Node]float64
+
+	// candidates are the scored elements in the order they were scored.
+	candidates []*html.Node
+}
+
+// score scores the paragraphs of the subtree, elements without block children
+// are paragraphs too.
+func (s *contentScorer) score(n *html.Node) {
+	if n.Type != html.ElementNode || isBoilerplate(n) {
+		return
+	}
+
+	switch strings.ToLower(n.Data) {
+	case "p", "pre", "td", "blockquote":
+		s.addParagraph(n)
+		return
+	case "div", "section", "article", "main":
+		if !hasBlockChild(n) {
+			s.addParagraph(n)
+			return
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s.score(c)
+	}
+}
+
+// addParagraph adds the score of the paragraph to its ancestors,
+// the more distant ones get a smaller share.
+func (s *contentScorer) addParagraph(p *html.Node) {
+	text := visibleText(p, isBoilerplate)
+
+	length := utf8.RuneCountInString(text)
+	if length < 25 {
+		return
+	}
+
+	score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length/100), 3)
+
+	level := 0
+	for a := p.Parent; a != nil && a.Type == html.ElementNode && level < 5; a = a.Parent {
+		if _, ok := s.scores[a]; !ok {
+			s.scores[a] = initialScore(a)
+			s.candidates = append(s.candidates, a)
+		}
+
+		switch level {
+		case 0:
+			s.scores[a] += score
+		case 1:
+			s.scores[a] += score / 2
+		default:
+			s.scores[a] += score / float64(level*3)
+		}
+
+		level++
+	}
+}
+
+// initialScore weights the element by its tag, class and id.
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+
+	switch strings.ToLower(n.Data) {
+	case "article", "main":
+		score += 25
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+
+	return score
+}
+
+// classWeight rewards content-like and penalizes boilerplate-like classes and ids.
+func classWeight(n *html.Node) float64 {
+	var weight float64
+
+	for _, key := range []string{"class", "id"} {
+		v, ok := getAttribute(n, key)
+		if !ok || v == "" {
+			continue
+		}
+
+		if reNegative.MatchString(v) {
+			weight -= 25
+		}
+
+		if rePositive.MatchString(v) {
+			weight += 25
+		}
+	}
+
+	return weight
+}
+
+// contentBlocks returns the top candidate and its siblings of a similar score
+// or that are paragraphs with little links.
+func contentBlocks(s *contentScorer, top *html.Node, topScore float64) []*html.Node {
+	if top.Parent == nil || top.Data == "body" {
+		return []*html.Node{top}
+	}
+
+	threshold := math.Max(10, topScore*0.2)
+
+	var out []*html.Node
+
+	for c := top.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || isBoilerplate(c) {
+			continue
+		}
+
+		include := c == top
+
+		if score, ok := s.scores[c]; ok && score*(1-linkDensity(c)) >= threshold {
+			include = true
+		}
+
+		if strings.ToLower(c.Data) == "p" {
+			text := visibleText(c, isBoilerplate)
+			length, density := utf8.RuneCountInString(text), linkDensity(c)
+
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				include = true
+			}
+		}
+
+		if include {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// isBoilerplate checks if the element is never part of the main content.
+func isBoilerplate(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return true
+	}
+
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	tag := strings.ToLower(n.Data)
+	if boilerplateElements[tag] || isHidden(n) {
+		return true
+	}
+
+	switch tag {
+	case "html", "body", "article", "main", "a":
+		return false
+	case "header":
+		// a header of the article itself is kept.
+		for a := n.Parent; a != nil; a = a.Parent {
+			if a.Type == html.ElementNode && (a.Data == "article" || a.Data == "main") {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	class, _ := getAttribute(n, "class")
+	id, _ := getAttribute(n, "id")
+
+	match := class + " " + id
+
+	return reUnlikely.MatchString(match) && !reMaybe.MatchString(match)
+}
+
+// isHidden checks if the element is hidden by an attribute or inline style.
+func isHidden(n *html.Node) bool {
+	if _, ok := getAttribute(n, "hidden"); ok {
+		return true
+	}
+
+	if v, _ := getAttribute(n, "aria-hidden"); strings.EqualFold(strings.TrimSpace(v), "true") {
+		return true
+	}
+
+	style, _ := getAttribute(n, "style")
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// isByline checks if the element holds the author of the article.
+func isByline(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	if rel, _ := getAttribute(n, "rel"); strings.EqualFold(rel, "author") {
+		return true
+	}
+
+	if prop, _ := getAttribute(n, "itemprop"); strings.Contains(strings.ToLower(prop), "author") {
+		return true
+	}
+
+	class, _ := getAttribute(n, "class")
+	id, _ := getAttribute(n, "id")
+
+	return reByline.MatchString(class + " " + id)
+}
+
+// hasBlockChild checks if the element has a block element child.
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockElements[strings.ToLower(c.Data)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// visibleText returns the collapsed text of the subtree without the skipped elements.
+func visibleText(n *html.Node, skip func(*html.Node) bool) string {
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if skip(n) {
+			return
+		}
+
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// linkDensity is the share of the text of the element inside links.
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(visibleText(n, isBoilerplate))
+	if length == 0 {
+		return 0
+	}
+
+	links := 0
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if isBoilerplate(n) {
+			return
+		}
+
+		if n.Type == html.ElementNode && strings.ToLower(n.Data) == "a" {
+			links += utf8.RuneCountInString(visibleText(n, isBoilerplate))
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(n)
+
+	return float64(links) / float64(length)
+}
+
+// blockText collects the paragraphs of a text with collapsed whitespace,
+// block elements start a new paragraph.
+type blockText struct {
+	paragraphs []string
+	current    strings.Builder
+}
+
+func (t *blockText) write(n *html.Node, skip func(*html.Node) bool) {
+	if skip(n) {
+		return
+	}
+
+	if n.Type == html.TextNode {
+		t.current.WriteString(n.Data)
+		return
+	}
+
+	block := n.Type == html.ElementNode && blockElements[strings.ToLower(n.Data)]
+	if block {
+		t.flush()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t.write(c, skip)
+	}
+
+	if block {
+		t.flush()
+	}
+}
+
+// flush ends the current paragraph.
+func (t *blockText) flush() {
+	if p := strings.Join(strings.Fields(t.current.String()), " "); p != "" {
+		t.paragraphs = append(t.paragraphs, p)
+	}
+
+	t.current.Reset()
+}
+
+// renderArticle returns the simplified HTML of the subtree. Lists and other
+// containers with mostly links are dropped, as are emptied elements.
+func renderArticle(n *html.Node, skip func(*html.Node) bool) string {
+	if skip(n) {
+		return ""
+	}
+
+	switch n.Type {
+	case html.TextNode:
+		text := reSpaces.ReplaceAllString(n.Data, " ")
+
+		// whitespace between blocks is dropped.
+		if strings.TrimSpace(text) == "" && (!isInline(n.PrevSibling) || !isInline(n.NextSibling)) {
+			return ""
+		}
+
+		return html.EscapeString(text)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	tag := strings.ToLower(n.Data)
+
+	switch tag {
+	case "ul", "ol", "dl", "table", "div", "section":
+		if linkDensity(n) > 0.5 {
+			return ""
+		}
+	}
+
+	var inner strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		inner.WriteString(renderArticle(c, skip))
+	}
+
+	if !articleElements[tag] {
+		return inner.String()
+	}
+
+	var b strings.Builder
+
+	b.WriteString("<" + tag)
+
+	for _, a := range n.Attr {
+		if key := strings.ToLower(a.Key); articleAttributes[key] {
+			b.WriteString(" " + key + `="` + html.EscapeString(a.Val) + `"`)
+		}
+	}
+
+	b.WriteString(">")
+
+	switch tag {
+	case "img", "br", "hr":
+		if _, ok := getAttribute(n, "src"); !ok && tag == "img" {
+			return ""
+		}
+
+		return b.String()
+	}
+
+	if strings.TrimSpace(inner.String()) == "" {
+		return ""
+	}
+
+	b.WriteString(inner.String())
+	b.WriteString("</" + tag + ">")
+
+	return b.String()
+}
+
+// isInline checks if the node is a text node or an inline element.
+func isInline(n *html.Node) bool {
+	if n == nil {
+		return false
+	}
+
+	return n.Type == html.TextNode || (n.Type == html.ElementNode && !blockElements[strings.ToLower(n.Data)])
+}
+
+// byline returns the author from the byline element or the author <meta>.
+func byline(root *html.Node) string {
+	var out string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		// the byline is often in the header of the page.
+		if out != "" || (isBoilerplate(n) && n.Data != "header") {
+			return
+		}
+
+		if isByline(n) {
+			text := reBylinePrefx.ReplaceAllString(Text(n), "")
+			if length := utf8.RuneCountInString(text); length > 0 && length < 100 {
+				out = text
+				return
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(root)
+
+	if out != "" {
+		return out
+	}
+
+	return metaContent(root, "author")
+}
+
+// published returns the publication date from the <meta> tags or the first
+// <time> element of the content.
+func published(root *html.Node, content []*html.Node) string {
+	date := ""
+
+	for _, name := range publishedMeta {
+		if date = metaContent(root, name); date != "" {
+			break
+		}
+	}
+
+	if date == "" {
+		for _, n := range content {
+			nodes, err := Select(n, "time[datetime]")
+			if err == nil && len(nodes) > 0 {
+				date, _ = getAttribute(nodes[0], "datetime")
+				break
+			}
+		}
+	}
+
+	date = strings.TrimSpace(date)
+
+	if parsed, err := ParseDate(date); err == nil {
+		return parsed
+	}
+
+	return date
+}
+
+// leadImage returns the Open Graph or Twitter card image or the first
+// image of the content.
+func leadImage(root *html.Node, content []*html.Node) string {
+	for _, name := range []string{"og:image", "twitter:image"} {
+		if image := metaContent(root, name); image != "" {
+			return image
+		}
+	}
+
+	for _, n := range content {
+		nodes, err := Select(n, "img[src]")
+		if err == nil && len(nodes) > 0 {
+			src, _ := getAttribute(nodes[0], "src")
+			return strings.TrimSpace(src)
+		}
+	}
+
+	return ""
+}
+
+// metaContent returns the content of the first <meta> element with the
+// name, property or itemprop, case-insensitively.
+func metaContent(root *html.Node, name string) string {
+	nodes, err := Select(root, "meta[content]")
+	if err != nil {
+		return ""
+	}
+
+	for _, n := range nodes {
+		for _, key := range []string{"name", "property", "itemprop"} {
+			if v, ok := getAttribute(n, key); ok && strings.EqualFold(strings.TrimSpace(v), name) {
+				content, _ := getAttribute(n, "content")
+				return strings.TrimSpace(content)
+			}
+		}
+	}
+
+	return ""
+}
+
+// articleExtractor reports the main content of the page.
+type articleExtractor struct {
+	article *Article
+}
+
+func (e *articleExtractor) Name() string { return ExtractorArticle }
+
+func (e *articleExtractor) Visit(node *html.Node) error {
+	if node.Type == html.DocumentNode {
+		e.article = MainContent(node)
+	}
+
+	return nil
+}
+
+func (e *articleExtractor) Result() interface{} { return e.article }
@@ -0,0 +1,138 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Growing tomatoes</title>
+	<meta property="article:published_time" content="2021-05-04T08:30:00Z">
+</head>
+<body>
+	<header class="site-header">
+		<a href="/">Garden Weekly</a>
+		<nav><a href="/news">News</a> <a href="/tips">Tips</a> <a href="/about">About</a></nav>
+	</header>
+	<div id="main" class="layout">
+		<article class="post">
+			<h1>Growing tomatoes</h1>
+			<p class="byline">By <a href="/authors/jane" rel="author">Jane Doe</a></p>
+			<p>Tomatoes need at least six hours of sun a day, a warm spot, and soil that drains well, which is why most gardeners start them indoors.</p>
+			<figure><img src="/img/tomatoes.jpg" alt="Tomatoes" class="wide"><figcaption>Ripe tomatoes</figcaption></figure>
+			<div class="ad-slot">Buy the best fertilizer now, limited offer, only today!</div>
+			<p>Water them deeply, but not too often, so the roots grow down into the soil instead of staying near the surface, where it dries out quickly.</p>
+			<p style="display:none">Hidden text that should never be part of the article content at all.</p>
+			<ul class="related">
+				<li><a href="/peppers">Growing peppers in containers on a balcony</a></li>
+				<li><a href="/basil">Basil, the perfect companion plant for tomatoes</a></li>
+			</ul>
+			<p>Pick the fruit when it is <em>fully coloured</em> and slightly soft.</p>
+		</article>
+		<aside>Popular: ten tips for a greener lawn, how to compost, and more.</aside>
+	</div>
+	<footer>Copyright 2021 Garden Weekly, all rights reserved, contact us for reprints.</footer>
+</body>
+</html>`
+
+func TestMainContent(t *testing.T) {
+	tests := []struct {
+		Name string
+		Page string
+		want *Article
+	}{
+		{
+			Name: "ok",
+			Page: articlePage,
+			want: &Article{
+				Text: strings.Join([]string{
+					"Growing tomatoes",
+					"Tomatoes need at least six hours of sun a day, a warm spot, and soil that drains well, which is why most gardeners start them indoors.",
+					"Ripe tomatoes",
+					"Water them deeply, but not too often, so the roots grow down into the soil instead of staying near the surface, where it dries out quickly.",
+					"Pick the fruit when it is fully coloured and slightly soft.",
+				}, "\n\n"),
+				HTML: `<h1>Growing tomatoes</h1>` +
+					`<p>Tomatoes need at least six hours of sun a day, a warm spot, and soil that drains well, which is why most gardeners start them indoors.</p>` +
+					`<figure><img src="/img/tomatoes.jpg" alt="Tomatoes"><figcaption>Ripe tomatoes</figcaption></figure>` +
+					`<p>Water them deeply, but not too often, so the roots grow down into the soil instead of staying near the surface, where it dries out quickly.</p>` +
+					`<p>Pick the fruit when it is <em>fully coloured</em> and slightly soft.</p>`,
+				Byline:    "Jane Doe",
+				Published: "2021-05-04T08:30:00Z",
+				Image:     "/img/tomatoes.jpg",
+			},
+		},
+		{
+			Name: "ok-siblings",
+			Page: `<html><head><meta property="og:image" content="https://cdn.example.com/lead.png"><meta name="author" content="John Roe"></head><body>
+				<div class="sidebar"><p>Subscribe to our newsletter, get weekly updates, tips and more.</p></div>
+				<div class="content">
+					<p>First paragraph of the story, long enough to be scored, with a comma.</p>
+					<p>Second paragraph of the story, also long enough, with two, commas.</p>
+				</div>
+				<p>A closing remark right after the content. It has a full sentence.</p>
+				<p>Short.</p>
+				<time datetime="2021-05-06">May 6</time>
+			</body></html>`,
+			want: &Article{
+				Text: strings.Join([]string{
+					"First paragraph of the story, long enough to be scored, with a comma.",
+					"Second paragraph of the story, also long enough, with two, commas.",
+					"A closing remark right after the content. It has a full sentence.",
+				}, "\n\n"),
+				HTML: `<p>First paragraph of the story, long enough to be scored, with a comma.</p>` +
+					`<p>Second paragraph of the story, also long enough, with two, commas.</p>` +
+					`<p>A closing remark right after the content. It has a full sentence.</p>`,
+				Byline: "John Roe",
+				Image:  "https://cdn.example.com/lead.png",
+			},
+		},
+		{
+			Name: "ok-no-text",
+			Page: `<html><body><nav><a href="/">Home</a></nav><script>var x = 1;</script></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.Page))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(MainContent(root), tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestArticleExtractor(t *testing.T) {
+	extractors, err := NewExtractors(ExtractorArticle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := Page(strings.NewReader(articlePage), extractors...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	article, ok := contents.Sections[ExtractorArticle].(*Article)
+	if !ok || article.Byline != "Jane Doe" {
+		t.Errorf("Page() article section = %v", contents.Sections[ExtractorArticle])
+	}
+
+	// the extractor must not change the DOM the other contents are extracted from.
+	if diff := cmp.Diff(contents.Headings, map[string]int{"h1": 1}); diff != "" {
+		t.Error(diff)
+	}
+}
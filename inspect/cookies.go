@@ -0,0 +1,127 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxCookieSize is the size browsers are guaranteed to store for a single cookie.
+const maxCookieSize = 4096
+
+// reSessionCookie matches names of cookies that likely hold a session.
+var reSessionCookie = regexp.MustCompile(`(?i)(sess|sid|auth|token|jwt|login|remember)`)
+
+// Cookie is a cookie set by the response of an inspected page.
+type Cookie struct {
+	Name   string
+	Domain string
+	Path   string
+
+	// Expiry of the cookie, zero for session cookies.
+	Expires time.Time
+
+	// Max-Age in seconds, 0 if not set and -1 if the cookie is deleted.
+	MaxAge int
+
+	Secure   bool
+	HttpOnly bool
+
+	// SameSite attribute, empty if not set.
+	SameSite string
+
+	// Size of the name and value in bytes.
+	Size int
+
+	// Issues found with the attributes of the cookie.
+	Findings []Finding
+}
+
+// Cookies analyses the cookies set by the response of the page at pageURL.
+func Cookies(cookies []*http.Cookie, pageURL url.URL) []Cookie {
+	var out []Cookie
+
+	for _, c := range cookies {
+		cookie := Cookie{
+			Name:     c.Name,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Expires:  c.Expires,
+			MaxAge:   c.MaxAge,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: sameSite(c.SameSite),
+			Size:     len(c.Name) + len(c.Value),
+		}
+
+		cookie.Findings = cookie.check(pageURL)
+		out = append(out, cookie)
+	}
+
+	return out
+}
+
+// check reports insecure attributes of the cookie.
+func (c *Cookie) check(pageURL url.URL) []Finding {
+	var (
+		out     []Finding
+		session = reSessionCookie.MatchString(c.Name)
+	)
+
+	if session && !c.Secure {
+		out = append(out, Finding{SeverityHigh, "secure", fmt.Sprintf("session cookie %v is sent over unencrypted connections", c.Name)})
+	}
+
+	if session && !c.HttpOnly {
+		out = append(out, Finding{SeverityMedium, "httponly", fmt.Sprintf("session cookie %v is readable by scripts", c.Name)})
+	}
+
+	if c.SameSite == "None" && !c.Secure {
+		out = append(out, Finding{SeverityHigh, "samesite", fmt.Sprintf("cookie %v uses SameSite=None without Secure and is rejected by browsers", c.Name)})
+	}
+
+	if c.Domain != "" {
+		host := strings.ToLower(pageURL.Hostname())
+
+		if suffix, _ := publicsuffix.PublicSuffix(c.Domain); suffix == c.Domain {
+			out = append(out, Finding{SeverityHigh, "domain", fmt.Sprintf("cookie %v is scoped to the public suffix %v", c.Name, c.Domain)})
+		} else if c.Domain != host {
+			out = append(out, Finding{SeverityMedium, "domain", fmt.Sprintf("cookie %v is shared with every subdomain of %v", c.Name, c.Domain)})
+		}
+	}
+
+	if strings.HasPrefix(c.Name, "__Secure-") && !c.Secure {
+		out = append(out, Finding{SeverityMedium, "prefix", fmt.Sprintf("cookie %v with the __Secure- prefix requires Secure", c.Name)})
+	}
+
+	if strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || c.Domain != "" || c.Path != "/") {
+		out = append(out, Finding{SeverityMedium, "prefix", fmt.Sprintf("cookie %v with the __Host- prefix requires Secure, Path=/ and no Domain", c.Name)})
+	}
+
+	if c.Size > maxCookieSize {
+		out = append(out, Finding{SeverityLow, "size", fmt.Sprintf("cookie %v exceeds %v bytes", c.Name, maxCookieSize)})
+	}
+
+	return out
+}
+
+func sameSite(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCookies(t *testing.T) {
+	pageURL, err := url.Parse("https://www.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name      string
+		setCookie []string
+		want      []Cookie
+	}{
+		{
+			Name:      "ok-empty",
+			setCookie: nil,
+			want:      nil,
+		},
+		{
+			Name: "ok-secure",
+			setCookie: []string{
+				"__Host-SESSIONID=abc; Path=/; Secure; HttpOnly; SameSite=Strict; Max-Age=3600",
+				"theme=dark; Expires=Wed, 21 Oct 2015 07:28:00 GMT",
+			},
+			want: []Cookie{
+				{
+					Name:     "__Host-SESSIONID",
+					Path:     "/",
+					MaxAge:   3600,
+					Secure:   true,
+					HttpOnly: true,
+					SameSite: "Strict",
+					Size:     19,
+				},
+				{
+					Name:    "theme",
+					Expires: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+					Size:    9,
+				},
+			},
+		},
+		{
+			Name: "ok-insecure",
+			setCookie: []string{
+				"PHPSESSID=abc; Domain=.example.com; SameSite=None",
+				"tracking=1; Domain=com",
+				"__Secure-id=1",
+				"big=" + strings.Repeat("a", 4096),
+			},
+			want: []Cookie{
+				{
+					Name:     "PHPSESSID",
+					Domain:   "example.com",
+					SameSite: "None",
+					Size:     12,
+					Findings: []Finding{
+						{SeverityHigh, "secure", "session cookie PHPSESSID is sent over unencrypted connections"},
+						{SeverityMedium, "httponly", "session cookie PHPSESSID is readable by scripts"},
+						{SeverityHigh, "samesite", "cookie PHPSESSID uses SameSite=None without Secure and is rejected by browsers"},
+						{SeverityMedium, "domain", "cookie PHPSESSID is shared with every subdomain of example.com"},
+					},
+				},
+				{
+					Name:   "tracking",
+					Domain: "com",
+					Size:   9,
+					Findings: []Finding{
+						{SeverityHigh, "domain", "cookie tracking is scoped to the public suffix com"},
+					},
+				},
+				{
+					Name: "__Secure-id",
+					Size: 12,
+					Findings: []Finding{
+						{SeverityMedium, "prefix", "cookie __Secure-id with the __Secure- prefix requires Secure"},
+					},
+				},
+				{
+					Name: "big",
+					Size: 4099,
+					Findings: []Finding{
+						{SeverityLow, "size", "cookie big exceeds 4096 bytes"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{"Set-Cookie": tt.setCookie}}
+
+			have := Cookies(resp.Cookies(), *pageURL)
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
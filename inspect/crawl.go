@@ -0,0 +1,397 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Crawl defaults
+const (
+	DefaultMaxDepth    = 3
+	DefaultMaxPages    = 100
+	DefaultMaxPageSize = 10 << 20
+	DefaultParallelism = 4
+)
+
+// CrawlOptions configures a crawl.
+type CrawlOptions struct {
+	// Maximum number of links followed from the seed, DefaultMaxDepth if 0.
+	MaxDepth int
+
+	// Maximum number of pages fetched, DefaultMaxPages if 0.
+	MaxPages int
+
+	// Maximum number of bytes of a page that will be inspected, DefaultMaxPageSize if 0.
+	MaxPageSize int64
+
+	// Maximum number of pages fetched concurrently, DefaultParallelism if 0.
+	Parallelism int
+
+	// If not empty only URLs matching at least one of the patterns are followed.
+	Include []*regexp.Regexp
+
+	// URLs matching any of the patterns are not followed.
+	Exclude []*regexp.Regexp
+}
+
+// CrawledPage is a page visited by the crawler.
+type CrawledPage struct {
+	URL string
+
+	// URL of the page after following redirects.
+	FinalURL string
+
+	// Number of links followed from the seed.
+	Depth int
+
+	StatusCode int
+	Header     http.Header
+
+	// Contents of the page, nil if the page is not a HTML page.
+	Contents *PageContents
+
+	// Absolute internal links found on the page.
+	Links []string
+
+	// Reason the page could not be inspected.
+	Error string
+}
+
+// Site is the result of a crawl.
+type Site struct {
+	Seed string
+
+	// Visited pages in breadth-first order.
+	Pages []*CrawledPage
+
+	// If the crawl stopped because it reached the maximum number of pages.
+	Truncated bool
+}
+
+// SiteSummary contains site-wide statistics of a crawl.
+type SiteSummary struct {
+	Pages  int
+	Errors int
+
+	// Maps status codes to the number of pages that responded with it.
+	StatusCodes map[int]int
+
+	// Maps HTML versions to the number of pages using it.
+	Versions map[string]int
+
+	// Number of distinct internal and external links.
+	InternalLinks int
+	ExternalLinks int
+
+	// Deepest level reached from the seed.
+	MaxDepth int
+
+	Truncated bool
+}
+
+// Crawl visits the pages of the site breadth-first starting from the seed,
+// following the internal links discovered by Page. The requests are issued
+// through the fetcher.
+func Crawl(ctx context.Context, seed url.URL, f *Fetcher, opts CrawlOptions) (*Site, error) {
+	if seed.Scheme != "http" && seed.Scheme != "https" {
+		return nil, fmt.Errorf("inspect.Crawl: unsupported seed URL %q", seed.String())
+	}
+
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = DefaultMaxPageSize
+	}
+
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DefaultParallelism
+	}
+
+	seed.Fragment = ""
+
+	var (
+		site    = &Site{Seed: seed.String()}
+		visited = map[string]struct{}{seed.String(): {}}
+		level   = []string{seed.String()}
+
+		// host of the site, the seed may redirect to another host.
+		host = seed.Host
+	)
+
+	for depth := 0; len(level) > 0; depth++ {
+		if remaining := opts.MaxPages - len(site.Pages); len(level) > remaining {
+			level, site.Truncated = level[:remaining], true
+		}
+
+		pages := crawlLevel(ctx, level, depth, f, opts)
+		site.Pages = append(site.Pages, pages...)
+
+		if ctx.Err() != nil {
+			return site, fmt.Errorf("inspect.Crawl: %w", ctx.Err())
+		}
+
+		if depth == 0 {
+			if u, err := url.Parse(pages[0].FinalURL); err == nil {
+				host = u.Host
+				visited[pages[0].FinalURL] = struct{}{}
+			}
+		}
+
+		if depth >= opts.MaxDepth || site.Truncated {
+			break
+		}
+
+		var next []string
+
+		for _, page := range pages {
+			for _, link := range page.Links {
+				if _, ok := visited[link]; ok || !opts.follow(link, host) {
+					continue
+				}
+
+				visited[link] = struct{}{}
+				next = append(next, link)
+			}
+		}
+
+		level = next
+	}
+
+	return site, nil
+}
+
+// crawlLevel concurrently visits the links of one level of the crawl,
+// at most opts.Parallelism at a time.
+func crawlLevel(ctx context.Context, links []string, depth int, f *Fetcher, opts CrawlOptions) []*CrawledPage {
+	out := make([]*CrawledPage, len(links))
+
+	forEach(len(links), opts.Parallelism, func(i int) {
+		out[i] = crawlPage(ctx, links[i], depth, f, opts.MaxPageSize)
+	})
+
+	return out
+}
+
+// forEach calls fn with the indices from 0 to n-1 from at most
+// parallelism goroutines and waits until all calls return.
+func forEach(n, parallelism int, fn func(i int)) {
+	if parallelism <= 0 || parallelism > n {
+		parallelism = n
+	}
+
+	var (
+		indices = make(chan int)
+		wg      = new(sync.WaitGroup)
+	)
+
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+}
+
+// crawlPage fetches and inspects a single page.
+func crawlPage(ctx context.Context, link string, depth int, f *Fetcher, maxPageSize int64) *CrawledPage {
+	page := &CrawledPage{URL: link, FinalURL: link, Depth: depth}
+
+	resp, err := f.Get(ctx, link)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	defer resp.Body.Close()
+
+	page.StatusCode = resp.StatusCode
+	page.Header = resp.Header
+	page.FinalURL = resp.Request.URL.String()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		page.Error = fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode)
+		return page
+	}
+
+	// an empty content-type is accepted.
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !IsHTML(contentType) {
+		page.Error = fmt.Sprintf("not a HTML page: %v", contentType)
+		return page
+	}
+
+	// pages exceeding the maximum size are inspected partially.
+	contents, err := PageStream(ctx, resp.Body, StreamOptions{MaxSize: maxPageSize})
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+
+	page.Contents = contents
+	page.Links = contents.InternalLinks(*resp.Request.URL)
+
+	return page
+}
+
+// follow checks if the crawler should follow the link.
+func (o *CrawlOptions) follow(link, host string) bool {
+	u, err := url.Parse(link)
+	if err != nil || !strings.EqualFold(u.Host, host) {
+		return false
+	}
+
+	for _, re := range o.Exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+
+	if len(o.Include) == 0 {
+		return true
+	}
+
+	for _, re := range o.Include {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InternalLinks returns the sorted absolute URLs of the links pointing to the
+// host of base. Relative links are resolved against base, fragments are removed
+// and links with other schemes than http and https are skipped.
+func (p *PageContents) InternalLinks(base url.URL) []string {
+	var (
+		out  []string
+		seen = make(map[string]struct{})
+	)
+
+	for domain, links := range p.Links {
+		if domain != "" && !strings.EqualFold(domain, base.Hostname()) {
+			continue
+		}
+
+		for l := range links {
+			u, err := base.Parse(strings.TrimSpace(l))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, base.Host) {
+				continue
+			}
+
+			u.Fragment, u.RawFragment = "", ""
+
+			if _, ok := seen[u.String()]; !ok {
+				seen[u.String()] = struct{}{}
+				out = append(out, u.String())
+			}
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// Summary computes the site-wide statistics of the crawl.
+func (s *Site) Summary() SiteSummary {
+	var (
+		out = SiteSummary{
+			Pages:       len(s.Pages),
+			StatusCodes: make(map[int]int),
+			Versions:    make(map[string]int),
+			Truncated:   s.Truncated,
+		}
+
+		internal = make(map[string]struct{})
+		external = make(map[string]struct{})
+	)
+
+	for _, page := range s.Pages {
+		if page.Error != "" || page.StatusCode >= 400 {
+			out.Errors++
+		}
+
+		if page.StatusCode != 0 {
+			out.StatusCodes[page.StatusCode]++
+		}
+
+		if page.Depth > out.MaxDepth {
+			out.MaxDepth = page.Depth
+		}
+
+		for _, l := range page.Links {
+			internal[l] = struct{}{}
+		}
+
+		if page.Contents == nil {
+			continue
+		}
+
+		if page.Contents.Version != "" {
+			out.Versions[page.Contents.Version]++
+		}
+
+		u, err := url.Parse(page.FinalURL)
+		if err != nil {
+			continue
+		}
+
+		for domain, links := range page.Contents.Links {
+			if domain == "" || strings.EqualFold(domain, u.Hostname()) {
+				continue
+			}
+
+			for l := range links {
+				external[l] = struct{}{}
+			}
+		}
+	}
+
+	out.InternalLinks = len(internal)
+	out.ExternalLinks = len(external)
+
+	return out
+}
+
+// IsHTML checks if the media type of the content-type is a HTML document.
+func IsHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(mediaType) {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+
+	return false
+}
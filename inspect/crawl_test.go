@@ -0,0 +1,321 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockSite() *httptest.Server {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>Home</title></head><body>
+			<a href="/a">a</a>
+			<a href="b#section">b</a>
+			<a href="/logo.png">logo</a>
+			<a href="mailto:info@example.com">mail</a>
+			<a href="https://www.google.com">google</a>
+			<a href="#top">top</a>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/a", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>A</title></head><body>
+			<a href="/">home</a>
+			<a href="/private/c">c</a>
+			<a href="/b">b</a>
+		</body></html>`))
+	})
+
+	r.HandleFunc("/private/c", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<html><head><title>C</title></head><body><a href="/d">d</a></body></html>`))
+	})
+
+	r.HandleFunc("/d", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<!DOCTYPE html><html><head><title>D</title></head><body></body></html>`))
+	})
+
+	r.HandleFunc("/logo.png", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "image/png")
+		rw.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+
+	return httptest.NewServer(r)
+}
+
+func TestCrawl(t *testing.T) {
+	mockServer := mockSite()
+	defer mockServer.Close()
+
+	seed, err := url.Parse(mockServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type page struct {
+		URL        string
+		Depth      int
+		StatusCode int
+		Title      string
+		Links      []string
+		Error      string
+	}
+
+	tests := []struct {
+		Name          string
+		opts          CrawlOptions
+		wantPages     []page
+		wantTruncated bool
+	}{
+		{
+			Name: "ok",
+			opts: CrawlOptions{},
+			wantPages: []page{
+				{URL: "/", Depth: 0, StatusCode: 200, Title: "Home", Links: []string{"/", "/a", "/b", "/logo.png"}},
+				{URL: "/a", Depth: 1, StatusCode: 200, Title: "A", Links: []string{"/", "/b", "/private/c"}},
+				{URL: "/b", Depth: 1, StatusCode: 404, Error: "endpoint responded with code: 404"},
+				{URL: "/logo.png", Depth: 1, StatusCode: 200, Error: "not a HTML page: image/png"},
+				{URL: "/private/c", Depth: 2, StatusCode: 200, Title: "C", Links: []string{"/d"}},
+				{URL: "/d", Depth: 3, StatusCode: 200, Title: "D"},
+			},
+		},
+		{
+			Name: "ok-max-depth",
+			opts: CrawlOptions{MaxDepth: 1},
+			wantPages: []page{
+				{URL: "/", Depth: 0, StatusCode: 200, Title: "Home", Links: []string{"/", "/a", "/b", "/logo.png"}},
+				{URL: "/a", Depth: 1, StatusCode: 200, Title: "A", Links: []string{"/", "/b", "/private/c"}},
+				{URL: "/b", Depth: 1, StatusCode: 404, Error: "endpoint responded with code: 404"},
+				{URL: "/logo.png", Depth: 1, StatusCode: 200, Error: "not a HTML page: image/png"},
+			},
+		},
+		{
+			Name: "ok-max-pages",
+			opts: CrawlOptions{MaxPages: 2},
+			wantPages: []page{
+				{URL: "/", Depth: 0, StatusCode: 200, Title: "Home", Links: []string{"/", "/a", "/b", "/logo.png"}},
+				{URL: "/a", Depth: 1, StatusCode: 200, Title: "A", Links: []string{"/", "/b", "/private/c"}},
+			},
+			wantTruncated: true,
+		},
+		{
+			Name: "ok-include-exclude",
+			opts: CrawlOptions{
+				Include: []*regexp.Regexp{regexp.MustCompile(`/(a|private/.*)$`)},
+				Exclude: []*regexp.Regexp{regexp.MustCompile(`/private/`)},
+			},
+			wantPages: []page{
+				{URL: "/", Depth: 0, StatusCode: 200, Title: "Home", Links: []string{"/", "/a", "/b", "/logo.png"}},
+				{URL: "/a", Depth: 1, StatusCode: 200, Title: "A", Links: []string{"/", "/b", "/private/c"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			site, err := Crawl(context.Background(), *seed, NewFetcher(FetchOptions{Concurrency: 2}), tt.opts)
+			if err != nil {
+				t.Fatalf("Crawl() err = %v", err)
+			}
+
+			var have []page
+			for _, p := range site.Pages {
+				hp := page{
+					URL:        p.URL[len(mockServer.URL):],
+					Depth:      p.Depth,
+					StatusCode: p.StatusCode,
+					Error:      p.Error,
+				}
+
+				if p.Contents != nil {
+					hp.Title = p.Contents.Title
+				}
+
+				for _, l := range p.Links {
+					hp.Links = append(hp.Links, l[len(mockServer.URL):])
+				}
+
+				have = append(have, hp)
+			}
+
+			if diff := cmp.Diff(have, tt.wantPages); diff != "" {
+				t.Error(diff)
+			}
+
+			if site.Truncated != tt.wantTruncated {
+				t.Errorf("Crawl() truncated = %v, want %v", site.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCrawlSeedRedirect(t *testing.T) {
+	mockServer := mockSite()
+	defer mockServer.Close()
+
+	redirectServer := httptest.NewServer(http.RedirectHandler(mockServer.URL+"/", http.StatusMovedPermanently))
+	defer redirectServer.Close()
+
+	seed, err := url.Parse(redirectServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Crawl(context.Background(), *seed, NewFetcher(FetchOptions{}), CrawlOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var have []string
+	for _, p := range site.Pages {
+		have = append(have, p.FinalURL)
+	}
+
+	// the links are followed on the host the seed redirected to, the seed is not visited twice.
+	want := []string{mockServer.URL + "/", mockServer.URL + "/a", mockServer.URL + "/b", mockServer.URL + "/logo.png"}
+
+	if diff := cmp.Diff(have, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCrawlParallelism(t *testing.T) {
+	const parallelism = 2
+
+	var inFlight, maxInFlight int32
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		if r.URL.Path != "/" {
+			rw.Write([]byte(`<html><body></body></html>`))
+			return
+		}
+
+		rw.Write([]byte(`<html><body>`))
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(rw, `<a href="/%d">%d</a>`, i, i)
+		}
+		rw.Write([]byte(`</body></html>`))
+	}))
+	defer mockServer.Close()
+
+	seed, err := url.Parse(mockServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Crawl(context.Background(), *seed, NewFetcher(FetchOptions{}), CrawlOptions{Parallelism: parallelism})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(site.Pages) != 11 {
+		t.Errorf("crawled %d pages, want 11", len(site.Pages))
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > parallelism {
+		t.Errorf("%d concurrent requests, want at most %d", max, parallelism)
+	}
+}
+
+func TestCrawlInvalidSeed(t *testing.T) {
+	if _, err := Crawl(context.Background(), url.URL{Path: "/relative"}, NewFetcher(FetchOptions{}), CrawlOptions{}); err == nil {
+		t.Error("Crawl() err = nil, want error for relative seed")
+	}
+}
+
+func TestSiteSummary(t *testing.T) {
+	mockServer := mockSite()
+	defer mockServer.Close()
+
+	seed, err := url.Parse(mockServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site, err := Crawl(context.Background(), *seed, NewFetcher(FetchOptions{}), CrawlOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SiteSummary{
+		Pages:         6,
+		Errors:        2,
+		StatusCodes:   map[int]int{200: 5, 404: 1},
+		Versions:      map[string]int{Version5: 3},
+		InternalLinks: 6,
+		ExternalLinks: 1,
+		MaxDepth:      3,
+	}
+
+	if diff := cmp.Diff(site.Summary(), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestInternalLinks(t *testing.T) {
+	base, err := url.Parse("https://www.example.com/blog/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := &PageContents{
+		Links: map[string]map[string]struct{}{
+			"": {
+				"#":                  {},
+				"other":              {},
+				"/about#team":        {},
+				"../contact":         {},
+				"mailto:a@b.com":     {},
+				"javascript:void(0)": {},
+			},
+			"www.example.com": {
+				"https://www.example.com/about": {},
+				"http://www.example.com/":       {},
+			},
+			"www.google.com": {
+				"https://www.google.com": {},
+			},
+		},
+	}
+
+	want := []string{
+		"http://www.example.com/",
+		"https://www.example.com/about",
+		"https://www.example.com/blog/other",
+		"https://www.example.com/blog/post",
+		"https://www.example.com/contact",
+	}
+
+	if diff := cmp.Diff(pc.InternalLinks(*base), want); diff != "" {
+		t.Error(diff)
+	}
+}
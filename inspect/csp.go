@@ -0,0 +1,630 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Kinds of inline content subject to a Content-Security-Policy.
+const (
+	InlineScript         = "script"
+	InlineStyle          = "style"
+	InlineEventHandler   = "event-handler"
+	InlineStyleAttribute = "style-attribute"
+	InlineJavascriptURL  = "javascript-url"
+)
+
+// Kinds of external resources loaded by a page.
+const (
+	ResourceScript = "script"
+	ResourceStyle  = "style"
+	ResourceImage  = "img"
+	ResourceMedia  = "media"
+	ResourceFrame  = "frame"
+	ResourceObject = "object"
+)
+
+// cspFallbacks lists the directives consulted, in order,
+// when a resource or an inline content of a kind is loaded.
+var cspFallbacks = map[string][]string{
+	ResourceScript:       {"script-src-elem", "script-src", "default-src"},
+	ResourceStyle:        {"style-src-elem", "style-src", "default-src"},
+	ResourceImage:        {"img-src", "default-src"},
+	ResourceMedia:        {"media-src", "default-src"},
+	ResourceFrame:        {"frame-src", "child-src", "default-src"},
+	ResourceObject:       {"object-src", "default-src"},
+	InlineEventHandler:   {"script-src-attr", "script-src", "default-src"},
+	InlineStyleAttribute: {"style-src-attr", "style-src", "default-src"},
+	InlineJavascriptURL:  {"script-src-elem", "script-src", "default-src"},
+}
+
+// InlineContent is a piece of inline code found on the page.
+type InlineContent struct {
+	Kind string
+
+	// Nonce attribute of the element.
+	Nonce string
+
+	// Source of the script, style, handler or URL.
+	Content string
+}
+
+// Subresource is an external resource loaded by the page.
+type Subresource struct {
+	Kind string
+
+	// URL as written in the document.
+	URL string
+
+	// Nonce attribute of the element.
+	Nonce string
+}
+
+// CSP is a parsed Content-Security-Policy.
+type CSP struct {
+	// Maps lower cased directive names to their source lists.
+	Directives map[string][]string
+}
+
+// CSPViolation is a part of the page that a browser would block.
+type CSPViolation struct {
+	// Directive that blocked the resource.
+	Directive string
+
+	Kind string
+
+	// URL of the resource or a snippet of the inline content.
+	Resource string
+}
+
+// CSPReport is the result of evaluating a page against its policies.
+type CSPReport struct {
+	// Policies that were evaluated.
+	Policies []string
+
+	// Resources and inline content the browser would block.
+	Blocked []CSPViolation
+
+	// Unsafe or missing directives.
+	Findings []Finding
+}
+
+// ParseCSP parses a serialized Content-Security-Policy.
+// Only the first occurrence of a directive is used.
+func ParseCSP(policy string) *CSP {
+	out := &CSP{Directives: make(map[string][]string)}
+
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if _, ok := out.Directives[name]; ok {
+			continue
+		}
+
+		out.Directives[name] = fields[1:]
+	}
+
+	return out
+}
+
+// Has checks if the policy contains the directive.
+func (c *CSP) Has(directive string) bool {
+	_, ok := c.Directives[directive]
+	return ok
+}
+
+// effective returns the directive governing the kind together with its sources.
+func (c *CSP) effective(kind string) (string, []string, bool) {
+	for _, name := range cspFallbacks[kind] {
+		if sources, ok := c.Directives[name]; ok {
+			return name, sources, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// CheckCSP evaluates the page against the policies delivered through the
+// response headers and the <meta http-equiv> elements of the page.
+// The pageURL is used to resolve relative URLs and the 'self' keyword.
+// Returns nil if no policy applies to the page.
+func (p *PageContents) CheckCSP(pageURL url.URL, headerPolicies []string) *CSPReport {
+	var raw []string
+	for _, policy := range append(append([]string{}, headerPolicies...), p.MetaCSP...) {
+		if strings.TrimSpace(policy) != "" {
+			raw = append(raw, policy)
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var (
+		out      = &CSPReport{Policies: raw}
+		policies = make([]*CSP, 0, len(raw))
+	)
+
+	for _, policy := range raw {
+		csp := ParseCSP(policy)
+		policies = append(policies, csp)
+		out.Findings = append(out.Findings, csp.findings()...)
+	}
+
+	out.Findings = append(out.Findings, missingFindings(policies)...)
+
+	for _, resource := range p.Subresources {
+		u, err := pageURL.Parse(strings.TrimSpace(resource.URL))
+		if err != nil {
+			continue
+		}
+
+		for _, csp := range policies {
+			if directive, sources, ok := csp.effective(resource.Kind); ok && !allowsURL(resource.Kind, sources, u, &pageURL, resource.Nonce) {
+				out.Blocked = append(out.Blocked, CSPViolation{
+					Directive: directive,
+					Kind:      resource.Kind,
+					Resource:  u.String(),
+				})
+
+				break
+			}
+		}
+	}
+
+	for _, inline := range p.Inline {
+		for _, csp := range policies {
+			if directive, sources, ok := csp.effective(inline.Kind); ok && !allowsInline(inline, sources) {
+				out.Blocked = append(out.Blocked, CSPViolation{
+					Directive: directive,
+					Kind:      inline.Kind,
+					Resource:  snippet(inline.Content),
+				})
+
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+// findings reports unsafe directives of the policy.
+func (c *CSP) findings() []Finding {
+	const check = "content-security-policy"
+
+	var out []Finding
+
+	if directive, sources, ok := c.effective(ResourceScript); ok {
+		if hasSource(sources, "'unsafe-inline'") && !hasSource(sources, "'strict-dynamic'") && !hasNonceOrHash(sources) {
+			out = append(out, Finding{SeverityHigh, check, fmt.Sprintf("'unsafe-inline' in %v allows inline scripts", directive)})
+		}
+
+		if hasSource(sources, "'unsafe-eval'") {
+			out = append(out, Finding{SeverityMedium, check, fmt.Sprintf("'unsafe-eval' in %v allows eval()", directive)})
+		}
+
+		for _, source := range sources {
+			switch strings.ToLower(source) {
+			case "*", "http:", "https:", "data:":
+				if !hasSource(sources, "'strict-dynamic'") {
+					out = append(out, Finding{SeverityHigh, check, fmt.Sprintf("%v in %v allows scripts from any origin", source, directive)})
+				}
+			}
+		}
+	}
+
+	if directive, sources, ok := c.effective(ResourceStyle); ok && hasSource(sources, "'unsafe-inline'") && !hasNonceOrHash(sources) {
+		out = append(out, Finding{SeverityLow, check, fmt.Sprintf("'unsafe-inline' in %v allows inline styles", directive)})
+	}
+
+	if directive, sources, ok := c.effective(ResourceObject); ok && hasSource(sources, "*") {
+		out = append(out, Finding{SeverityHigh, check, fmt.Sprintf("* in %v allows plugins from any origin", directive)})
+	}
+
+	// wildcards in the remaining directives.
+	var names []string
+	for name := range c.Directives {
+		switch name {
+		case "script-src", "script-src-elem", "default-src", "object-src":
+			continue
+		}
+
+		if hasSource(c.Directives[name], "*") {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		out = append(out, Finding{SeverityLow, check, fmt.Sprintf("* in %v allows any origin", name)})
+	}
+
+	return out
+}
+
+// missingFindings reports directives that none of the policies restrict.
+func missingFindings(policies []*CSP) []Finding {
+	const check = "content-security-policy"
+
+	var scripts, objects, baseURI bool
+
+	for _, csp := range policies {
+		_, _, ok := csp.effective(ResourceScript)
+		scripts = scripts || ok
+
+		_, _, ok = csp.effective(ResourceObject)
+		objects = objects || ok
+
+		baseURI = baseURI || csp.Has("base-uri")
+	}
+
+	var out []Finding
+
+	if !scripts {
+		out = append(out, Finding{SeverityHigh, check, "scripts are not restricted, script-src and default-src are missing"})
+	}
+
+	if !objects {
+		out = append(out, Finding{SeverityMedium, check, "object-src is missing, plugins can be loaded from any origin"})
+	}
+
+	if !baseURI {
+		out = append(out, Finding{SeverityMedium, check, "base-uri is missing, injected <base> elements can redirect relative URLs"})
+	}
+
+	return out
+}
+
+// allowsURL checks if the source list allows loading the URL.
+func allowsURL(kind string, sources []string, u, self *url.URL, nonce string) bool {
+	// with 'strict-dynamic' only nonced or hashed scripts are trusted.
+	strictDynamic := kind == ResourceScript && hasSource(sources, "'strict-dynamic'")
+
+	for _, source := range sources {
+		lower := strings.ToLower(source)
+
+		switch {
+		case lower == "'none'":
+			continue
+		case strings.HasPrefix(lower, "'nonce-"):
+			if v, ok := nonceValue(source); ok && nonce != "" && v == nonce {
+				return true
+			}
+		case strictDynamic:
+			continue
+		case lower == "'self'":
+			if schemeMatches(self.Scheme, u.Scheme) && strings.EqualFold(self.Host, u.Host) {
+				return true
+			}
+		case lower == "*":
+			// * does not match data:, blob: and filesystem: URLs.
+			switch u.Scheme {
+			case "http", "https", "ws", "wss":
+				return true
+			}
+
+			if u.Scheme == self.Scheme {
+				return true
+			}
+		case strings.HasPrefix(lower, "'"):
+			continue
+		case strings.HasSuffix(lower, ":") && !strings.Contains(lower, "/"):
+			if schemeMatches(strings.TrimSuffix(lower, ":"), u.Scheme) {
+				return true
+			}
+		default:
+			if hostSourceMatches(source, u, self) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allowsInline checks if the source list allows the inline content.
+func allowsInline(inline InlineContent, sources []string) bool {
+	element := inline.Kind == InlineScript || inline.Kind == InlineStyle
+
+	for _, source := range sources {
+		lower := strings.ToLower(source)
+
+		switch {
+		case strings.HasPrefix(lower, "'nonce-"):
+			if v, ok := nonceValue(source); ok && element && inline.Nonce != "" && v == inline.Nonce {
+				return true
+			}
+		case strings.HasPrefix(lower, "'sha"):
+			// hashes apply to attributes and URLs only with 'unsafe-hashes'.
+			if (element || hasSource(sources, "'unsafe-hashes'")) && hashMatches(source, inline.Content) {
+				return true
+			}
+		}
+	}
+
+	// 'unsafe-inline' is ignored when nonces, hashes or 'strict-dynamic' are present.
+	if hasNonceOrHash(sources) || (inline.Kind != InlineStyle && inline.Kind != InlineStyleAttribute && hasSource(sources, "'strict-dynamic'")) {
+		return false
+	}
+
+	return hasSource(sources, "'unsafe-inline'")
+}
+
+// hostSourceMatches matches the URL against a host-source expression.
+func hostSourceMatches(source string, u, self *url.URL) bool {
+	scheme := ""
+	if i := strings.Index(source, "://"); i >= 0 {
+		scheme, source = source[:i], source[i+3:]
+	}
+
+	if scheme != "" {
+		if !schemeMatches(scheme, u.Scheme) {
+			return false
+		}
+	} else if !schemeMatches(self.Scheme, u.Scheme) {
+		return false
+	}
+
+	path := ""
+	if i := strings.Index(source, "/"); i >= 0 {
+		source, path = source[:i], source[i:]
+	}
+
+	host, port := strings.ToLower(source), ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, port = host[:i], host[i+1:]
+	}
+
+	hostname := strings.ToLower(u.Hostname())
+
+	switch {
+	case host == "*":
+	case strings.HasPrefix(host, "*."):
+		if !strings.HasSuffix(hostname, host[1:]) {
+			return false
+		}
+	case host != hostname:
+		return false
+	}
+
+	if port != "*" && port != portOf(u) {
+		if port != "" || u.Port() != "" {
+			return false
+		}
+	}
+
+	switch {
+	case path == "" || path == "/":
+		return true
+	case strings.HasSuffix(path, "/"):
+		return strings.HasPrefix(u.Path, path)
+	}
+
+	return u.Path == path
+}
+
+// schemeMatches checks if the scheme satisfies the source scheme,
+// secure upgrades of the source scheme are allowed.
+func schemeMatches(source, scheme string) bool {
+	source, scheme = strings.ToLower(source), strings.ToLower(scheme)
+
+	switch {
+	case source == scheme:
+		return true
+	case source == "http" && scheme == "https":
+		return true
+	case source == "ws" && (scheme == "wss" || scheme == "http" || scheme == "https"):
+		return true
+	}
+
+	return false
+}
+
+// portOf returns the port of the URL, using the default port of its scheme.
+func portOf(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+
+	switch u.Scheme {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	}
+
+	return ""
+}
+
+// hashMatches checks if a hash-source matches the content.
+func hashMatches(source, content string) bool {
+	source = strings.Trim(source, "'")
+
+	i := strings.Index(source, "-")
+	if i < 0 {
+		return false
+	}
+
+	var sum []byte
+
+	switch strings.ToLower(source[:i]) {
+	case "sha256":
+		s := sha256.Sum256([]byte(content))
+		sum = s[:]
+	case "sha384":
+		s := sha512.Sum384([]byte(content))
+		sum = s[:]
+	case "sha512":
+		s := sha512.Sum512([]byte(content))
+		sum = s[:]
+	default:
+		return false
+	}
+
+	return base64.StdEncoding.EncodeToString(sum) == source[i+1:]
+}
+
+// nonceValue returns the value of a 'nonce-<value>' source expression,
+// reporting false for malformed expressions such as an unterminated quote.
+func nonceValue(source string) (string, bool) {
+	if len(source) <= len("'nonce-")+1 || !strings.HasSuffix(source, "'") {
+		return "", false
+	}
+
+	return source[len("'nonce-") : len(source)-1], true
+}
+
+func hasSource(sources []string, want string) bool {
+	for _, source := range sources {
+		if strings.EqualFold(source, want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasNonceOrHash(sources []string) bool {
+	for _, source := range sources {
+		switch lower := strings.ToLower(source); {
+		case strings.HasPrefix(lower, "'nonce-"), strings.HasPrefix(lower, "'sha256-"),
+			strings.HasPrefix(lower, "'sha384-"), strings.HasPrefix(lower, "'sha512-"):
+			return true
+		}
+	}
+
+	return false
+}
+
+// snippet shortens inline content to 60 characters for reporting.
+func snippet(content string) string {
+	const max = 60
+
+	content = strings.Join(strings.Fields(content), " ")
+	if runes := []rune(content); len(runes) > max {
+		return string(runes[:max]) + "..."
+	}
+
+	return content
+}
+
+// extractCSPContent collects the policies, inline content and
+// subresources of an element node subject to a Content-Security-Policy.
+func (p *PageContents) extractCSPContent(node *html.Node) {
+	nonce, _ := getAttribute(node, "nonce")
+
+	switch strings.ToLower(node.Data) {
+	case "meta":
+		if equiv, _ := getAttribute(node, "http-equiv"); strings.EqualFold(strings.TrimSpace(equiv), "content-security-policy") {
+			if content, ok := getAttribute(node, "content"); ok {
+				p.MetaCSP = append(p.MetaCSP, content)
+			}
+		}
+	case "script":
+		if src, ok := getAttribute(node, "src"); ok {
+			p.addSubresource(ResourceScript, src, nonce)
+		} else if typ, _ := getAttribute(node, "type"); isScriptType(typ) && node.FirstChild != nil {
+			p.Inline = append(p.Inline, InlineContent{Kind: InlineScript, Nonce: nonce, Content: node.FirstChild.Data})
+		}
+	case "style":
+		if node.FirstChild != nil {
+			p.Inline = append(p.Inline, InlineContent{Kind: InlineStyle, Nonce: nonce, Content: node.FirstChild.Data})
+		}
+	case "link":
+		rel, _ := getAttribute(node, "rel")
+		href, ok := getAttribute(node, "href")
+
+		if ok {
+			for _, r := range strings.Fields(strings.ToLower(rel)) {
+				switch r {
+				case "stylesheet":
+					p.addSubresource(ResourceStyle, href, nonce)
+				case "icon", "apple-touch-icon":
+					p.addSubresource(ResourceImage, href, nonce)
+				}
+			}
+		}
+	case "img":
+		if src, ok := getAttribute(node, "src"); ok {
+			p.addSubresource(ResourceImage, src, nonce)
+		}
+	case "audio", "video", "track":
+		if src, ok := getAttribute(node, "src"); ok {
+			p.addSubresource(ResourceMedia, src, nonce)
+		}
+	case "source":
+		kind := ResourceMedia
+		if node.Parent != nil && strings.ToLower(node.Parent.Data) == "picture" {
+			kind = ResourceImage
+		}
+
+		if src, ok := getAttribute(node, "src"); ok {
+			p.addSubresource(kind, src, nonce)
+		}
+	case "iframe", "frame":
+		if src, ok := getAttribute(node, "src"); ok && !isJavascriptURL(src) {
+			p.addSubresource(ResourceFrame, src, nonce)
+		}
+	case "object":
+		if data, ok := getAttribute(node, "data"); ok {
+			p.addSubresource(ResourceObject, data, nonce)
+		}
+	case "embed":
+		if src, ok := getAttribute(node, "src"); ok {
+			p.addSubresource(ResourceObject, src, nonce)
+		}
+	}
+
+	for i := 0; i < len(node.Attr); i++ {
+		key, val := strings.ToLower(node.Attr[i].Key), node.Attr[i].Val
+
+		switch {
+		case strings.HasPrefix(key, "on"):
+			p.Inline = append(p.Inline, InlineContent{Kind: InlineEventHandler, Content: val})
+		case key == "style":
+			p.Inline = append(p.Inline, InlineContent{Kind: InlineStyleAttribute, Content: val})
+		case key == "href" || key == "src" || key == "action" || key == "formaction":
+			if isJavascriptURL(val) {
+				p.Inline = append(p.Inline, InlineContent{Kind: InlineJavascriptURL, Content: strings.TrimSpace(val)})
+			}
+		}
+	}
+}
+
+func (p *PageContents) addSubresource(kind, u, nonce string) {
+	if strings.TrimSpace(u) == "" {
+		return
+	}
+
+	p.Subresources = append(p.Subresources, Subresource{Kind: kind, URL: u, Nonce: nonce})
+}
+
+// isScriptType checks if the type attribute of a <script> denotes executable code.
+func isScriptType(typ string) bool {
+	switch strings.ToLower(strings.TrimSpace(typ)) {
+	case "", "module", "text/javascript", "application/javascript", "text/ecmascript", "application/ecmascript":
+		return true
+	}
+
+	return false
+}
+
+func isJavascriptURL(u string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(u)), "javascript:")
+}
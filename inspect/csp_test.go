@@ -0,0 +1,237 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCSP(t *testing.T) {
+	tests := []struct {
+		Name string
+		in   string
+		want *CSP
+	}{
+		{
+			Name: "ok",
+			in:   "default-src 'self'; Script-Src 'nonce-abc' https://cdn.example.com;; script-src *; upgrade-insecure-requests",
+			want: &CSP{
+				Directives: map[string][]string{
+					"default-src":               {"'self'"},
+					"script-src":                {"'nonce-abc'", "https://cdn.example.com"},
+					"upgrade-insecure-requests": {},
+				},
+			},
+		},
+		{
+			Name: "ok-empty",
+			in:   "",
+			want: &CSP{Directives: map[string][]string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if diff := cmp.Diff(ParseCSP(tt.in), tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
+
+func TestExtractCSPContent(t *testing.T) {
+	pc, err := Page(strings.NewReader(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<meta http-equiv="Content-Security-Policy" content="img-src 'self'">
+		<link rel="stylesheet" href="/main.css">
+		<style>body { color: red; }</style>
+		<script nonce="abc">console.log("hi")</script>
+		<script type="application/ld+json">{}</script>
+		<script src="https://cdn.example.com/app.js"></script>
+	</head>
+	<body onload="init()">
+		<img src="/logo.png" style="width: 10px">
+		<a href="javascript:void(0)">link</a>
+		<iframe src="https://frames.example.com/"></iframe>
+	</body>
+	</html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantMeta := []string{"img-src 'self'"}
+	if diff := cmp.Diff(pc.MetaCSP, wantMeta); diff != "" {
+		t.Error(diff)
+	}
+
+	wantInline := []InlineContent{
+		{Kind: InlineStyle, Content: "body { color: red; }"},
+		{Kind: InlineScript, Nonce: "abc", Content: `console.log("hi")`},
+		{Kind: InlineEventHandler, Content: "init()"},
+		{Kind: InlineStyleAttribute, Content: "width: 10px"},
+		{Kind: InlineJavascriptURL, Content: "javascript:void(0)"},
+	}
+	if diff := cmp.Diff(pc.Inline, wantInline); diff != "" {
+		t.Error(diff)
+	}
+
+	wantSubresources := []Subresource{
+		{Kind: ResourceStyle, URL: "/main.css"},
+		{Kind: ResourceScript, URL: "https://cdn.example.com/app.js"},
+		{Kind: ResourceImage, URL: "/logo.png"},
+		{Kind: ResourceFrame, URL: "https://frames.example.com/"},
+	}
+	if diff := cmp.Diff(pc.Subresources, wantSubresources); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCheckCSP(t *testing.T) {
+	pageURL, err := url.Parse("https://www.example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := &PageContents{
+		MetaCSP: []string{"img-src 'self' *.images.com"},
+		Inline: []InlineContent{
+			{Kind: InlineScript, Nonce: "abc", Content: "trusted()"},
+			{Kind: InlineScript, Content: "untrusted()"},
+			{Kind: InlineScript, Content: "hashed()"},
+			{Kind: InlineEventHandler, Content: "init()"},
+			{Kind: InlineStyle, Content: "p {}"},
+			{Kind: InlineJavascriptURL, Content: "javascript:void(0)"},
+		},
+		Subresources: []Subresource{
+			{Kind: ResourceScript, URL: "/app.js"},
+			{Kind: ResourceScript, URL: "https://cdn.example.com/lib/x.js"},
+			{Kind: ResourceScript, URL: "https://evil.com/x.js"},
+			{Kind: ResourceImage, URL: "https://static.images.com/a.png"},
+			{Kind: ResourceImage, URL: "https://images.com/a.png"},
+			{Kind: ResourceImage, URL: "data:image/png;base64,AAAA"},
+			{Kind: ResourceStyle, URL: "http://www.example.com/main.css"},
+		},
+	}
+
+	tests := []struct {
+		Name    string
+		page    *PageContents
+		headers []string
+		want    *CSPReport
+	}{
+		{
+			Name: "ok-no-policy",
+			page: new(PageContents),
+			want: nil,
+		},
+		{
+			Name: "ok",
+			page: page,
+			headers: []string{
+				// sha256 of hashed()
+				"default-src 'self'; script-src 'self' 'nonce-abc' 'sha256-3FQkzkTwS/aaE43TWIIWhPFiYgVLs8LJ5KhXh2rtzIo=' https://cdn.example.com/lib/; style-src 'self' 'unsafe-inline'; img-src *; object-src 'none'",
+			},
+			want: &CSPReport{
+				Policies: []string{
+					"default-src 'self'; script-src 'self' 'nonce-abc' 'sha256-3FQkzkTwS/aaE43TWIIWhPFiYgVLs8LJ5KhXh2rtzIo=' https://cdn.example.com/lib/; style-src 'self' 'unsafe-inline'; img-src *; object-src 'none'",
+					"img-src 'self' *.images.com",
+				},
+				Blocked: []CSPViolation{
+					{Directive: "script-src", Kind: ResourceScript, Resource: "https://evil.com/x.js"},
+					{Directive: "img-src", Kind: ResourceImage, Resource: "https://images.com/a.png"},
+					{Directive: "img-src", Kind: ResourceImage, Resource: "data:image/png;base64,AAAA"},
+					{Directive: "style-src", Kind: ResourceStyle, Resource: "http://www.example.com/main.css"},
+					{Directive: "script-src", Kind: InlineScript, Resource: "untrusted()"},
+					{Directive: "script-src", Kind: InlineEventHandler, Resource: "init()"},
+					{Directive: "script-src", Kind: InlineJavascriptURL, Resource: "javascript:void(0)"},
+				},
+				Findings: []Finding{
+					{SeverityLow, "content-security-policy", "'unsafe-inline' in style-src allows inline styles"},
+					{SeverityLow, "content-security-policy", "* in img-src allows any origin"},
+					{SeverityMedium, "content-security-policy", "base-uri is missing, injected <base> elements can redirect relative URLs"},
+				},
+			},
+		},
+		{
+			Name:    "ok-missing",
+			page:    new(PageContents),
+			headers: []string{"img-src 'self'"},
+			want: &CSPReport{
+				Policies: []string{"img-src 'self'"},
+				Findings: []Finding{
+					{SeverityHigh, "content-security-policy", "scripts are not restricted, script-src and default-src are missing"},
+					{SeverityMedium, "content-security-policy", "object-src is missing, plugins can be loaded from any origin"},
+					{SeverityMedium, "content-security-policy", "base-uri is missing, injected <base> elements can redirect relative URLs"},
+				},
+			},
+		},
+		{
+			Name:    "ok-unsafe",
+			page:    new(PageContents),
+			headers: []string{"script-src * 'unsafe-inline' 'unsafe-eval'; object-src *; img-src *; base-uri 'self'"},
+			want: &CSPReport{
+				Policies: []string{"script-src * 'unsafe-inline' 'unsafe-eval'; object-src *; img-src *; base-uri 'self'"},
+				Findings: []Finding{
+					{SeverityHigh, "content-security-policy", "'unsafe-inline' in script-src allows inline scripts"},
+					{SeverityMedium, "content-security-policy", "'unsafe-eval' in script-src allows eval()"},
+					{SeverityHigh, "content-security-policy", "* in script-src allows scripts from any origin"},
+					{SeverityHigh, "content-security-policy", "* in object-src allows plugins from any origin"},
+					{SeverityLow, "content-security-policy", "* in img-src allows any origin"},
+				},
+			},
+		},
+		{
+			Name: "ok-malformed-nonce",
+			page: &PageContents{
+				Inline: []InlineContent{
+					{Kind: InlineScript, Nonce: "abc", Content: "trusted()"},
+				},
+				Subresources: []Subresource{
+					{Kind: ResourceScript, URL: "/app.js", Nonce: "abc"},
+				},
+			},
+			headers: []string{"script-src 'nonce-' 'nonce-abc; object-src 'none'; base-uri 'self'"},
+			want: &CSPReport{
+				Policies: []string{"script-src 'nonce-' 'nonce-abc; object-src 'none'; base-uri 'self'"},
+				Blocked: []CSPViolation{
+					{Directive: "script-src", Kind: ResourceScript, Resource: "https://www.example.com/app.js"},
+					{Directive: "script-src", Kind: InlineScript, Resource: "trusted()"},
+				},
+			},
+		},
+		{
+			Name: "ok-long-inline-snippet",
+			page: &PageContents{
+				Inline: []InlineContent{
+					{Kind: InlineScript, Content: "alert('" + strings.Repeat("č", 70) + "')"},
+				},
+			},
+			headers: []string{"script-src 'self'; object-src 'none'; base-uri 'self'"},
+			want: &CSPReport{
+				Policies: []string{"script-src 'self'; object-src 'none'; base-uri 'self'"},
+				Blocked: []CSPViolation{
+					{Directive: "script-src", Kind: InlineScript, Resource: "alert('" + strings.Repeat("č", 53) + "..."},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have := tt.page.CheckCSP(*pageURL, tt.headers)
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractionSection is the name of the section of an Extraction.
+const ExtractionSection = "extract"
+
+// Extraction modes
+const (
+	ExtractText = "text"
+	ExtractHTML = "html"
+	ExtractAttr = "attr"
+)
+
+// ExtractRule selects the values extracted from the elements matching a CSS selector
+// or from the result of an XPath expression, exactly one of them must be set.
+type ExtractRule struct {
+	Selector string
+	XPath    string
+
+	// What is extracted from the matching elements, ExtractText if empty.
+	Mode string
+
+	// Attribute extracted in the ExtractAttr mode, elements without it are skipped.
+	Attr string
+}
+
+// Extraction extracts the values selected by named rules from a page. It is an
+// Extractor that queries the whole document once it is visited, so it needs
+// the DOM built by Page. Its result maps the names of the rules to the values
+// extracted from the matching elements in document order.
+type Extraction struct {
+	rules  map[string]compiledRule
+	values map[string][]string
+}
+
+// compiledRule is an ExtractRule with its compiled selector or expression.
+type compiledRule struct {
+	ExtractRule
+	selector *Selector
+	xpath    *XPath
+}
+
+// NewExtraction validates the rules and compiles their selectors and expressions.
+func NewExtraction(rules map[string]ExtractRule) (*Extraction, error) {
+	out := &Extraction{rules: make(map[string]compiledRule)}
+
+	for name, rule := range rules {
+		if rule.Selector == "" && rule.XPath == "" {
+			return nil, fmt.Errorf("inspect.NewExtraction: rule %q: missing selector or xpath", name)
+		}
+
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("inspect.NewExtraction: rule %q: %w", name, err)
+		}
+
+		out.rules[name] = compiled
+	}
+
+	return out, nil
+}
+
+// compileRule validates the mode of the rule and compiles its selector or
+// expression. A rule with neither extracts from the node it is applied to.
+func compileRule(rule ExtractRule) (compiledRule, error) {
+	switch rule.Mode {
+	case "":
+		rule.Mode = ExtractText
+	case ExtractText, ExtractHTML:
+	case ExtractAttr:
+		if rule.Attr == "" {
+			return compiledRule{}, errors.New("missing attribute")
+		}
+	default:
+		return compiledRule{}, fmt.Errorf("unsupported mode %q", rule.Mode)
+	}
+
+	compiled := compiledRule{ExtractRule: rule}
+
+	var err error
+
+	switch {
+	case rule.Selector != "" && rule.XPath != "":
+		return compiledRule{}, errors.New("both selector and xpath set")
+	case rule.XPath != "":
+		compiled.xpath, err = CompileXPath(rule.XPath)
+	case rule.Selector != "":
+		compiled.selector, err = CompileSelector(rule.Selector)
+	}
+
+	return compiled, err
+}
+
+func (e *Extraction) Name() string { return ExtractionSection }
+
+func (e *Extraction) Visit(node *html.Node) error {
+	if node.Type != html.DocumentNode {
+		return nil
+	}
+
+	values, err := e.Extract(node)
+	if err != nil {
+		return err
+	}
+
+	e.values = values
+
+	return nil
+}
+
+func (e *Extraction) Result() interface{} { return e.values }
+
+// Extract returns the values selected by the rules from the descendants of root.
+// The nodes selected by an XPath expression are evaluated with root as the context
+// node, attributes extract their value in all modes. Expressions evaluating to a
+// string, number or boolean extract a single value.
+func (e *Extraction) Extract(root *html.Node) (map[string][]string, error) {
+	out := make(map[string][]string)
+	doc := newXPathDocument(root)
+
+	for name, rule := range e.rules {
+		values, err := rule.values(root, doc)
+		if err != nil {
+			return nil, fmt.Errorf("inspect.Extraction.Extract: rule %q: %w", name, err)
+		}
+
+		out[name] = values
+	}
+
+	return out, nil
+}
+
+// values returns the values extracted by the rule with node as the context
+// in the document.
+func (r compiledRule) values(node *html.Node, doc *xpathDocument) ([]string, error) {
+	values := make([]string, 0)
+
+	switch {
+	case r.selector != nil:
+		for _, n := range r.selector.Select(node) {
+			if v, ok := r.extract(n); ok {
+				values = append(values, v)
+			}
+		}
+	case r.xpath != nil:
+		result, err := r.xpath.evaluate(node, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, ok := result.([]XPathNode)
+		if !ok {
+			return append(values, xpathString(result)), nil
+		}
+
+		for _, n := range nodes {
+			if n.Attr != nil {
+				values = append(values, n.Attr.Val)
+				continue
+			}
+
+			if v, ok := r.extract(n.Node); ok {
+				values = append(values, v)
+			}
+		}
+	default:
+		if v, ok := r.extract(node); ok {
+			values = append(values, v)
+		}
+	}
+
+	return values, nil
+}
+
+// extract returns the value extracted from the node by the mode of the rule.
+func (r compiledRule) extract(n *html.Node) (string, bool) {
+	switch r.Mode {
+	case ExtractText:
+		return Text(n), true
+	case ExtractHTML:
+		return OuterHTML(n), true
+	case ExtractAttr:
+		if n.Type == html.ElementNode {
+			return getAttribute(n, strings.ToLower(r.Attr))
+		}
+	}
+
+	return "", false
+}
+
+// Text returns the text content of the node with collapsed whitespace.
+func Text(node *html.Node) string {
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(node)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// OuterHTML returns the HTML serialization of the node and its subtree.
+func OuterHTML(node *html.Node) string {
+	var b strings.Builder
+
+	if err := html.Render(&b, node); err != nil {
+		return ""
+	}
+
+	return b.String()
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func TestExtraction(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Rules   map[string]ExtractRule
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			Name: "ok",
+			Rules: map[string]ExtractRule{
+				"names":  {Selector: ".product .name"},
+				"prices": {Selector: ".price", Mode: ExtractText},
+				"skus":   {Selector: ".product", Mode: ExtractAttr, Attr: "DATA-SKU"},
+				"links":  {Selector: "#menu a", Mode: ExtractAttr, Attr: "rel"},
+				"note":   {Selector: "#note", Mode: ExtractHTML},
+				"none":   {Selector: "table"},
+				"hrefs":  {XPath: "//a[starts-with(@href, '/')]/@href", Mode: ExtractAttr, Attr: "rel"},
+				"items":  {XPath: "//div[@data-sku][span[@class='name' and . != '']]", Mode: ExtractAttr, Attr: "data-sku"},
+				"label":  {XPath: "//li[2]/a/text()"},
+				"count":  {XPath: "count(//li)"},
+				"title":  {XPath: "//h2", Mode: ExtractHTML},
+			},
+			want: map[string][]string{
+				"names":  {"Lamp", "Chair"},
+				"prices": {"10 EUR", "20 EUR"},
+				"skus":   {"A-1", "B-2"},
+				"links":  {"external"},
+				"note":   {`<p id="note">Prices include VAT.</p>`},
+				"none":   {},
+				"hrefs":  {"/home", "/about.html"},
+				"items":  {"A-1", "B-2"},
+				"label":  {"Shop"},
+				"count":  {"4"},
+				"title":  {`<h2 id="title">Products</h2>`},
+			},
+		},
+		{
+			Name:  "ok-no-rules",
+			Rules: nil,
+			want:  map[string][]string{},
+		},
+		{
+			Name:    "fail-selector",
+			Rules:   map[string]ExtractRule{"prices": {Selector: ".price >"}},
+			wantErr: true,
+		},
+		{
+			Name:    "fail-xpath",
+			Rules:   map[string]ExtractRule{"prices": {XPath: "//span["}},
+			wantErr: true,
+		},
+		{
+			Name:    "fail-selector-and-xpath",
+			Rules:   map[string]ExtractRule{"prices": {Selector: ".price", XPath: "//span"}},
+			wantErr: true,
+		},
+		{
+			Name:    "fail-mode",
+			Rules:   map[string]ExtractRule{"prices": {Selector: ".price", Mode: "json"}},
+			wantErr: true,
+		},
+		{
+			Name:    "fail-missing-attr",
+			Rules:   map[string]ExtractRule{"skus": {Selector: ".product", Mode: ExtractAttr}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			extraction, err := NewExtraction(tt.Rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExtraction() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			contents, err := Page(strings.NewReader(selectorPage), extraction)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(contents.Sections[ExtractionSection], tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExtractionXPathError(t *testing.T) {
+	extraction, err := NewExtraction(map[string]ExtractRule{"count": {XPath: "count('li')"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Page(strings.NewReader(selectorPage), extraction); err == nil {
+		t.Error("Page() err = nil, want an evaluation error")
+	}
+}
+
+func TestExtractionStream(t *testing.T) {
+	extraction, err := NewExtraction(map[string]ExtractRule{"prices": {Selector: ".price"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := PageStream(context.Background(), strings.NewReader(selectorPage), StreamOptions{Extractors: []Extractor{extraction}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the document is not built in stream mode.
+	if values := contents.Sections[ExtractionSection].(map[string][]string); values != nil {
+		t.Errorf("PageStream() extracted %v, want nil", values)
+	}
+}
+
+func TestText(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(`<div id="x">  Hello,<b>world</b>
+		<i> again </i></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := Select(root, "#x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have := Text(nodes[0]); have != "Hello,world again" {
+		t.Errorf("Text() = %q", have)
+	}
+}
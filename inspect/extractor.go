@@ -0,0 +1,254 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Built-in extractors
+const (
+	ExtractorMeta     = "meta"
+	ExtractorTracking = "tracking"
+	ExtractorArticle  = "article"
+)
+
+// Tracking tag vendors reported by the tracking extractor.
+const (
+	TrackerGoogleAnalytics  = "google-analytics"
+	TrackerGoogleTagManager = "google-tag-manager"
+	TrackerGoogleAds        = "google-ads"
+	TrackerFacebookPixel    = "facebook-pixel"
+)
+
+// Tracking tag regexes
+var (
+	reGoogleTag     = regexp.MustCompile(`\b(UA-\d{4,10}-\d{1,4}|G-[A-Z0-9]{6,12}|GTM-[A-Z0-9]{4,9}|AW-\d{6,12})\b`)
+	reFacebookPixel = regexp.MustCompile(`fbq\(\s*['"]init['"]\s*,\s*['"](\d{10,20})['"]`)
+)
+
+// Extractor contributes a custom section to the contents of a page. It is
+// called for every node during the same traversal that extracts the contents
+// of PageContents.
+//
+// Page visits the nodes of the whole DOM. PageStream visits only the elements,
+// at their start tag with the ancestors set but without children, except for
+// the <a>, <title>, <script>, <style> and <noscript> elements which are visited
+// together with their subtree once their end tag is reached.
+type Extractor interface {
+	// Name of the section in PageContents.Sections.
+	Name() string
+
+	// Visit is called for every node of the page in document order.
+	Visit(node *html.Node) error
+
+	// Result returns the section once the whole page was visited.
+	Result() interface{}
+}
+
+// registry holds the constructors of the registered extractors by their name.
+var registry = struct {
+	mu         sync.RWMutex
+	extractors map[string]func() Extractor
+}{
+	extractors: make(map[string]func() Extractor),
+}
+
+func init() {
+	RegisterExtractor(func() Extractor { return &metaExtractor{tags: make(map[string]string)} })
+	RegisterExtractor(func() Extractor { return &trackingExtractor{ids: make(map[string]map[string]struct{})} })
+	RegisterExtractor(func() Extractor { return new(articleExtractor) })
+}
+
+// RegisterExtractor makes an extractor available by the name of the extractors
+// created by newExtractor. Each page needs a new extractor as extractors carry
+// the state of a single page. If an extractor with the same name is already
+// registered or the name is empty, RegisterExtractor panics.
+func RegisterExtractor(newExtractor func() Extractor) {
+	name := newExtractor().Name()
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if name == "" {
+		panic("inspect.RegisterExtractor: extractor with an empty name")
+	}
+
+	if _, ok := registry.extractors[name]; ok {
+		panic(fmt.Sprintf("inspect.RegisterExtractor: extractor %q registered twice", name))
+	}
+
+	registry.extractors[name] = newExtractor
+}
+
+// Extractors returns the sorted names of the registered extractors.
+func Extractors() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	out := make([]string, 0, len(registry.extractors))
+	for name := range registry.extractors {
+		out = append(out, name)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// NewExtractors creates new instances of the registered extractors with the given names.
+func NewExtractors(names ...string) ([]Extractor, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	var out []Extractor
+
+	for _, name := range names {
+		newExtractor, ok := registry.extractors[name]
+		if !ok {
+			return nil, fmt.Errorf("inspect.NewExtractors: unknown extractor %q", name)
+		}
+
+		out = append(out, newExtractor())
+	}
+
+	return out, nil
+}
+
+// visitExtractors passes the node to every extractor.
+func visitExtractors(node *html.Node, extractors []Extractor) error {
+	for _, e := range extractors {
+		if err := e.Visit(node); err != nil {
+			return fmt.Errorf("extractor %q: %w", e.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// addSections stores the results of the extractors in the contents of the page.
+func (p *PageContents) addSections(extractors []Extractor) {
+	for _, e := range extractors {
+		if p.Sections == nil {
+			p.Sections = make(map[string]interface{})
+		}
+
+		p.Sections[e.Name()] = e.Result()
+	}
+}
+
+// metaExtractor collects the content of the <meta name> and <meta property>
+// elements, like the description or the Open Graph and Twitter card tags.
+// The result maps the lower-cased names to the content of their first element.
+type metaExtractor struct {
+	tags map[string]string
+}
+
+func (e *metaExtractor) Name() string { return ExtractorMeta }
+
+func (e *metaExtractor) Visit(node *html.Node) error {
+	if node.Type != html.ElementNode || strings.ToLower(node.Data) != "meta" {
+		return nil
+	}
+
+	content, ok := getAttribute(node, "content")
+	if !ok {
+		return nil
+	}
+
+	for _, key := range []string{"name", "property"} {
+		if name, ok := getAttribute(node, key); ok {
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if _, ok := e.tags[name]; !ok && name != "" {
+				e.tags[name] = strings.TrimSpace(content)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *metaExtractor) Result() interface{} { return e.tags }
+
+// trackingExtractor collects the IDs of the tracking tags found in the sources
+// and the contents of the scripts, the <noscript> fallbacks and the iframes.
+// The result maps the vendors to their sorted IDs.
+type trackingExtractor struct {
+	ids map[string]map[string]struct{}
+}
+
+func (e *trackingExtractor) Name() string { return ExtractorTracking }
+
+func (e *trackingExtractor) Visit(node *html.Node) error {
+	if node.Type != html.ElementNode {
+		return nil
+	}
+
+	switch strings.ToLower(node.Data) {
+	case "script":
+		if src, ok := getAttribute(node, "src"); ok {
+			e.scan(src)
+		} else if node.FirstChild != nil {
+			e.scan(node.FirstChild.Data)
+		}
+	case "iframe":
+		if src, ok := getAttribute(node, "src"); ok {
+			e.scan(src)
+		}
+	case "noscript":
+		// the contents of <noscript> are raw text, like the tag manager <iframe> fallback.
+		if node.FirstChild != nil && node.FirstChild.Type == html.TextNode {
+			e.scan(node.FirstChild.Data)
+		}
+	}
+
+	return nil
+}
+
+// scan collects the tracking IDs of the source or the content of an element.
+func (e *trackingExtractor) scan(s string) {
+	for _, id := range reGoogleTag.FindAllString(s, -1) {
+		switch {
+		case strings.HasPrefix(id, "GTM-"):
+			e.add(TrackerGoogleTagManager, id)
+		case strings.HasPrefix(id, "AW-"):
+			e.add(TrackerGoogleAds, id)
+		default:
+			e.add(TrackerGoogleAnalytics, id)
+		}
+	}
+
+	for _, match := range reFacebookPixel.FindAllStringSubmatch(s, -1) {
+		e.add(TrackerFacebookPixel, match[1])
+	}
+}
+
+func (e *trackingExtractor) add(vendor, id string) {
+	if e.ids[vendor] == nil {
+		e.ids[vendor] = make(map[string]struct{})
+	}
+
+	e.ids[vendor][id] = struct{}{}
+}
+
+func (e *trackingExtractor) Result() interface{} {
+	out := make(map[string][]string)
+
+	for vendor, ids := range e.ids {
+		for id := range ids {
+			out[vendor] = append(out[vendor], id)
+		}
+
+		sort.Strings(out[vendor])
+	}
+
+	return out
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+// countExtractor counts the elements with the given name.
+type countExtractor struct {
+	element string
+	count   int
+	err     error
+}
+
+func (e *countExtractor) Name() string { return "count-" + e.element }
+
+func (e *countExtractor) Visit(node *html.Node) error {
+	if node.Type == html.ElementNode && node.Data == e.element {
+		e.count++
+		return e.err
+	}
+
+	return nil
+}
+
+func (e *countExtractor) Result() interface{} { return e.count }
+
+func TestExtractors(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Tracked</title>
+	<meta name="Description" content=" A tracked page ">
+	<meta name="description" content="duplicate">
+	<meta property="og:title" content="Tracked">
+	<meta name="viewport">
+	<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123XYZ"></script>
+	<script>
+		gtag('config', 'G-ABC123XYZ');
+		gtag('config', 'AW-123456789');
+		fbq('init', '1234567890123');
+	</script>
+</head>
+<body>
+	<noscript><iframe src="https://www.googletagmanager.com/ns.html?id=GTM-K9X2Q"></iframe></noscript>
+	<p>Legal <a href="/imprint">imprint</a></p>
+	<p>Contact</p>
+</body>
+</html>`
+
+	want := map[string]interface{}{
+		ExtractorMeta: map[string]string{
+			"description": "A tracked page",
+			"og:title":    "Tracked",
+		},
+		ExtractorTracking: map[string][]string{
+			TrackerGoogleAnalytics:  {"G-ABC123XYZ"},
+			TrackerGoogleAds:        {"AW-123456789"},
+			TrackerGoogleTagManager: {"GTM-K9X2Q"},
+			TrackerFacebookPixel:    {"1234567890123"},
+		},
+		"count-p": 2,
+	}
+
+	newExtractors := func(t *testing.T) []Extractor {
+		extractors, err := NewExtractors(ExtractorMeta, ExtractorTracking)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return append(extractors, &countExtractor{element: "p"})
+	}
+
+	t.Run("page", func(t *testing.T) {
+		have, err := Page(strings.NewReader(page), newExtractors(t)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(have.Sections, want); diff != "" {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		have, err := PageStream(context.Background(), strings.NewReader(page), StreamOptions{Extractors: newExtractors(t)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(have.Sections, want); diff != "" {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("no-extractors", func(t *testing.T) {
+		have, err := Page(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have.Sections != nil {
+			t.Errorf("Page() sections = %v, want nil", have.Sections)
+		}
+	})
+
+	t.Run("fail-visit", func(t *testing.T) {
+		errVisit := errors.New("visit failed")
+
+		_, err := Page(strings.NewReader(page), &countExtractor{element: "p", err: errVisit})
+		if !errors.Is(err, errVisit) {
+			t.Errorf("Page() err = %v, want %v", err, errVisit)
+		}
+	})
+}
+
+func TestRegisterExtractor(t *testing.T) {
+	defer func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+
+		delete(registry.extractors, "count-section")
+	}()
+
+	RegisterExtractor(func() Extractor { return &countExtractor{element: "section"} })
+
+	if diff := cmp.Diff(Extractors(), []string{ExtractorArticle, "count-section", ExtractorMeta, ExtractorTracking}); diff != "" {
+		t.Error(diff)
+	}
+
+	tests := []struct {
+		Name    string
+		Names   []string
+		wantErr bool
+	}{
+		{Name: "ok", Names: []string{"count-section", ExtractorMeta}},
+		{Name: "ok-empty"},
+		{Name: "fail-unknown", Names: []string{ExtractorMeta, "unknown"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := NewExtractors(tt.Names...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExtractors() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(have) != len(tt.Names) && !tt.wantErr {
+				t.Errorf("NewExtractors() created %v extractors, want %v", len(have), len(tt.Names))
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterExtractor() expected a panic for a duplicate name")
+		}
+	}()
+
+	RegisterExtractor(func() Extractor { return &countExtractor{element: "section"} })
+}
@@ -0,0 +1,372 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Feed formats
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+// MaxFeedSize is the maximum size of a feed in bytes.
+const MaxFeedSize = 10 << 20
+
+// feedTypes maps the media types of the feed <link> elements to the feed formats.
+var feedTypes = map[string]string{
+	"application/rss+xml":   FeedRSS,
+	"application/atom+xml":  FeedAtom,
+	"application/feed+json": FeedJSON,
+}
+
+// feedDateLayouts are the date formats accepted in feeds, RSS uses RFC 822 dates
+// and Atom and JSON Feed use RFC 3339 dates.
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+// FeedLink is a <link rel="alternate"> element referencing a feed of the page.
+type FeedLink struct {
+	Href string
+
+	// Media type of the feed.
+	Type string
+
+	Title string
+}
+
+// Feed is a parsed RSS, Atom or JSON Feed.
+type Feed struct {
+	URL string
+
+	// Format of the feed, one of FeedRSS, FeedAtom or FeedJSON.
+	Format string
+
+	Title string
+	Items []FeedItem
+
+	// Reason the feed could not be fetched or parsed.
+	Error string
+}
+
+// FeedItem is a single item of a feed.
+type FeedItem struct {
+	Title string
+	Link  string
+
+	// Publication date of the item, the update date if there is none.
+	// Zero if the item has no valid date.
+	Published time.Time
+}
+
+// FeedReport is the result of fetching and checking a feed.
+type FeedReport struct {
+	Feed *Feed
+
+	// Date of the newest item, zero if no item has a valid date.
+	Newest time.Time
+
+	// Item links that are missing or are not http(s) URLs.
+	InvalidLinks []InvalidLink
+
+	// Item links that are inaccessible, see CheckLinks.
+	BrokenLinks []InvalidLink
+}
+
+type xmlFeed struct {
+	XMLName xml.Name
+
+	// RSS 2.0
+	Channel struct {
+		Title string        `xml:"title"`
+		Items []xmlFeedItem `xml:"item"`
+	} `xml:"channel"`
+
+	// RSS 1.0 items are siblings of the channel.
+	Items []xmlFeedItem `xml:"item"`
+
+	// Atom
+	Title   string        `xml:"title"`
+	Entries []xmlFeedItem `xml:"entry"`
+}
+
+type xmlFeedItem struct {
+	Title     string        `xml:"title"`
+	Links     []xmlFeedLink `xml:"link"`
+	PubDate   string        `xml:"pubDate"`
+	Date      string        `xml:"date"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+}
+
+// xmlFeedLink is a RSS link holding the URL as its text or an Atom link holding it in href.
+type xmlFeedLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+type jsonFeed struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Items   []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		ExternalURL   string `json:"external_url"`
+		DatePublished string `json:"date_published"`
+		DateModified  string `json:"date_modified"`
+	} `json:"items"`
+}
+
+// extractFeedLink extracts the <link rel="alternate"> elements referencing feeds.
+func (p *PageContents) extractFeedLink(node *html.Node) {
+	if strings.ToLower(node.Data) != "link" {
+		return
+	}
+
+	rel, _ := getAttribute(node, "rel")
+	href, ok := getAttribute(node, "href")
+
+	if !ok || !hasRel(strings.ToLower(rel), "alternate") {
+		return
+	}
+
+	typ, _ := getAttribute(node, "type")
+
+	mediaType, _, err := mime.ParseMediaType(typ)
+	if err != nil {
+		return
+	}
+
+	if _, ok := feedTypes[strings.ToLower(mediaType)]; !ok {
+		return
+	}
+
+	title, _ := getAttribute(node, "title")
+
+	p.Feeds = append(p.Feeds, FeedLink{
+		Href:  strings.TrimSpace(href),
+		Type:  strings.ToLower(mediaType),
+		Title: strings.TrimSpace(title),
+	})
+}
+
+// ParseFeed parses a RSS 2.0, RSS 1.0, Atom or JSON Feed document.
+func ParseFeed(r io.Reader) (*Feed, error) {
+	br := bufio.NewReader(io.LimitReader(r, MaxFeedSize))
+
+	// skip leading whitespace and byte order marks to detect JSON feeds.
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("inspect.ParseFeed: failed to read feed: %w", err)
+		}
+
+		if b[0] == '{' {
+			return parseJSONFeed(br)
+		}
+
+		if strings.IndexByte(" \t\r\n\xef\xbb\xbf", b[0]) < 0 {
+			break
+		}
+
+		br.ReadByte()
+	}
+
+	var doc xmlFeed
+	if err := xml.NewDecoder(br).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("inspect.ParseFeed: failed to parse feed: %w", err)
+	}
+
+	out := new(Feed)
+
+	var items []xmlFeedItem
+
+	switch doc.XMLName.Local {
+	case "rss":
+		out.Format, out.Title, items = FeedRSS, doc.Channel.Title, doc.Channel.Items
+	case "RDF":
+		out.Format, out.Title, items = FeedRSS, doc.Channel.Title, doc.Items
+	case "feed":
+		out.Format, out.Title, items = FeedAtom, doc.Title, doc.Entries
+	default:
+		return nil, fmt.Errorf("inspect.ParseFeed: not a feed, root element: %v", doc.XMLName.Local)
+	}
+
+	out.Title = strings.TrimSpace(out.Title)
+
+	for _, item := range items {
+		out.Items = append(out.Items, FeedItem{
+			Title:     strings.TrimSpace(item.Title),
+			Link:      item.link(),
+			Published: parseFeedDate(item.PubDate, item.Published, item.Date, item.Updated),
+		})
+	}
+
+	return out, nil
+}
+
+// parseJSONFeed parses a JSON Feed document.
+func parseJSONFeed(r io.Reader) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("inspect.ParseFeed: failed to parse feed: %w", err)
+	}
+
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("inspect.ParseFeed: not a JSON Feed, version: %q", doc.Version)
+	}
+
+	out := &Feed{Format: FeedJSON, Title: strings.TrimSpace(doc.Title)}
+
+	for _, item := range doc.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		out.Items = append(out.Items, FeedItem{
+			Title:     strings.TrimSpace(item.Title),
+			Link:      strings.TrimSpace(link),
+			Published: parseFeedDate(item.DatePublished, item.DateModified),
+		})
+	}
+
+	return out, nil
+}
+
+// link returns the URL of the RSS link or of the Atom alternate link of the item.
+func (item xmlFeedItem) link() string {
+	for _, l := range item.Links {
+		if v := strings.TrimSpace(l.Value); v != "" && l.Href == "" {
+			return v
+		}
+
+		if rel := strings.TrimSpace(l.Rel); l.Href != "" && (rel == "" || rel == "alternate") {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+
+	return ""
+}
+
+// parseFeedDate returns the first of the dates in a known format, zero if there is none.
+func parseFeedDate(dates ...string) time.Time {
+	for _, d := range dates {
+		d = strings.TrimSpace(d)
+
+		for _, layout := range feedDateLayouts {
+			if t, err := time.Parse(layout, d); err == nil {
+				return t
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// FetchFeed fetches and parses a single feed.
+func FetchFeed(ctx context.Context, f *Fetcher, link string) *Feed {
+	resp, err := f.Get(ctx, link)
+	if err != nil {
+		return &Feed{URL: link, Error: err.Error()}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return &Feed{URL: link, Error: fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode)}
+	}
+
+	feed, err := ParseFeed(resp.Body)
+	if err != nil {
+		return &Feed{URL: link, Error: err.Error()}
+	}
+
+	feed.URL = link
+
+	return feed
+}
+
+// CheckFeed fetches the feed and checks the links of its items with the link
+// checker. Relative item links are resolved against the URL of the feed.
+func CheckFeed(ctx context.Context, f *Fetcher, link string) *FeedReport {
+	out := &FeedReport{Feed: FetchFeed(ctx, f, link)}
+	if out.Feed.Error != "" {
+		return out
+	}
+
+	base, err := url.Parse(link)
+	if err != nil {
+		out.Feed.Error = err.Error()
+		return out
+	}
+
+	links := &PageContents{Links: make(map[string]map[string]struct{})}
+
+	for _, item := range out.Feed.Items {
+		if item.Published.After(out.Newest) {
+			out.Newest = item.Published
+		}
+
+		if item.Link == "" {
+			out.InvalidLinks = append(out.InvalidLinks, InvalidLink{Reason: fmt.Sprintf("item %q has no link", item.Title)})
+			continue
+		}
+
+		u, err := base.Parse(item.Link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			out.InvalidLinks = append(out.InvalidLinks, InvalidLink{URL: item.Link, Reason: "not a http(s) URL"})
+			continue
+		}
+
+		if links.Links[u.Hostname()] == nil {
+			links.Links[u.Hostname()] = make(map[string]struct{})
+		}
+
+		links.Links[u.Hostname()][u.String()] = struct{}{}
+	}
+
+	for _, broken := range links.CheckLinks(ctx, *base, f) {
+		out.BrokenLinks = append(out.BrokenLinks, broken...)
+	}
+
+	sort.Slice(out.BrokenLinks, func(i, j int) bool { return out.BrokenLinks[i].URL < out.BrokenLinks[j].URL })
+
+	return out
+}
+
+// FeedURL returns the absolute URL of the feed resolved against base.
+func (l FeedLink) FeedURL(base url.URL) string {
+	u, err := base.Parse(l.Href)
+	if err != nil {
+		return l.Href
+	}
+
+	return u.String()
+}
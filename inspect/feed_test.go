@@ -0,0 +1,169 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractFeedLink(t *testing.T) {
+	contents, err := Page(strings.NewReader(`<html><head>
+		<link rel="alternate" type="application/rss+xml" title=" Blog " href="/feed.xml">
+		<link rel="alternate" type="application/atom+xml; charset=utf-8" href="https://www.example.com/atom.xml">
+		<link rel="alternate" type="application/feed+json" href="/feed.json">
+		<link rel="alternate" hreflang="de" href="/de">
+		<link rel="stylesheet" type="application/rss+xml" href="/not-a-feed">
+	</head><body></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FeedLink{
+		{Href: "/feed.xml", Type: "application/rss+xml", Title: "Blog"},
+		{Href: "https://www.example.com/atom.xml", Type: "application/atom+xml"},
+		{Href: "/feed.json", Type: "application/feed+json"},
+	}
+
+	if diff := cmp.Diff(contents.Feeds, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Feed    string
+		Want    *Feed
+		wantErr bool
+	}{
+		{
+			Name: "ok-rss",
+			Feed: `<?xml version="1.0"?>
+				<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+					<title> Blog </title>
+					<atom:link href="https://www.example.com/feed.xml" rel="self"/>
+					<item><title>First</title><link>https://www.example.com/first</link><pubDate>Sat, 01 May 2021 10:00:00 +0000</pubDate></item>
+					<item><title>Second</title><link> /second </link><pubDate>Sun, 2 May 2021 10:00:00 GMT</pubDate></item>
+				</channel></rss>`,
+			Want: &Feed{Format: FeedRSS, Title: "Blog", Items: []FeedItem{
+				{Title: "First", Link: "https://www.example.com/first", Published: time.Date(2021, 5, 1, 10, 0, 0, 0, time.FixedZone("", 0))},
+				{Title: "Second", Link: "/second", Published: time.Date(2021, 5, 2, 10, 0, 0, 0, time.FixedZone("GMT", 0))},
+			}},
+		},
+		{
+			Name: "ok-rdf",
+			Feed: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+					<channel><title>Blog</title></channel>
+					<item><title>First</title><link>https://www.example.com/first</link><dc:date>2021-05-01T10:00:00Z</dc:date></item>
+				</rdf:RDF>`,
+			Want: &Feed{Format: FeedRSS, Title: "Blog", Items: []FeedItem{
+				{Title: "First", Link: "https://www.example.com/first", Published: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)},
+			}},
+		},
+		{
+			Name: "ok-atom",
+			Feed: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+					<entry><title>First</title><link rel="edit" href="/edit/1"/><link href="https://www.example.com/first"/><updated>2021-05-01T10:00:00Z</updated></entry>
+					<entry><title>Second</title><link rel="alternate" href="https://www.example.com/second"/><published>not a date</published></entry>
+				</feed>`,
+			Want: &Feed{Format: FeedAtom, Title: "Blog", Items: []FeedItem{
+				{Title: "First", Link: "https://www.example.com/first", Published: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)},
+				{Title: "Second", Link: "https://www.example.com/second"},
+			}},
+		},
+		{
+			Name: "ok-json",
+			Feed: "\xef\xbb\xbf\n" + `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog", "items": [
+					{"id": "1", "title": "First", "url": "https://www.example.com/first", "date_modified": "2021-05-01T10:00:00Z"},
+					{"id": "2", "external_url": "https://www.example.org/"}
+				]}`,
+			Want: &Feed{Format: FeedJSON, Title: "Blog", Items: []FeedItem{
+				{Title: "First", Link: "https://www.example.com/first", Published: time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)},
+				{Link: "https://www.example.org/"},
+			}},
+		},
+		{
+			Name:    "fail-json-version",
+			Feed:    `{"version": "1", "items": []}`,
+			wantErr: true,
+		},
+		{
+			Name:    "fail-not-a-feed",
+			Feed:    `<urlset></urlset>`,
+			wantErr: true,
+		},
+		{
+			Name:    "fail-empty",
+			Feed:    "  ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := ParseFeed(strings.NewReader(tt.Feed))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(have, tt.Want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestCheckFeed(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog/feed.xml":
+			rw.Write([]byte(`<rss version="2.0"><channel><title>Blog</title>
+				<item><title>First</title><link>first</link><pubDate>Sat, 01 May 2021 10:00:00 +0000</pubDate></item>
+				<item><title>Second</title><link>/down</link><pubDate>Mon, 03 May 2021 10:00:00 +0000</pubDate></item>
+				<item><title>Third</title><pubDate>Sun, 02 May 2021 10:00:00 +0000</pubDate></item>
+				<item><title>Fourth</title><link>mailto:blog@example.com</link></item>
+			</channel></rss>`))
+		case "/blog/first":
+			rw.Write([]byte(`<html></html>`))
+		default:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer mockServer.Close()
+
+	f := NewFetcher(FetchOptions{})
+
+	have := CheckFeed(context.Background(), f, mockServer.URL+"/blog/feed.xml")
+
+	want := &FeedReport{
+		Feed:   have.Feed,
+		Newest: time.Date(2021, 5, 3, 10, 0, 0, 0, time.FixedZone("", 0)),
+		InvalidLinks: []InvalidLink{
+			{Reason: `item "Third" has no link`},
+			{URL: "mailto:blog@example.com", Reason: "not a http(s) URL"},
+		},
+		BrokenLinks: []InvalidLink{
+			{URL: mockServer.URL + "/down", Reason: "endpoint responded with code: 503"},
+		},
+	}
+
+	if diff := cmp.Diff(have, want); diff != "" {
+		t.Error(diff)
+	}
+
+	if have.Feed.Title != "Blog" || len(have.Feed.Items) != 4 {
+		t.Errorf("Feed = %+v", have.Feed)
+	}
+
+	if broken := CheckFeed(context.Background(), f, mockServer.URL+"/missing.xml"); broken.Feed.Error != "endpoint responded with code: 503" {
+		t.Errorf("Error = %q", broken.Feed.Error)
+	}
+}
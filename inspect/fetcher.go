@@ -0,0 +1,249 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetchOptions configures the requests issued by a Fetcher.
+type FetchOptions struct {
+	// Client used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	// User-Agent header sent with every request.
+	UserAgent string
+
+	// Maximum number of concurrent requests, unlimited if 0.
+	Concurrency int
+
+	// Minimum delay between two requests to the same host.
+	Delay time.Duration
+
+	// Honor the robots.txt of the hosts, requests to disallowed URLs
+	// fail with ErrDisallowed and longer Crawl-delays are respected.
+	RespectRobots bool
+
+	// User-agent the robots.txt rules are matched against, UserAgent if empty.
+	RobotsUserAgent string
+}
+
+// Fetcher performs the requests issued by the link checker and the
+// crawler while honoring the concurrency and politeness settings.
+// A Fetcher is safe for concurrent use.
+type Fetcher struct {
+	opts FetchOptions
+
+	// limits the number of concurrent requests.
+	sem chan struct{}
+
+	mu sync.Mutex
+	// maps hosts to the earliest time of the next request.
+	next map[string]time.Time
+	// maps robots.txt URLs to their parsed contents.
+	robots map[string]*robotsEntry
+}
+
+// robotsEntry caches the robots.txt of a single host.
+type robotsEntry struct {
+	mu     sync.Mutex
+	robots *Robots
+}
+
+// NewFetcher creates a *Fetcher with the given options.
+func NewFetcher(opts FetchOptions) *Fetcher {
+	f := &Fetcher{
+		opts:   opts,
+		next:   make(map[string]time.Time),
+		robots: make(map[string]*robotsEntry),
+	}
+
+	if opts.Concurrency > 0 {
+		f.sem = make(chan struct{}, opts.Concurrency)
+	}
+
+	return f
+}
+
+// Options returns the options the fetcher was created with.
+func (f *Fetcher) Options() FetchOptions { return f.opts }
+
+// Get issues a GET request for the link. The concurrency slot taken
+// by the request is released once the response body is closed.
+func (f *Fetcher) Get(ctx context.Context, link string) (*http.Response, error) {
+	if !f.opts.RespectRobots {
+		return f.do(ctx, link)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return f.do(ctx, link)
+	}
+
+	robots := f.Robots(ctx, *u)
+	if !robots.Allowed(f.RobotsUserAgent(), link) {
+		return nil, fmt.Errorf("%w for user-agent %q", ErrDisallowed, f.RobotsUserAgent())
+	}
+
+	return f.get(ctx, link, robots.CrawlDelay(f.RobotsUserAgent()))
+}
+
+// Robots returns the robots.txt of the host of the URL. The robots.txt is
+// fetched once per host, if it can't be fetched everything is allowed and
+// it is fetched again by the next call.
+func (f *Fetcher) Robots(ctx context.Context, u url.URL) *Robots {
+	key := RobotsURL(u)
+
+	f.mu.Lock()
+	entry, ok := f.robots[key]
+	if !ok {
+		entry = new(robotsEntry)
+		f.robots[key] = entry
+	}
+	f.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.robots != nil {
+		return entry.robots
+	}
+
+	// failures, like a canceled context, are not cached.
+	robots, err := FetchRobots(ctx, f, u)
+	if err != nil {
+		return new(Robots)
+	}
+
+	entry.robots = robots
+
+	return robots
+}
+
+// RobotsUserAgent returns the user-agent the robots.txt rules are matched against.
+func (f *Fetcher) RobotsUserAgent() string {
+	switch {
+	case f.opts.RobotsUserAgent != "":
+		return f.opts.RobotsUserAgent
+	case f.opts.UserAgent != "":
+		return f.opts.UserAgent
+	}
+
+	return "Go-http-client"
+}
+
+// do issues a GET request for the link without consulting robots.txt.
+func (f *Fetcher) do(ctx context.Context, link string) (*http.Response, error) {
+	return f.get(ctx, link, 0)
+}
+
+// get issues a GET request for the link waiting at least
+// the politeness delay or the crawl delay, whichever is longer.
+func (f *Fetcher) get(ctx context.Context, link string, crawlDelay time.Duration) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", f.opts.UserAgent)
+	}
+
+	delay := f.opts.Delay
+	if crawlDelay > delay {
+		delay = crawlDelay
+	}
+
+	if err := f.wait(ctx, strings.ToLower(req.URL.Host), delay); err != nil {
+		return nil, err
+	}
+
+	if err := f.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	client := f.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		f.release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: f.release}
+
+	return resp, nil
+}
+
+// wait blocks until a request to the host is allowed by the delay.
+func (f *Fetcher) wait(ctx context.Context, host string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	now := time.Now()
+
+	at := f.next[host]
+	if at.Before(now) {
+		at = now
+	}
+
+	f.next[host] = at.Add(delay)
+	f.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (f *Fetcher) acquire(ctx context.Context) error {
+	if f.sem == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case f.sem <- struct{}{}:
+		return nil
+	}
+}
+
+func (f *Fetcher) release() {
+	if f.sem != nil {
+		<-f.sem
+	}
+}
+
+// releaseBody releases the concurrency slot of a request once closed.
+type releaseBody struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
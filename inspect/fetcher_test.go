@@ -0,0 +1,125 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFetcherUserAgent(t *testing.T) {
+	var userAgent string
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+	}))
+	defer mockServer.Close()
+
+	resp, err := NewFetcher(FetchOptions{UserAgent: "htmlinspect/1.0"}).Get(context.Background(), mockServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if userAgent != "htmlinspect/1.0" {
+		t.Errorf("Get() user agent = %q, want %q", userAgent, "htmlinspect/1.0")
+	}
+}
+
+func TestFetcherDelay(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer mockServer.Close()
+
+	const delay = 50 * time.Millisecond
+
+	f := NewFetcher(FetchOptions{Delay: delay})
+	wg := new(sync.WaitGroup)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := f.Get(context.Background(), mockServer.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			resp.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("Get() 3 requests took %v, want at least %v", elapsed, 2*delay)
+	}
+}
+
+func TestFetcherConcurrency(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		active, max int
+	)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > max {
+			max = active
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer mockServer.Close()
+
+	f := NewFetcher(FetchOptions{Concurrency: 2})
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := f.Get(context.Background(), mockServer.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			resp.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	if max > 2 {
+		t.Errorf("Get() max concurrent requests = %v, want at most 2", max)
+	}
+}
+
+func TestFetcherCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f := NewFetcher(FetchOptions{Concurrency: 1, Delay: time.Hour})
+
+	if _, err := f.Get(ctx, "http://127.0.0.1:1"); err == nil {
+		t.Error("Get() err = nil, want context canceled")
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+// Severity of a finding.
+type Severity string
+
+// Severity levels
+const (
+	SeverityInfo   Severity = "info"
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Finding represents a single observation made while
+// inspecting a page, graded by its severity.
+type Finding struct {
+	Severity Severity
+
+	// Name of the check that produced the finding (e.g. a header name).
+	Check string
+
+	// Human readable description of the finding.
+	Message string
+}
@@ -0,0 +1,283 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// GraphNode is a page of the internal link graph.
+type GraphNode struct {
+	URL   string
+	Title string
+
+	// Number of links followed from the seed, -1 if the page was not crawled.
+	Depth int
+
+	// Status code of the page, 0 if the page was not crawled.
+	StatusCode int
+}
+
+// GraphEdge is a link between two pages of the internal link graph.
+type GraphEdge struct {
+	From, To string
+
+	// Anchor text and rel attribute of the link.
+	Text string
+	Rel  string
+}
+
+// LinkGraph is the internal link graph of a crawled site.
+type LinkGraph struct {
+	// Crawled pages followed by the linked pages that were not crawled.
+	Nodes []GraphNode
+
+	// Links in the order they appear on the pages, a page linking to
+	// another page multiple times has an edge for every link.
+	Edges []GraphEdge
+}
+
+// LinkGraph builds the internal link graph of the crawl from the anchors of the pages.
+func (s *Site) LinkGraph() *LinkGraph {
+	var (
+		out   = new(LinkGraph)
+		nodes = make(map[string]struct{})
+	)
+
+	for _, page := range s.Pages {
+		node := GraphNode{URL: page.URL, Depth: page.Depth, StatusCode: page.StatusCode}
+		if page.Contents != nil {
+			node.Title = page.Contents.Title
+		}
+
+		nodes[page.URL] = struct{}{}
+		out.Nodes = append(out.Nodes, node)
+	}
+
+	for _, page := range s.Pages {
+		for _, e := range page.internalEdges() {
+			if _, ok := nodes[e.To]; !ok {
+				nodes[e.To] = struct{}{}
+				out.Nodes = append(out.Nodes, GraphNode{URL: e.To, Depth: -1})
+			}
+
+			out.Edges = append(out.Edges, e)
+		}
+	}
+
+	return out
+}
+
+// internalEdges returns the edges of the anchors of the page pointing to
+// the same host. Links are resolved against the final URL of the page.
+func (page *CrawledPage) internalEdges() []GraphEdge {
+	if page.Contents == nil {
+		return nil
+	}
+
+	base, err := url.Parse(page.FinalURL)
+	if err != nil {
+		return nil
+	}
+
+	var out []GraphEdge
+
+	for _, a := range page.Contents.Anchors {
+		u, err := base.Parse(strings.TrimSpace(a.Href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, base.Host) {
+			continue
+		}
+
+		u.Fragment, u.RawFragment = "", ""
+
+		out = append(out, GraphEdge{From: page.URL, To: u.String(), Text: a.Text, Rel: a.Rel})
+	}
+
+	return out
+}
+
+// WriteDOT writes the graph in the Graphviz DOT format.
+func (g *LinkGraph) WriteDOT(w io.Writer) error {
+	buf := new(strings.Builder)
+
+	buf.WriteString("digraph site {\n")
+
+	for _, n := range g.Nodes {
+		label := n.Title
+		if label == "" {
+			label = n.URL
+		}
+
+		fmt.Fprintf(buf, "  %v [label=%v, depth=%v, status_code=%v];\n", dotQuote(n.URL), dotQuote(label), n.Depth, n.StatusCode)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(buf, "  %v -> %v [label=%v, rel=%v];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Text), dotQuote(e.Rel))
+	}
+
+	buf.WriteString("}\n")
+
+	if _, err := io.WriteString(w, buf.String()); err != nil {
+		return fmt.Errorf("inspect.WriteDOT: %w", err)
+	}
+
+	return nil
+}
+
+// dotQuote quotes the string as a DOT identifier.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "").Replace(s) + `"`
+}
+
+type (
+	graphML struct {
+		XMLName xml.Name     `xml:"graphml"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   graphMLGraph `xml:"graph"`
+	}
+
+	graphMLKey struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+
+	graphMLGraph struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	}
+
+	graphMLNode struct {
+		ID   string        `xml:"id,attr"`
+		Data []graphMLData `xml:"data"`
+	}
+
+	graphMLEdge struct {
+		ID     string        `xml:"id,attr"`
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+
+	graphMLData struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+)
+
+// WriteGraphML writes the graph in the GraphML format.
+func (g *LinkGraph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "url", For: "node", Name: "url", Type: "string"},
+			{ID: "depth", For: "node", Name: "depth", Type: "int"},
+			{ID: "status_code", For: "node", Name: "status_code", Type: "int"},
+			{ID: "text", For: "edge", Name: "text", Type: "string"},
+			{ID: "rel", For: "edge", Name: "rel", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "site", EdgeDefault: "directed"},
+	}
+
+	ids := make(map[string]string)
+
+	for i, n := range g.Nodes {
+		ids[n.URL] = fmt.Sprintf("n%v", i)
+
+		label := n.Title
+		if label == "" {
+			label = n.URL
+		}
+
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: ids[n.URL],
+			Data: []graphMLData{
+				{Key: "label", Value: label},
+				{Key: "url", Value: n.URL},
+				{Key: "depth", Value: fmt.Sprint(n.Depth)},
+				{Key: "status_code", Value: fmt.Sprint(n.StatusCode)},
+			},
+		})
+	}
+
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%v", i),
+			Source: ids[e.From],
+			Target: ids[e.To],
+			Data: []graphMLData{
+				{Key: "text", Value: e.Text},
+				{Key: "rel", Value: e.Rel},
+			},
+		})
+	}
+
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("inspect.WriteGraphML: failed to encode graph: %w", err)
+	}
+
+	if _, err := io.WriteString(w, xml.Header+string(b)+"\n"); err != nil {
+		return fmt.Errorf("inspect.WriteGraphML: %w", err)
+	}
+
+	return nil
+}
+
+type (
+	jsonGraph struct {
+		Nodes     []jsonGraphNode            `json:"nodes"`
+		Adjacency map[string][]jsonGraphEdge `json:"adjacency"`
+	}
+
+	jsonGraphNode struct {
+		URL        string `json:"url"`
+		Title      string `json:"title"`
+		Depth      int    `json:"depth"`
+		StatusCode int    `json:"status_code"`
+	}
+
+	jsonGraphEdge struct {
+		To   string `json:"to"`
+		Text string `json:"text"`
+		Rel  string `json:"rel"`
+	}
+)
+
+// WriteJSON writes the graph as a JSON object with the nodes and
+// an adjacency list mapping the URL of every page to its links.
+func (g *LinkGraph) WriteJSON(w io.Writer) error {
+	doc := jsonGraph{
+		Nodes:     []jsonGraphNode{},
+		Adjacency: make(map[string][]jsonGraphEdge),
+	}
+
+	for _, n := range g.Nodes {
+		doc.Nodes = append(doc.Nodes, jsonGraphNode{URL: n.URL, Title: n.Title, Depth: n.Depth, StatusCode: n.StatusCode})
+		doc.Adjacency[n.URL] = []jsonGraphEdge{}
+	}
+
+	for _, e := range g.Edges {
+		doc.Adjacency[e.From] = append(doc.Adjacency[e.From], jsonGraphEdge{To: e.To, Text: e.Text, Rel: e.Rel})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("inspect.WriteJSON: failed to encode graph: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func mockGraphSite() *Site {
+	return &Site{
+		Seed: "https://www.example.com/",
+		Pages: []*CrawledPage{
+			{
+				URL:        "https://www.example.com/",
+				FinalURL:   "https://www.example.com/",
+				StatusCode: 200,
+				Contents: &PageContents{
+					Title: `Home "page"`,
+					Anchors: []Anchor{
+						{Href: "/about#team", Text: "About us"},
+						{Href: "about", Text: "About"},
+						{Href: "https://www.example.com/deep", Text: "Deep", Rel: "nofollow"},
+						{Href: "https://www.google.com", Text: "Google"},
+						{Href: "mailto:info@example.com", Text: "Mail"},
+					},
+				},
+			},
+			{
+				URL:        "https://www.example.com/about",
+				FinalURL:   "https://www.example.com/about",
+				Depth:      1,
+				StatusCode: 200,
+				Contents: &PageContents{
+					Anchors: []Anchor{{Href: "/", Text: "Home & more"}},
+				},
+			},
+		},
+	}
+}
+
+func TestLinkGraph(t *testing.T) {
+	want := &LinkGraph{
+		Nodes: []GraphNode{
+			{URL: "https://www.example.com/", Title: `Home "page"`, StatusCode: 200},
+			{URL: "https://www.example.com/about", Depth: 1, StatusCode: 200},
+			{URL: "https://www.example.com/deep", Depth: -1},
+		},
+		Edges: []GraphEdge{
+			{From: "https://www.example.com/", To: "https://www.example.com/about", Text: "About us"},
+			{From: "https://www.example.com/", To: "https://www.example.com/about", Text: "About"},
+			{From: "https://www.example.com/", To: "https://www.example.com/deep", Text: "Deep", Rel: "nofollow"},
+			{From: "https://www.example.com/about", To: "https://www.example.com/", Text: "Home & more"},
+		},
+	}
+
+	if diff := cmp.Diff(mockGraphSite().LinkGraph(), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	buf := new(strings.Builder)
+	if err := mockGraphSite().LinkGraph().WriteDOT(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph site {
+  "https://www.example.com/" [label="Home \"page\"", depth=0, status_code=200];
+  "https://www.example.com/about" [label="https://www.example.com/about", depth=1, status_code=200];
+  "https://www.example.com/deep" [label="https://www.example.com/deep", depth=-1, status_code=0];
+  "https://www.example.com/" -> "https://www.example.com/about" [label="About us", rel=""];
+  "https://www.example.com/" -> "https://www.example.com/about" [label="About", rel=""];
+  "https://www.example.com/" -> "https://www.example.com/deep" [label="Deep", rel="nofollow"];
+  "https://www.example.com/about" -> "https://www.example.com/" [label="Home & more", rel=""];
+}
+`
+
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	buf := new(strings.Builder)
+	if err := mockGraphSite().LinkGraph().WriteGraphML(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"></key>
+  <key id="url" for="node" attr.name="url" attr.type="string"></key>
+  <key id="depth" for="node" attr.name="depth" attr.type="int"></key>
+  <key id="status_code" for="node" attr.name="status_code" attr.type="int"></key>
+  <key id="text" for="edge" attr.name="text" attr.type="string"></key>
+  <key id="rel" for="edge" attr.name="rel" attr.type="string"></key>
+  <graph id="site" edgedefault="directed">
+    <node id="n0">
+      <data key="label">Home &#34;page&#34;</data>
+      <data key="url">https://www.example.com/</data>
+      <data key="depth">0</data>
+      <data key="status_code">200</data>
+    </node>
+    <node id="n1">
+      <data key="label">https://www.example.com/about</data>
+      <data key="url">https://www.example.com/about</data>
+      <data key="depth">1</data>
+      <data key="status_code">200</data>
+    </node>
+    <node id="n2">
+      <data key="label">https://www.example.com/deep</data>
+      <data key="url">https://www.example.com/deep</data>
+      <data key="depth">-1</data>
+      <data key="status_code">0</data>
+    </node>
+    <edge id="e0" source="n0" target="n1">
+      <data key="text">About us</data>
+      <data key="rel"></data>
+    </edge>
+    <edge id="e1" source="n0" target="n1">
+      <data key="text">About</data>
+      <data key="rel"></data>
+    </edge>
+    <edge id="e2" source="n0" target="n2">
+      <data key="text">Deep</data>
+      <data key="rel">nofollow</data>
+    </edge>
+    <edge id="e3" source="n1" target="n0">
+      <data key="text">Home &amp; more</data>
+      <data key="rel"></data>
+    </edge>
+  </graph>
+</graphml>
+`
+
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := new(strings.Builder)
+	if err := mockGraphSite().LinkGraph().WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"nodes":[{"url":"https://www.example.com/","title":"Home \"page\"","depth":0,"status_code":200},{"url":"https://www.example.com/about","title":"","depth":1,"status_code":200},{"url":"https://www.example.com/deep","title":"","depth":-1,"status_code":0}],"adjacency":{"https://www.example.com/":[{"to":"https://www.example.com/about","text":"About us","rel":""},{"to":"https://www.example.com/about","text":"About","rel":""},{"to":"https://www.example.com/deep","text":"Deep","rel":"nofollow"}],"https://www.example.com/about":[{"to":"https://www.example.com/","text":"Home & more","rel":""}],"https://www.example.com/deep":[]}}
+`
+
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Error(diff)
+	}
+}
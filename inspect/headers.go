@@ -0,0 +1,296 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hstsMinMaxAge is the minimum max-age (1 year) recommended
+// for HSTS and required for the HSTS preload list.
+const hstsMinMaxAge = 31536000
+
+// reBannerVersion matches version numbers disclosed in server banners.
+var reBannerVersion = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// ResponseInfo contains the HTTP response metadata of an inspected page.
+type ResponseInfo struct {
+	StatusCode  int
+	ContentType string
+
+	// Length of the body as announced by the server, -1 if unknown.
+	ContentLength int64
+
+	// Content-Encoding the body was transferred with.
+	Compression string
+
+	// Caching headers.
+	CacheControl string
+	ETag         string
+	LastModified string
+	Age          string
+
+	// Server banners.
+	Server    string
+	PoweredBy string
+}
+
+// HSTS is a parsed Strict-Transport-Security header.
+type HSTS struct {
+	MaxAge            int64
+	IncludeSubDomains bool
+	Preload           bool
+}
+
+// Response extracts the metadata of the response of an inspected page.
+func Response(resp *http.Response) *ResponseInfo {
+	info := &ResponseInfo{
+		StatusCode:    resp.StatusCode,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+		Compression:   resp.Header.Get("Content-Encoding"),
+		CacheControl:  resp.Header.Get("Cache-Control"),
+		ETag:          resp.Header.Get("ETag"),
+		LastModified:  resp.Header.Get("Last-Modified"),
+		Age:           resp.Header.Get("Age"),
+		Server:        resp.Header.Get("Server"),
+		PoweredBy:     resp.Header.Get("X-Powered-By"),
+	}
+
+	// the transport removes the Content-Encoding header when
+	// it transparently decompressed a gzip encoded body.
+	if resp.Uncompressed {
+		info.Compression = "gzip"
+	}
+
+	return info
+}
+
+// ParseHSTS parses the value of a Strict-Transport-Security header.
+func ParseHSTS(value string) (*HSTS, error) {
+	var (
+		out       = new(HSTS)
+		hasMaxAge = false
+	)
+
+	for _, directive := range strings.Split(value, ";") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		name, val := directive, ""
+		if i := strings.Index(directive, "="); i >= 0 {
+			name, val = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			maxAge, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || maxAge < 0 {
+				return nil, fmt.Errorf("inspect.ParseHSTS: invalid max-age %q", val)
+			}
+
+			out.MaxAge = maxAge
+			hasMaxAge = true
+		case "includesubdomains":
+			out.IncludeSubDomains = true
+		case "preload":
+			out.Preload = true
+		}
+	}
+
+	if !hasMaxAge {
+		return nil, fmt.Errorf("inspect.ParseHSTS: missing max-age directive")
+	}
+
+	return out, nil
+}
+
+// SecurityHeaders grades the security related headers of a response.
+// The secure parameter reports if the response was received over TLS.
+func SecurityHeaders(h http.Header, secure bool) []Finding {
+	var out []Finding
+
+	if secure {
+		out = append(out, checkHSTS(h.Get("Strict-Transport-Security"))...)
+	}
+
+	out = append(out, checkCSPHeader(h)...)
+	out = append(out, checkFrameOptions(h)...)
+	out = append(out, checkContentTypeOptions(h.Get("X-Content-Type-Options"))...)
+	out = append(out, checkReferrerPolicy(h.Get("Referrer-Policy"))...)
+	out = append(out, checkPermissionsPolicy(h)...)
+	out = append(out, checkCrossOriginPolicies(h)...)
+	out = append(out, checkBanners(h)...)
+
+	return out
+}
+
+func checkHSTS(value string) []Finding {
+	const check = "strict-transport-security"
+
+	if value == "" {
+		return []Finding{{SeverityMedium, check, "HSTS header is missing"}}
+	}
+
+	hsts, err := ParseHSTS(value)
+	if err != nil {
+		return []Finding{{SeverityMedium, check, fmt.Sprintf("HSTS header is invalid: %v", err)}}
+	}
+
+	if hsts.MaxAge == 0 {
+		return []Finding{{SeverityMedium, check, "HSTS is disabled with max-age=0"}}
+	}
+
+	var out []Finding
+
+	if hsts.MaxAge < hstsMinMaxAge {
+		out = append(out, Finding{SeverityLow, check, fmt.Sprintf("HSTS max-age=%v is shorter than one year", hsts.MaxAge)})
+	}
+
+	if hsts.Preload && (!hsts.IncludeSubDomains || hsts.MaxAge < hstsMinMaxAge) {
+		out = append(out, Finding{SeverityLow, check, "HSTS preload requires includeSubDomains and a max-age of at least one year"})
+	}
+
+	if len(out) == 0 {
+		out = append(out, Finding{SeverityInfo, check, fmt.Sprintf("HSTS is enabled with max-age=%v", hsts.MaxAge)})
+	}
+
+	return out
+}
+
+func checkCSPHeader(h http.Header) []Finding {
+	const check = "content-security-policy"
+
+	if h.Get("Content-Security-Policy") != "" {
+		return []Finding{{SeverityInfo, check, "Content-Security-Policy is enforced"}}
+	}
+
+	if h.Get("Content-Security-Policy-Report-Only") != "" {
+		return []Finding{{SeverityLow, check, "Content-Security-Policy is only in report-only mode"}}
+	}
+
+	return []Finding{{SeverityMedium, check, "Content-Security-Policy header is missing"}}
+}
+
+func checkFrameOptions(h http.Header) []Finding {
+	const check = "x-frame-options"
+
+	// every delivered policy is enforced, any of them may restrict framing.
+	for _, policy := range h.Values("Content-Security-Policy") {
+		if ParseCSP(policy).Has("frame-ancestors") {
+			return []Finding{{SeverityInfo, check, "framing is restricted by the frame-ancestors directive"}}
+		}
+	}
+
+	value := strings.ToUpper(strings.TrimSpace(h.Get("X-Frame-Options")))
+
+	switch {
+	case value == "":
+		return []Finding{{SeverityMedium, check, "neither X-Frame-Options nor frame-ancestors is set, the page can be framed"}}
+	case value == "DENY" || value == "SAMEORIGIN":
+		return []Finding{{SeverityInfo, check, fmt.Sprintf("framing is restricted with %v", value)}}
+	case strings.HasPrefix(value, "ALLOW-FROM"):
+		return []Finding{{SeverityLow, check, "ALLOW-FROM is not supported by modern browsers, use frame-ancestors instead"}}
+	}
+
+	return []Finding{{SeverityMedium, check, fmt.Sprintf("X-Frame-Options has an invalid value %q", value)}}
+}
+
+func checkContentTypeOptions(value string) []Finding {
+	const check = "x-content-type-options"
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "nosniff":
+		return []Finding{{SeverityInfo, check, "MIME type sniffing is disabled"}}
+	case "":
+		return []Finding{{SeverityLow, check, "X-Content-Type-Options header is missing"}}
+	}
+
+	return []Finding{{SeverityLow, check, fmt.Sprintf("X-Content-Type-Options has an invalid value %q", value)}}
+}
+
+func checkReferrerPolicy(value string) []Finding {
+	const check = "referrer-policy"
+
+	if value == "" {
+		return []Finding{{SeverityLow, check, "Referrer-Policy header is missing"}}
+	}
+
+	// browsers use the last policy they recognize.
+	policy := ""
+	for _, p := range strings.Split(value, ",") {
+		switch p = strings.ToLower(strings.TrimSpace(p)); p {
+		case "no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+			"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url":
+			policy = p
+		}
+	}
+
+	switch policy {
+	case "":
+		return []Finding{{SeverityLow, check, fmt.Sprintf("Referrer-Policy has an invalid value %q", value)}}
+	case "unsafe-url":
+		return []Finding{{SeverityMedium, check, "unsafe-url leaks the full URL to every origin"}}
+	case "no-referrer-when-downgrade":
+		return []Finding{{SeverityLow, check, "no-referrer-when-downgrade leaks the full URL to other origins"}}
+	}
+
+	return []Finding{{SeverityInfo, check, fmt.Sprintf("Referrer-Policy is set to %v", policy)}}
+}
+
+func checkPermissionsPolicy(h http.Header) []Finding {
+	const check = "permissions-policy"
+
+	if h.Get("Permissions-Policy") != "" {
+		return []Finding{{SeverityInfo, check, "Permissions-Policy is set"}}
+	}
+
+	if h.Get("Feature-Policy") != "" {
+		return []Finding{{SeverityLow, check, "Feature-Policy is deprecated, use Permissions-Policy instead"}}
+	}
+
+	return []Finding{{SeverityLow, check, "Permissions-Policy header is missing"}}
+}
+
+func checkCrossOriginPolicies(h http.Header) []Finding {
+	var out []Finding
+
+	switch coop := strings.ToLower(strings.TrimSpace(h.Get("Cross-Origin-Opener-Policy"))); coop {
+	case "same-origin", "same-origin-allow-popups":
+		out = append(out, Finding{SeverityInfo, "cross-origin-opener-policy", fmt.Sprintf("Cross-Origin-Opener-Policy is set to %v", coop)})
+	case "", "unsafe-none":
+		out = append(out, Finding{SeverityLow, "cross-origin-opener-policy", "the page does not isolate its browsing context group"})
+	default:
+		out = append(out, Finding{SeverityLow, "cross-origin-opener-policy", fmt.Sprintf("Cross-Origin-Opener-Policy has an invalid value %q", coop)})
+	}
+
+	switch coep := strings.ToLower(strings.TrimSpace(h.Get("Cross-Origin-Embedder-Policy"))); coep {
+	case "require-corp", "credentialless":
+		out = append(out, Finding{SeverityInfo, "cross-origin-embedder-policy", fmt.Sprintf("Cross-Origin-Embedder-Policy is set to %v", coep)})
+	case "", "unsafe-none":
+		out = append(out, Finding{SeverityInfo, "cross-origin-embedder-policy", "the page is not cross-origin isolated"})
+	default:
+		out = append(out, Finding{SeverityLow, "cross-origin-embedder-policy", fmt.Sprintf("Cross-Origin-Embedder-Policy has an invalid value %q", coep)})
+	}
+
+	return out
+}
+
+func checkBanners(h http.Header) []Finding {
+	var out []Finding
+
+	for _, header := range []string{"Server", "X-Powered-By", "X-AspNet-Version"} {
+		if value := h.Get(header); reBannerVersion.MatchString(value) {
+			out = append(out, Finding{SeverityLow, strings.ToLower(header), fmt.Sprintf("banner discloses software version %q", value)})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHSTS(t *testing.T) {
+	tests := []struct {
+		Name    string
+		in      string
+		want    *HSTS
+		wantErr bool
+	}{
+		{
+			Name: "ok",
+			in:   "max-age=31536000; includeSubDomains; preload",
+			want: &HSTS{MaxAge: 31536000, IncludeSubDomains: true, Preload: true},
+		},
+		{
+			Name: "ok-quoted",
+			in:   `max-age="600"`,
+			want: &HSTS{MaxAge: 600},
+		},
+		{
+			Name:    "fail-missing-max-age",
+			in:      "includeSubDomains",
+			wantErr: true,
+		},
+		{
+			Name:    "fail-invalid-max-age",
+			in:      "max-age=-1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := ParseHSTS(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseHSTS() err = %v, want %v", err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		Name   string
+		header http.Header
+		secure bool
+		want   []Finding
+	}{
+		{
+			Name:   "ok-missing",
+			header: http.Header{},
+			secure: true,
+			want: []Finding{
+				{SeverityMedium, "strict-transport-security", "HSTS header is missing"},
+				{SeverityMedium, "content-security-policy", "Content-Security-Policy header is missing"},
+				{SeverityMedium, "x-frame-options", "neither X-Frame-Options nor frame-ancestors is set, the page can be framed"},
+				{SeverityLow, "x-content-type-options", "X-Content-Type-Options header is missing"},
+				{SeverityLow, "referrer-policy", "Referrer-Policy header is missing"},
+				{SeverityLow, "permissions-policy", "Permissions-Policy header is missing"},
+				{SeverityLow, "cross-origin-opener-policy", "the page does not isolate its browsing context group"},
+				{SeverityInfo, "cross-origin-embedder-policy", "the page is not cross-origin isolated"},
+			},
+		},
+		{
+			Name: "ok-hardened",
+			header: http.Header{
+				"Strict-Transport-Security":    {"max-age=63072000; includeSubDomains; preload"},
+				"Content-Security-Policy":      {"default-src 'self'; frame-ancestors 'none'"},
+				"X-Content-Type-Options":       {"nosniff"},
+				"Referrer-Policy":              {"no-referrer, strict-origin-when-cross-origin"},
+				"Permissions-Policy":           {"geolocation=()"},
+				"Cross-Origin-Opener-Policy":   {"same-origin"},
+				"Cross-Origin-Embedder-Policy": {"require-corp"},
+				"Server":                       {"nginx"},
+			},
+			secure: true,
+			want: []Finding{
+				{SeverityInfo, "strict-transport-security", "HSTS is enabled with max-age=63072000"},
+				{SeverityInfo, "content-security-policy", "Content-Security-Policy is enforced"},
+				{SeverityInfo, "x-frame-options", "framing is restricted by the frame-ancestors directive"},
+				{SeverityInfo, "x-content-type-options", "MIME type sniffing is disabled"},
+				{SeverityInfo, "referrer-policy", "Referrer-Policy is set to strict-origin-when-cross-origin"},
+				{SeverityInfo, "permissions-policy", "Permissions-Policy is set"},
+				{SeverityInfo, "cross-origin-opener-policy", "Cross-Origin-Opener-Policy is set to same-origin"},
+				{SeverityInfo, "cross-origin-embedder-policy", "Cross-Origin-Embedder-Policy is set to require-corp"},
+			},
+		},
+		{
+			Name: "ok-frame-ancestors-second-policy",
+			header: http.Header{
+				"Content-Security-Policy": {"default-src 'self'", "frame-ancestors 'self'"},
+				"X-Content-Type-Options":  {"nosniff"},
+			},
+			secure: false,
+			want: []Finding{
+				{SeverityInfo, "content-security-policy", "Content-Security-Policy is enforced"},
+				{SeverityInfo, "x-frame-options", "framing is restricted by the frame-ancestors directive"},
+				{SeverityInfo, "x-content-type-options", "MIME type sniffing is disabled"},
+				{SeverityLow, "referrer-policy", "Referrer-Policy header is missing"},
+				{SeverityLow, "permissions-policy", "Permissions-Policy header is missing"},
+				{SeverityLow, "cross-origin-opener-policy", "the page does not isolate its browsing context group"},
+				{SeverityInfo, "cross-origin-embedder-policy", "the page is not cross-origin isolated"},
+			},
+		},
+		{
+			Name: "ok-weak",
+			header: http.Header{
+				"Strict-Transport-Security":           {"max-age=600; preload"},
+				"Content-Security-Policy-Report-Only": {"default-src 'self'"},
+				"X-Frame-Options":                     {"ALLOW-FROM https://example.com"},
+				"Referrer-Policy":                     {"unsafe-url"},
+				"Feature-Policy":                      {"camera 'none'"},
+				"Server":                              {"Apache/2.4.1"},
+				"X-Powered-By":                        {"PHP/7.4.3"},
+			},
+			secure: true,
+			want: []Finding{
+				{SeverityLow, "strict-transport-security", "HSTS max-age=600 is shorter than one year"},
+				{SeverityLow, "strict-transport-security", "HSTS preload requires includeSubDomains and a max-age of at least one year"},
+				{SeverityLow, "content-security-policy", "Content-Security-Policy is only in report-only mode"},
+				{SeverityLow, "x-frame-options", "ALLOW-FROM is not supported by modern browsers, use frame-ancestors instead"},
+				{SeverityLow, "x-content-type-options", "X-Content-Type-Options header is missing"},
+				{SeverityMedium, "referrer-policy", "unsafe-url leaks the full URL to every origin"},
+				{SeverityLow, "permissions-policy", "Feature-Policy is deprecated, use Permissions-Policy instead"},
+				{SeverityLow, "cross-origin-opener-policy", "the page does not isolate its browsing context group"},
+				{SeverityInfo, "cross-origin-embedder-policy", "the page is not cross-origin isolated"},
+				{SeverityLow, "server", `banner discloses software version "Apache/2.4.1"`},
+				{SeverityLow, "x-powered-by", `banner discloses software version "PHP/7.4.3"`},
+			},
+		},
+		{
+			Name: "ok-insecure-skips-hsts",
+			header: http.Header{
+				"Strict-Transport-Security": {"max-age=0"},
+				"Content-Security-Policy":   {"default-src 'self'"},
+				"X-Frame-Options":           {"sameorigin"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"Referrer-Policy":           {"origin"},
+				"Permissions-Policy":        {"geolocation=()"},
+			},
+			secure: false,
+			want: []Finding{
+				{SeverityInfo, "content-security-policy", "Content-Security-Policy is enforced"},
+				{SeverityInfo, "x-frame-options", "framing is restricted with SAMEORIGIN"},
+				{SeverityInfo, "x-content-type-options", "MIME type sniffing is disabled"},
+				{SeverityInfo, "referrer-policy", "Referrer-Policy is set to origin"},
+				{SeverityInfo, "permissions-policy", "Permissions-Policy is set"},
+				{SeverityLow, "cross-origin-opener-policy", "the page does not isolate its browsing context group"},
+				{SeverityInfo, "cross-origin-embedder-policy", "the page is not cross-origin isolated"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have := SecurityHeaders(tt.header, tt.secure)
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		Name string
+		resp *http.Response
+		want *ResponseInfo
+	}{
+		{
+			Name: "ok",
+			resp: &http.Response{
+				StatusCode:    http.StatusOK,
+				ContentLength: 120,
+				Header: http.Header{
+					"Content-Type":     {"text/html"},
+					"Content-Encoding": {"br"},
+					"Cache-Control":    {"max-age=60"},
+					"Etag":             {`"abc"`},
+					"Last-Modified":    {"Wed, 21 Oct 2015 07:28:00 GMT"},
+					"Age":              {"12"},
+					"Server":           {"nginx"},
+					"X-Powered-By":     {"Express"},
+				},
+			},
+			want: &ResponseInfo{
+				StatusCode:    http.StatusOK,
+				ContentType:   "text/html",
+				ContentLength: 120,
+				Compression:   "br",
+				CacheControl:  "max-age=60",
+				ETag:          `"abc"`,
+				LastModified:  "Wed, 21 Oct 2015 07:28:00 GMT",
+				Age:           "12",
+				Server:        "nginx",
+				PoweredBy:     "Express",
+			},
+		},
+		{
+			Name: "ok-uncompressed",
+			resp: &http.Response{
+				StatusCode:    http.StatusNotFound,
+				ContentLength: -1,
+				Uncompressed:  true,
+				Header:        http.Header{},
+			},
+			want: &ResponseInfo{
+				StatusCode:    http.StatusNotFound,
+				ContentLength: -1,
+				Compression:   "gzip",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if diff := cmp.Diff(Response(tt.resp), tt.want); diff != "" {
+				t.Error(diff)
+				return
+			}
+		})
+	}
+}
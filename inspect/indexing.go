@@ -0,0 +1,151 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractIndexing extracts the <meta name="robots"> directives and the
+// canonical, hreflang alternate, pagination and AMP <link> elements of the page.
+func (p *PageContents) extractIndexing(node *html.Node) {
+	switch strings.ToLower(node.Data) {
+	case "meta":
+		if name, _ := getAttribute(node, "name"); strings.EqualFold(strings.TrimSpace(name), "robots") {
+			content, _ := getAttribute(node, "content")
+			p.MetaRobots = append(p.MetaRobots, robotsDirectives(content)...)
+		}
+	case "link":
+		rel, _ := getAttribute(node, "rel")
+		href, ok := getAttribute(node, "href")
+
+		if !ok {
+			return
+		}
+
+		href = strings.TrimSpace(href)
+
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			switch r {
+			case "canonical":
+				if p.Canonical == "" {
+					p.Canonical = href
+				}
+
+				p.Canonicals = append(p.Canonicals, href)
+			case "alternate":
+				if lang, ok := getAttribute(node, "hreflang"); ok {
+					p.Alternates = append(p.Alternates, Alternate{HrefLang: strings.TrimSpace(lang), Href: href})
+				}
+			case "next":
+				if p.Next == "" {
+					p.Next = href
+				}
+			case "prev", "previous":
+				if p.Prev == "" {
+					p.Prev = href
+				}
+			case "amphtml":
+				if p.AMPHTML == "" {
+					p.AMPHTML = href
+				}
+			}
+		}
+	}
+}
+
+// robotsDirectives splits a comma separated list of robots directives.
+func robotsDirectives(content string) []string {
+	var out []string
+
+	for _, d := range strings.Split(content, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			out = append(out, d)
+		}
+	}
+
+	return out
+}
+
+// NoIndex checks if the page is excluded from search engine indexes by
+// a noindex or none directive in the X-Robots-Tag header or <meta name="robots">.
+func NoIndex(header http.Header, contents *PageContents) bool {
+	var directives []string
+
+	for _, v := range header.Values("X-Robots-Tag") {
+		// directives can be prefixed with the user-agent they apply to.
+		if i := strings.Index(v, ":"); i >= 0 && !strings.ContainsAny(v[:i], ",") {
+			if agent := strings.ToLower(strings.TrimSpace(v[:i])); agent != "googlebot" && agent != "bingbot" {
+				continue
+			}
+
+			v = v[i+1:]
+		}
+
+		directives = append(directives, robotsDirectives(v)...)
+	}
+
+	if contents != nil {
+		directives = append(directives, contents.MetaRobots...)
+	}
+
+	for _, d := range directives {
+		if d == "noindex" || d == "none" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanonicalURL returns the absolute canonical URL of the page resolved
+// against base, an empty string if the page does not declare one.
+func (p *PageContents) CanonicalURL(base url.URL) string {
+	if p.Canonical == "" {
+		return ""
+	}
+
+	u, err := base.Parse(p.Canonical)
+	if err != nil {
+		return ""
+	}
+
+	u.Fragment, u.RawFragment = "", ""
+
+	return u.String()
+}
+
+// SitemapExclusion returns the reason the crawled page can't be listed
+// in a sitemap, an empty string if the page is an indexable canonical
+// page that responded with 200.
+func (page *CrawledPage) SitemapExclusion() string {
+	switch {
+	case page.Error != "":
+		return page.Error
+	case page.StatusCode != http.StatusOK:
+		return fmt.Sprintf("endpoint responded with code: %v", page.StatusCode)
+	case normalizeURL(page.FinalURL) != normalizeURL(page.URL):
+		return fmt.Sprintf("redirects to %v", page.FinalURL)
+	case page.Contents == nil:
+		return "not a HTML page"
+	case NoIndex(page.Header, page.Contents):
+		return "page is marked noindex"
+	}
+
+	u, err := url.Parse(page.FinalURL)
+	if err != nil {
+		return err.Error()
+	}
+
+	if canonical := page.Contents.CanonicalURL(*u); canonical != "" && normalizeURL(canonical) != normalizeURL(page.URL) {
+		return fmt.Sprintf("canonical URL is %v", canonical)
+	}
+
+	return ""
+}
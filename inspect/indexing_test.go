@@ -0,0 +1,119 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExtractIndexing(t *testing.T) {
+	contents, err := Page(strings.NewReader(`<html><head>
+		<meta name="Robots" content="NoIndex, nofollow">
+		<meta name="description" content="noindex">
+		<link rel="canonical" href=" https://www.example.com/page ">
+		<link rel="canonical" href="https://www.example.com/other">
+		<link rel="alternate" hreflang="en-GB" href="https://www.example.com/page">
+		<link rel="alternate" href="https://www.example.com/feed.xml">
+		<link rel="next" href="/page/2">
+		<link rel="prev" href="/page/0">
+		<link rel="amphtml" href="/page/amp">
+	</head><body></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(contents.MetaRobots, []string{"noindex", "nofollow"}); diff != "" {
+		t.Error(diff)
+	}
+
+	if contents.Canonical != "https://www.example.com/page" {
+		t.Errorf("Canonical = %q, want %q", contents.Canonical, "https://www.example.com/page")
+	}
+
+	if diff := cmp.Diff(contents.Canonicals, []string{"https://www.example.com/page", "https://www.example.com/other"}); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff(contents.Alternates, []Alternate{{HrefLang: "en-GB", Href: "https://www.example.com/page"}}); diff != "" {
+		t.Error(diff)
+	}
+
+	if have := []string{contents.Next, contents.Prev, contents.AMPHTML}; !cmp.Equal(have, []string{"/page/2", "/page/0", "/page/amp"}) {
+		t.Errorf("Next, Prev, AMPHTML = %q", have)
+	}
+}
+
+func TestNoIndex(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Header   http.Header
+		Contents *PageContents
+		Want     bool
+	}{
+		{Name: "ok-indexable", Header: http.Header{}, Contents: &PageContents{MetaRobots: []string{"nofollow"}}},
+		{Name: "ok-meta-noindex", Header: http.Header{}, Contents: &PageContents{MetaRobots: []string{"noindex"}}, Want: true},
+		{Name: "ok-meta-none", Header: http.Header{}, Contents: &PageContents{MetaRobots: []string{"none"}}, Want: true},
+		{Name: "ok-header-noindex", Header: http.Header{"X-Robots-Tag": {"noarchive, NoIndex"}}, Want: true},
+		{Name: "ok-header-googlebot", Header: http.Header{"X-Robots-Tag": {"googlebot: noindex"}}, Want: true},
+		{Name: "ok-header-other-agent", Header: http.Header{"X-Robots-Tag": {"otherbot: noindex"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := NoIndex(tt.Header, tt.Contents); have != tt.Want {
+				t.Errorf("NoIndex() = %v, want %v", have, tt.Want)
+			}
+		})
+	}
+}
+
+func TestSitemapExclusion(t *testing.T) {
+	tests := []struct {
+		Name string
+		Page *CrawledPage
+		Want string
+	}{
+		{
+			Name: "ok",
+			Page: &CrawledPage{URL: "https://www.example.com/", FinalURL: "https://www.example.com/", StatusCode: 200, Contents: &PageContents{Canonical: "/"}},
+		},
+		{
+			Name: "ok-error",
+			Page: &CrawledPage{URL: "https://www.example.com/", StatusCode: 404, Error: "endpoint responded with code: 404"},
+			Want: "endpoint responded with code: 404",
+		},
+		{
+			Name: "ok-status",
+			Page: &CrawledPage{URL: "https://www.example.com/", FinalURL: "https://www.example.com/", StatusCode: 203, Contents: &PageContents{}},
+			Want: "endpoint responded with code: 203",
+		},
+		{
+			Name: "ok-redirect",
+			Page: &CrawledPage{URL: "https://www.example.com/old", FinalURL: "https://www.example.com/new", StatusCode: 200, Contents: &PageContents{}},
+			Want: "redirects to https://www.example.com/new",
+		},
+		{
+			Name: "ok-noindex",
+			Page: &CrawledPage{URL: "https://www.example.com/", FinalURL: "https://www.example.com/", StatusCode: 200, Header: http.Header{"X-Robots-Tag": {"noindex"}}, Contents: &PageContents{}},
+			Want: "page is marked noindex",
+		},
+		{
+			Name: "ok-canonical",
+			Page: &CrawledPage{URL: "https://www.example.com/page?ref=1", FinalURL: "https://www.example.com/page?ref=1", StatusCode: 200, Contents: &PageContents{Canonical: "/page"}},
+			Want: "canonical URL is https://www.example.com/page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := tt.Page.SitemapExclusion(); have != tt.Want {
+				t.Errorf("SitemapExclusion() = %q, want %q", have, tt.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// XDefault is the hreflang value of the alternate used for unmatched languages.
+const XDefault = "x-default"
+
+// ISO 639-1 language codes and ISO 3166-1 alpha-2 region codes accepted in hreflang values.
+var (
+	languageCodes = codeSet(`aa ab ae af ak am an ar as av ay az ba be bg bh bi bm bn bo br bs ca ce ch co cr
+		cs cu cv cy da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht hu
+		hy hz ia id ie ig ii ik io is it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky la lb lg li ln lo
+		lt lu lv mg mh mi mk ml mn mr ms mt my na nb nd ne ng nl nn no nr nv ny oc oj om or os pa pi pl ps pt qu
+		rm rn ro ru rw sa sc sd se sg si sk sl sm sn so sq sr ss st su sv sw ta te tg th ti tk tl tn to tr ts tt
+		tw ty ug uk ur uz ve vi vo wa wo xh yi yo za zh zu`)
+
+	regionCodes = codeSet(`ad ae af ag ai al am ao aq ar as at au aw ax az ba bb bd be bf bg bh bi bj bl bm
+		bn bo bq br bs bt bv bw by bz ca cc cd cf cg ch ci ck cl cm cn co cr cu cv cw cx cy cz de dj dk dm do dz
+		ec ee eg eh er es et fi fj fk fm fo fr ga gb gd ge gf gg gh gi gl gm gn gp gq gr gs gt gu gw gy hk hm hn
+		hr ht hu id ie il im in io iq ir is it je jm jo jp ke kg kh ki km kn kp kr kw ky kz la lb lc li lk lr ls
+		lt lu lv ly ma mc md me mf mg mh mk ml mm mn mo mp mq mr ms mt mu mv mw mx my mz na nc ne nf ng ni nl no
+		np nr nu nz om pa pe pf pg ph pk pl pm pn pr ps pt pw py qa re ro rs ru rw sa sb sc sd se sg sh si sj sk
+		sl sm sn so sr ss st sv sx sy sz tc td tf tg th tj tk tl tm tn to tr tt tv tw tz ua ug um us uy uz va vc
+		ve vg vi vn vu wf ws ye yt za zm zw`)
+)
+
+// PageFindings are the findings of a crawled page.
+type PageFindings struct {
+	URL      string
+	Findings []Finding
+}
+
+// ValidHrefLang checks if the hreflang value is x-default or an ISO 639-1 language
+// code optionally followed by a script subtag and an ISO 3166-1 alpha-2 or UN M.49 region.
+func ValidHrefLang(code string) bool {
+	if strings.EqualFold(code, XDefault) {
+		return true
+	}
+
+	parts := strings.Split(strings.ToLower(code), "-")
+	if _, ok := languageCodes[parts[0]]; !ok {
+		return false
+	}
+
+	parts = parts[1:]
+
+	// script subtag, e.g. zh-Hant.
+	if len(parts) > 0 && len(parts[0]) == 4 && isLetters(parts[0]) {
+		parts = parts[1:]
+	}
+
+	if len(parts) == 0 {
+		return true
+	}
+
+	if len(parts) > 1 {
+		return false
+	}
+
+	if _, ok := regionCodes[parts[0]]; ok {
+		return true
+	}
+
+	// numeric regions, e.g. es-419.
+	return len(parts[0]) == 3 && strings.Trim(parts[0], "0123456789") == ""
+}
+
+// CheckLinkElements validates the canonical and hreflang <link> elements of the
+// page at base. The canonical target is not requested, see CheckCanonical.
+func (p *PageContents) CheckLinkElements(base url.URL) []Finding {
+	var out []Finding
+
+	if len(p.Canonicals) > 1 {
+		out = append(out, Finding{
+			Severity: SeverityMedium,
+			Check:    "canonical",
+			Message:  fmt.Sprintf("page declares %v canonical URLs, search engines may ignore all of them", len(p.Canonicals)),
+		})
+	}
+
+	if p.Canonical != "" {
+		if u, err := url.Parse(p.Canonical); err != nil {
+			out = append(out, Finding{
+				Severity: SeverityMedium,
+				Check:    "canonical",
+				Message:  fmt.Sprintf("canonical URL %q is invalid", p.Canonical),
+			})
+		} else if !u.IsAbs() {
+			out = append(out, Finding{
+				Severity: SeverityLow,
+				Check:    "canonical",
+				Message:  fmt.Sprintf("canonical URL %q is relative", p.Canonical),
+			})
+		}
+	}
+
+	if len(p.Alternates) == 0 {
+		return out
+	}
+
+	var (
+		self     = normalizeURL(base.String())
+		xDefault = false
+		selfRef  = false
+	)
+
+	for _, a := range p.Alternates {
+		if !ValidHrefLang(a.HrefLang) {
+			out = append(out, Finding{
+				Severity: SeverityMedium,
+				Check:    "hreflang",
+				Message:  fmt.Sprintf("invalid hreflang code %q for %v", a.HrefLang, a.Href),
+			})
+		}
+
+		if strings.EqualFold(a.HrefLang, XDefault) {
+			xDefault = true
+		}
+
+		if u, err := base.Parse(a.Href); err == nil && normalizeURL(u.String()) == self {
+			selfRef = true
+		}
+	}
+
+	if !xDefault {
+		out = append(out, Finding{
+			Severity: SeverityLow,
+			Check:    "hreflang",
+			Message:  "hreflang annotations don't declare an x-default alternate",
+		})
+	}
+
+	if !selfRef {
+		out = append(out, Finding{
+			Severity: SeverityMedium,
+			Check:    "hreflang",
+			Message:  "hreflang annotations don't reference the page itself",
+		})
+	}
+
+	return out
+}
+
+// CheckCanonical checks that the canonical URL of the page points to an indexable
+// page that responded with 200 without redirecting. Unless the page declares itself
+// canonical the target is requested through the fetcher.
+func (p *PageContents) CheckCanonical(ctx context.Context, f *Fetcher, resp *http.Response) []Finding {
+	base := *resp.Request.URL
+
+	canonical := p.CanonicalURL(base)
+	if canonical == "" {
+		return nil
+	}
+
+	target := &CrawledPage{
+		URL:        base.String(),
+		FinalURL:   base.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Contents:   p,
+	}
+
+	if normalizeURL(canonical) != normalizeURL(base.String()) {
+		target = crawlPage(ctx, canonical, 0, f, DefaultMaxPageSize)
+	}
+
+	return canonicalTarget(canonical, target)
+}
+
+// CheckLinkElements validates the link elements of the crawled HTML pages and checks
+// that the hreflang annotations between the crawled pages are reciprocal. Canonical
+// URLs that were not crawled are requested through the fetcher. Only the pages with
+// findings are returned, in crawl order.
+func (s *Site) CheckLinkElements(ctx context.Context, f *Fetcher) []PageFindings {
+	var (
+		out     []PageFindings
+		index   = make(map[string]*CrawledPage)
+		missing []string
+	)
+
+	for _, page := range s.Pages {
+		index[normalizeURL(page.URL)] = page
+	}
+
+	for _, page := range s.Pages {
+		base, err := url.Parse(page.FinalURL)
+		if err != nil || page.Contents == nil {
+			continue
+		}
+
+		if canonical := page.Contents.CanonicalURL(*base); canonical != "" {
+			if _, ok := index[normalizeURL(canonical)]; !ok {
+				index[normalizeURL(canonical)] = nil
+				missing = append(missing, canonical)
+			}
+		}
+	}
+
+	for _, target := range crawlLevel(ctx, missing, 0, f, CrawlOptions{MaxPageSize: DefaultMaxPageSize, Parallelism: DefaultParallelism}) {
+		index[normalizeURL(target.URL)] = target
+	}
+
+	for _, page := range s.Pages {
+		base, err := url.Parse(page.FinalURL)
+		if err != nil || page.Contents == nil {
+			continue
+		}
+
+		findings := page.Contents.CheckLinkElements(*base)
+
+		if canonical := page.Contents.CanonicalURL(*base); canonical != "" {
+			findings = append(findings, canonicalTarget(canonical, index[normalizeURL(canonical)])...)
+		}
+
+		findings = append(findings, page.reciprocalAlternates(*base, index)...)
+
+		if len(findings) > 0 {
+			out = append(out, PageFindings{URL: page.URL, Findings: findings})
+		}
+	}
+
+	return out
+}
+
+// canonicalTarget checks the page the canonical URL points to.
+func canonicalTarget(canonical string, target *CrawledPage) []Finding {
+	var f *Finding
+
+	switch {
+	case target.StatusCode == 0:
+		f = &Finding{Severity: SeverityMedium, Message: fmt.Sprintf("canonical URL %v is inaccessible: %v", canonical, target.Error)}
+	case target.StatusCode >= 400:
+		f = &Finding{Severity: SeverityHigh, Message: fmt.Sprintf("canonical URL %v responded with code: %v", canonical, target.StatusCode)}
+	case normalizeURL(target.FinalURL) != normalizeURL(target.URL):
+		f = &Finding{Severity: SeverityMedium, Message: fmt.Sprintf("canonical URL %v redirects to %v", canonical, target.FinalURL)}
+	case NoIndex(target.Header, target.Contents):
+		f = &Finding{Severity: SeverityHigh, Message: fmt.Sprintf("canonical URL %v is marked noindex", canonical)}
+	default:
+		return nil
+	}
+
+	f.Check = "canonical"
+
+	return []Finding{*f}
+}
+
+// reciprocalAlternates checks that the crawled hreflang alternates of the page link back to it.
+func (page *CrawledPage) reciprocalAlternates(base url.URL, index map[string]*CrawledPage) []Finding {
+	var (
+		out  []Finding
+		self = normalizeURL(page.URL)
+		seen = make(map[string]struct{})
+	)
+
+	for _, a := range page.Contents.Alternates {
+		u, err := base.Parse(a.Href)
+		if err != nil {
+			continue
+		}
+
+		key := normalizeURL(u.String())
+		if _, ok := seen[key]; ok || key == self {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		target := index[key]
+		if target == nil || target.Contents == nil {
+			continue
+		}
+
+		if !target.linksBack(self, normalizeURL(page.FinalURL)) {
+			out = append(out, Finding{
+				Severity: SeverityMedium,
+				Check:    "hreflang",
+				Message:  fmt.Sprintf("alternate %v (%v) doesn't link back to the page", u.String(), a.HrefLang),
+			})
+		}
+	}
+
+	return out
+}
+
+// linksBack checks if one of the hreflang alternates of the page points to any of the normalized URLs.
+func (page *CrawledPage) linksBack(urls ...string) bool {
+	base, err := url.Parse(page.FinalURL)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range page.Contents.Alternates {
+		u, err := base.Parse(a.Href)
+		if err != nil {
+			continue
+		}
+
+		for _, link := range urls {
+			if normalizeURL(u.String()) == link {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// codeSet splits the whitespace separated codes into a set.
+func codeSet(codes string) map[string]struct{} {
+	out := make(map[string]struct{})
+
+	for _, c := range strings.Fields(codes) {
+		out[c] = struct{}{}
+	}
+
+	return out
+}
+
+// isLetters checks if s consists of ASCII letters only.
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
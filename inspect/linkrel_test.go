@@ -0,0 +1,184 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidHrefLang(t *testing.T) {
+	tests := []struct {
+		Name string
+		Code string
+		Want bool
+	}{
+		{Name: "ok-language", Code: "de", Want: true},
+		{Name: "ok-region", Code: "en-GB", Want: true},
+		{Name: "ok-lowercase-region", Code: "en-us", Want: true},
+		{Name: "ok-script", Code: "zh-Hant-TW", Want: true},
+		{Name: "ok-numeric-region", Code: "es-419", Want: true},
+		{Name: "ok-x-default", Code: "X-Default", Want: true},
+		{Name: "fail-region-only", Code: "gb"},
+		{Name: "fail-invalid-region", Code: "en-UK"},
+		{Name: "fail-underscore", Code: "en_US"},
+		{Name: "fail-three-letter-language", Code: "eng"},
+		{Name: "fail-too-many-subtags", Code: "en-US-GB"},
+		{Name: "fail-empty", Code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := ValidHrefLang(tt.Code); have != tt.Want {
+				t.Errorf("ValidHrefLang(%q) = %v, want %v", tt.Code, have, tt.Want)
+			}
+		})
+	}
+}
+
+func TestCheckLinkElements(t *testing.T) {
+	base, err := url.Parse("https://www.example.com/en/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Contents *PageContents
+		Want     []Finding
+	}{
+		{
+			Name:     "ok-none",
+			Contents: &PageContents{},
+		},
+		{
+			Name: "ok",
+			Contents: &PageContents{
+				Canonical:  "https://www.example.com/en/",
+				Canonicals: []string{"https://www.example.com/en/"},
+				Alternates: []Alternate{
+					{HrefLang: "en", Href: "/en/"},
+					{HrefLang: "de-DE", Href: "https://www.example.com/de/"},
+					{HrefLang: "x-default", Href: "https://www.example.com/"},
+				},
+			},
+		},
+		{
+			Name: "fail-canonical",
+			Contents: &PageContents{
+				Canonical:  "/en/",
+				Canonicals: []string{"/en/", "https://www.example.com/"},
+			},
+			Want: []Finding{
+				{Severity: SeverityMedium, Check: "canonical", Message: "page declares 2 canonical URLs, search engines may ignore all of them"},
+				{Severity: SeverityLow, Check: "canonical", Message: `canonical URL "/en/" is relative`},
+			},
+		},
+		{
+			Name: "fail-hreflang",
+			Contents: &PageContents{
+				Alternates: []Alternate{
+					{HrefLang: "en_GB", Href: "/en-gb/"},
+					{HrefLang: "de", Href: "/de/"},
+				},
+			},
+			Want: []Finding{
+				{Severity: SeverityMedium, Check: "hreflang", Message: `invalid hreflang code "en_GB" for /en-gb/`},
+				{Severity: SeverityLow, Check: "hreflang", Message: "hreflang annotations don't declare an x-default alternate"},
+				{Severity: SeverityMedium, Check: "hreflang", Message: "hreflang annotations don't reference the page itself"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.Contents.CheckLinkElements(*base), tt.Want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSiteCheckLinkElements(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		host := "http://" + r.Host
+
+		switch r.URL.Path {
+		case "/":
+			rw.Write([]byte(`<html><head><link rel="canonical" href="/"></head><body>
+				<a href="/en">en</a><a href="/de">de</a><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>
+			</body></html>`))
+		case "/en":
+			rw.Write([]byte(`<html><head>
+				<link rel="alternate" hreflang="en" href="/en">
+				<link rel="alternate" hreflang="de" href="/de">
+				<link rel="alternate" hreflang="x-default" href="/en">
+			</head><body></body></html>`))
+		case "/de":
+			rw.Write([]byte(`<html><head>
+				<link rel="alternate" hreflang="de" href="/de">
+				<link rel="alternate" hreflang="en-UK" href="/fr">
+				<link rel="alternate" hreflang="x-default" href="/de">
+			</head><body></body></html>`))
+		case "/a":
+			rw.Write([]byte(`<html><head><link rel="canonical" href="` + host + `/noindex"></head><body></body></html>`))
+		case "/b":
+			rw.Write([]byte(`<html><head><link rel="canonical" href="` + host + `/old"><link rel="canonical" href="` + host + `/b"></head><body></body></html>`))
+		case "/c":
+			rw.Write([]byte(`<html><head><link rel="canonical" href="` + host + `/gone"></head><body></body></html>`))
+		case "/noindex":
+			rw.Write([]byte(`<html><head><meta name="robots" content="noindex"></head><body></body></html>`))
+		case "/new":
+			rw.Write([]byte(`<html><body></body></html>`))
+		case "/old":
+			http.Redirect(rw, r, "/new", http.StatusMovedPermanently)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	seed, err := url.Parse(mockServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFetcher(FetchOptions{})
+
+	site, err := Crawl(context.Background(), *seed, f, CrawlOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PageFindings{
+		{URL: mockServer.URL + "/", Findings: []Finding{
+			{Severity: SeverityLow, Check: "canonical", Message: `canonical URL "/" is relative`},
+		}},
+		{URL: mockServer.URL + "/a", Findings: []Finding{
+			{Severity: SeverityHigh, Check: "canonical", Message: "canonical URL " + mockServer.URL + "/noindex is marked noindex"},
+		}},
+		{URL: mockServer.URL + "/b", Findings: []Finding{
+			{Severity: SeverityMedium, Check: "canonical", Message: "page declares 2 canonical URLs, search engines may ignore all of them"},
+			{Severity: SeverityMedium, Check: "canonical", Message: "canonical URL " + mockServer.URL + "/old redirects to " + mockServer.URL + "/new"},
+		}},
+		{URL: mockServer.URL + "/c", Findings: []Finding{
+			{Severity: SeverityHigh, Check: "canonical", Message: "canonical URL " + mockServer.URL + "/gone responded with code: 404"},
+		}},
+		{URL: mockServer.URL + "/de", Findings: []Finding{
+			{Severity: SeverityMedium, Check: "hreflang", Message: `invalid hreflang code "en-UK" for /fr`},
+		}},
+		{URL: mockServer.URL + "/en", Findings: []Finding{
+			{Severity: SeverityMedium, Check: "hreflang", Message: "alternate " + mockServer.URL + "/de (de) doesn't link back to the page"},
+		}},
+	}
+
+	if diff := cmp.Diff(site.CheckLinkElements(context.Background(), f), want); diff != "" {
+		t.Error(diff)
+	}
+}
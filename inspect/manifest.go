@@ -0,0 +1,584 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the GIF decoder for the icon checks.
+	_ "image/jpeg" // register the JPEG decoder for the icon checks.
+	_ "image/png"  // register the PNG decoder for the icon checks.
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Manifest and icon limits
+const (
+	// MaxManifestSize is the maximum size of a web app manifest in bytes.
+	MaxManifestSize = 1 << 20
+
+	// MaxIconSize is the maximum size of an icon in bytes.
+	MaxIconSize = 5 << 20
+
+	// MaxShortNameLength is the length of the short_name above which
+	// the name may be truncated on the home screen.
+	MaxShortNameLength = 12
+)
+
+// Icon sources that are not the rel value of a <link> element.
+const (
+	IconSourceManifest = "manifest"
+	IconSourceFavicon  = "favicon.ico"
+)
+
+// Display modes of a web app manifest
+var displayModes = map[string]bool{
+	"fullscreen": true,
+	"standalone": true,
+	"minimal-ui": true,
+	"browser":    false, // valid but not installable.
+}
+
+// Purposes of a web app manifest icon
+var iconPurposes = map[string]struct{}{
+	"any":        {},
+	"maskable":   {},
+	"monochrome": {},
+}
+
+var reIconSize = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`)
+
+// Icon is an icon declared by a <link> element of the HTML page.
+type Icon struct {
+	Href string
+
+	// Lower-cased rel value, e.g. icon, apple-touch-icon or mask-icon.
+	Rel string
+
+	// Declared media type and sizes.
+	Type  string
+	Sizes string
+}
+
+// Manifest is a parsed web app manifest.
+type Manifest struct {
+	Name      string
+	ShortName string
+	StartURL  string
+	Display   string
+	Icons     []ManifestIcon
+}
+
+// ManifestIcon is an icon of a web app manifest.
+type ManifestIcon struct {
+	Src     string
+	Sizes   string
+	Type    string
+	Purpose string
+}
+
+// ManifestReport is the result of fetching and validating a web app manifest.
+type ManifestReport struct {
+	URL string
+
+	// Parsed manifest, nil if it could not be fetched or parsed.
+	Manifest *Manifest
+
+	Findings []Finding
+
+	// Reason the manifest could not be fetched or parsed.
+	Error string
+}
+
+// IconReport is the result of fetching a declared icon.
+type IconReport struct {
+	URL string
+
+	// Where the icon is declared, the rel value of the <link>
+	// element, IconSourceManifest or IconSourceFavicon.
+	Source string
+
+	// Declared media type and sizes.
+	DeclaredType  string
+	DeclaredSizes string
+
+	StatusCode int
+
+	// Detected media type and dimensions of the image, the sizes
+	// are empty if they could not be detected (e.g. SVG images).
+	Type  string
+	Sizes []string
+
+	Findings []Finding
+}
+
+// AppReport is the result of the web app manifest and icon audit of a page.
+type AppReport struct {
+	// Manifest linked by the page, nil if there is none.
+	Manifest *ManifestReport
+
+	// Icons declared by the page and the manifest followed by the legacy /favicon.ico.
+	Icons []IconReport
+
+	Findings []Finding
+}
+
+type jsonManifest struct {
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	StartURL  string `json:"start_url"`
+	Display   string `json:"display"`
+	Icons     []struct {
+		Src     string `json:"src"`
+		Sizes   string `json:"sizes"`
+		Type    string `json:"type"`
+		Purpose string `json:"purpose"`
+	} `json:"icons"`
+}
+
+// extractAppLinks extracts the <link rel="manifest"> URL and the icon declarations.
+func (p *PageContents) extractAppLinks(node *html.Node) {
+	if strings.ToLower(node.Data) != "link" {
+		return
+	}
+
+	rel, _ := getAttribute(node, "rel")
+	href, ok := getAttribute(node, "href")
+
+	if !ok {
+		return
+	}
+
+	rel = strings.Join(strings.Fields(strings.ToLower(rel)), " ")
+
+	switch {
+	case hasRel(rel, "manifest"):
+		if p.Manifest == "" {
+			p.Manifest = strings.TrimSpace(href)
+		}
+	case hasRel(rel, "icon"), hasRel(rel, "apple-touch-icon"), hasRel(rel, "apple-touch-icon-precomposed"), hasRel(rel, "mask-icon"):
+		typ, _ := getAttribute(node, "type")
+		sizes, _ := getAttribute(node, "sizes")
+
+		p.Icons = append(p.Icons, Icon{
+			Href:  strings.TrimSpace(href),
+			Rel:   rel,
+			Type:  strings.ToLower(strings.TrimSpace(typ)),
+			Sizes: strings.ToLower(strings.TrimSpace(sizes)),
+		})
+	}
+}
+
+// ParseManifest parses a web app manifest.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	var doc jsonManifest
+	if err := json.NewDecoder(io.LimitReader(r, MaxManifestSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("inspect.ParseManifest: failed to parse manifest: %w", err)
+	}
+
+	out := &Manifest{
+		Name:      strings.TrimSpace(doc.Name),
+		ShortName: strings.TrimSpace(doc.ShortName),
+		StartURL:  strings.TrimSpace(doc.StartURL),
+		Display:   strings.TrimSpace(doc.Display),
+	}
+
+	for _, icon := range doc.Icons {
+		out.Icons = append(out.Icons, ManifestIcon{
+			Src:     strings.TrimSpace(icon.Src),
+			Sizes:   strings.ToLower(strings.TrimSpace(icon.Sizes)),
+			Type:    strings.ToLower(strings.TrimSpace(icon.Type)),
+			Purpose: strings.ToLower(strings.TrimSpace(icon.Purpose)),
+		})
+	}
+
+	return out, nil
+}
+
+// Check validates the name, short_name, start_url, display and icons of the
+// manifest. Relative URLs are resolved against base, the URL of the manifest.
+func (m *Manifest) Check(base url.URL) []Finding {
+	var out []Finding
+
+	finding := func(severity Severity, format string, args ...interface{}) {
+		out = append(out, Finding{Severity: severity, Check: "manifest", Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case m.Name == "" && m.ShortName == "":
+		finding(SeverityHigh, "manifest has neither a name nor a short_name")
+	case m.Name == "":
+		finding(SeverityMedium, "manifest has no name")
+	case m.ShortName == "":
+		finding(SeverityLow, "manifest has no short_name")
+	}
+
+	if utf8.RuneCountInString(m.ShortName) > MaxShortNameLength {
+		finding(SeverityLow, "short_name %q is longer than %v characters and may be truncated", m.ShortName, MaxShortNameLength)
+	}
+
+	if m.StartURL == "" {
+		finding(SeverityMedium, "manifest has no start_url")
+	} else if u, err := base.Parse(m.StartURL); err != nil || u.Scheme != base.Scheme || !strings.EqualFold(u.Host, base.Host) {
+		finding(SeverityMedium, "start_url %q is not on the origin of the manifest and is ignored", m.StartURL)
+	}
+
+	if installable, ok := displayModes[m.Display]; m.Display == "" {
+		finding(SeverityLow, "manifest has no display mode, the app opens in a browser tab")
+	} else if !ok {
+		finding(SeverityMedium, "invalid display mode %q", m.Display)
+	} else if !installable {
+		finding(SeverityMedium, "display mode %q prevents installing the app", m.Display)
+	}
+
+	if len(m.Icons) == 0 {
+		finding(SeverityHigh, "manifest declares no icons")
+		return out
+	}
+
+	var (
+		largest  int
+		maskable bool
+	)
+
+	for _, icon := range m.Icons {
+		if icon.Src == "" {
+			finding(SeverityMedium, "icon without src")
+			continue
+		}
+
+		sizes, ok := parseIconSizes(icon.Sizes)
+		if !ok {
+			finding(SeverityMedium, "invalid sizes %q of icon %v", icon.Sizes, icon.Src)
+		}
+
+		purposes := strings.Fields(icon.Purpose)
+		if len(purposes) == 0 {
+			purposes = []string{"any"}
+		}
+
+		for _, purpose := range purposes {
+			if _, ok := iconPurposes[purpose]; !ok {
+				finding(SeverityMedium, "invalid purpose %q of icon %v", purpose, icon.Src)
+				continue
+			}
+
+			if purpose == "maskable" {
+				maskable = true
+			}
+
+			if purpose != "any" {
+				continue
+			}
+
+			for _, size := range sizes {
+				var w, h int
+				if _, err := fmt.Sscanf(size, "%dx%d", &w, &h); err == nil && w == h && w > largest {
+					largest = w
+				}
+			}
+		}
+	}
+
+	if largest < 192 {
+		finding(SeverityMedium, "manifest has no square icon of at least 192x192")
+	}
+
+	if largest < 512 {
+		finding(SeverityMedium, "manifest has no square icon of at least 512x512, no splash screen can be generated")
+	}
+
+	if !maskable {
+		finding(SeverityLow, "manifest has no maskable icon")
+	}
+
+	return out
+}
+
+// AuditApp fetches the web app manifest linked by the page at base and every icon
+// declared by the page or the manifest, including the legacy /favicon.ico, and
+// checks that the icons exist and match their declared type and sizes.
+func (p *PageContents) AuditApp(ctx context.Context, f *Fetcher, base url.URL) *AppReport {
+	type declaration struct {
+		URL, Source, Type, Sizes string
+	}
+
+	var (
+		out      = new(AppReport)
+		icons    []declaration
+		seen     = make(map[declaration]struct{})
+		favicon  = false
+		apple    = false
+		manifest *Manifest
+	)
+
+	add := func(base url.URL, href, source, typ, sizes string) {
+		u, err := base.Parse(href)
+		if err != nil {
+			return
+		}
+
+		u.Fragment, u.RawFragment = "", ""
+
+		d := declaration{URL: u.String(), Source: source, Type: typ, Sizes: sizes}
+		if _, ok := seen[d]; !ok {
+			seen[d] = struct{}{}
+			icons = append(icons, d)
+		}
+	}
+
+	for _, icon := range p.Icons {
+		add(base, icon.Href, icon.Rel, icon.Type, icon.Sizes)
+
+		favicon = favicon || hasRel(icon.Rel, "icon")
+		apple = apple || strings.HasPrefix(icon.Rel, "apple-touch-icon")
+	}
+
+	if p.Manifest == "" {
+		out.Findings = append(out.Findings, Finding{Severity: SeverityMedium, Check: "manifest", Message: "page doesn't link a web app manifest"})
+	} else if u, err := base.Parse(p.Manifest); err != nil {
+		out.Manifest = &ManifestReport{URL: p.Manifest, Error: err.Error()}
+	} else {
+		out.Manifest = fetchManifest(ctx, f, u.String())
+		manifest = out.Manifest.Manifest
+
+		if manifest != nil {
+			for _, icon := range manifest.Icons {
+				if icon.Src != "" {
+					add(*u, icon.Src, IconSourceManifest, icon.Type, icon.Sizes)
+				}
+			}
+		}
+	}
+
+	add(base, "/favicon.ico", IconSourceFavicon, "", "")
+
+	if !apple {
+		out.Findings = append(out.Findings, Finding{Severity: SeverityLow, Check: "icons", Message: "page declares no apple-touch-icon"})
+	}
+
+	out.Icons = make([]IconReport, len(icons))
+	wg := new(sync.WaitGroup)
+
+	for i, d := range icons {
+		wg.Add(1)
+
+		go func(i int, d declaration) {
+			defer wg.Done()
+			out.Icons[i] = checkIcon(ctx, f, d.URL, d.Source, d.Type, d.Sizes)
+		}(i, d)
+	}
+
+	wg.Wait()
+
+	if last := out.Icons[len(out.Icons)-1]; !favicon && last.Type == "" {
+		out.Findings = append(out.Findings, Finding{Severity: SeverityMedium, Check: "icons", Message: "page has no favicon, neither declared nor at /favicon.ico"})
+	}
+
+	return out
+}
+
+// fetchManifest fetches, parses and validates a web app manifest.
+func fetchManifest(ctx context.Context, f *Fetcher, link string) *ManifestReport {
+	out := &ManifestReport{URL: link}
+
+	resp, err := f.Get(ctx, link)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		out.Error = fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode)
+		return out
+	}
+
+	if out.Manifest, err = ParseManifest(resp.Body); err != nil {
+		out.Error = err.Error()
+		return out
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "application/manifest+json" && mediaType != "application/json" {
+			out.Findings = append(out.Findings, Finding{
+				Severity: SeverityLow,
+				Check:    "manifest",
+				Message:  fmt.Sprintf("manifest is served as %v instead of application/manifest+json", contentType),
+			})
+		}
+	}
+
+	out.Findings = append(out.Findings, out.Manifest.Check(*resp.Request.URL)...)
+
+	return out
+}
+
+// checkIcon fetches the icon and checks it is an image matching the declared type and sizes.
+func checkIcon(ctx context.Context, f *Fetcher, link, source, declaredType, declaredSizes string) IconReport {
+	out := IconReport{URL: link, Source: source, DeclaredType: declaredType, DeclaredSizes: declaredSizes}
+
+	finding := func(severity Severity, format string, args ...interface{}) {
+		out.Findings = append(out.Findings, Finding{Severity: severity, Check: "icons", Message: fmt.Sprintf(format, args...)})
+	}
+
+	missing := SeverityHigh
+	if source == IconSourceFavicon {
+		missing = SeverityLow // only requested by legacy clients.
+	}
+
+	resp, err := f.Get(ctx, link)
+	if err != nil {
+		finding(missing, "icon is inaccessible: %v", err)
+		return out
+	}
+
+	defer resp.Body.Close()
+
+	if out.StatusCode = resp.StatusCode; resp.StatusCode != 200 {
+		finding(missing, "icon responded with code: %v", resp.StatusCode)
+		return out
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxIconSize))
+	if err != nil {
+		finding(SeverityMedium, "failed to read icon: %v", err)
+		return out
+	}
+
+	if out.Type, out.Sizes = detectImage(body); out.Type == "" {
+		finding(SeverityHigh, "icon is not an image (content-type: %v)", resp.Header.Get("Content-Type"))
+		return out
+	}
+
+	if declaredType != "" && imageType(declaredType) != out.Type {
+		finding(SeverityMedium, "declared type %v doesn't match the image type %v", declaredType, out.Type)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); !strings.HasPrefix(mediaType, "image/") {
+		finding(SeverityLow, "icon is served as %v", resp.Header.Get("Content-Type"))
+	}
+
+	for _, size := range strings.Fields(declaredSizes) {
+		if size == "any" {
+			if out.Type != "image/svg+xml" {
+				finding(SeverityLow, "sizes any is only valid for scalable icons")
+			}
+
+			continue
+		}
+
+		if len(out.Sizes) > 0 && !containsString(out.Sizes, size) {
+			finding(SeverityMedium, "declared size %v doesn't match the image size %v", size, strings.Join(out.Sizes, " "))
+		}
+	}
+
+	switch {
+	case hasRel(source, "mask-icon") && out.Type != "image/svg+xml":
+		finding(SeverityMedium, "mask-icon must be a SVG image")
+	case strings.HasPrefix(source, "apple-touch-icon") && out.Type != "image/png":
+		finding(SeverityMedium, "apple-touch-icon should be a PNG image")
+	}
+
+	return out
+}
+
+// detectImage detects the media type and the dimensions of the image. The
+// dimensions of every image of an ICO file are returned. An empty type is
+// returned if the data is not a supported image.
+func detectImage(data []byte) (string, []string) {
+	switch {
+	case len(data) >= 6 && bytes.Equal(data[:4], []byte{0, 0, 1, 0}):
+		var sizes []string
+
+		count := int(binary.LittleEndian.Uint16(data[4:6]))
+		for i := 0; i < count && 6+16*(i+1) <= len(data); i++ {
+			w, h := int(data[6+16*i]), int(data[6+16*i+1])
+			if w == 0 {
+				w = 256
+			}
+
+			if h == 0 {
+				h = 256
+			}
+
+			sizes = append(sizes, fmt.Sprintf("%vx%v", w, h))
+		}
+
+		return "image/x-icon", sizes
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "image/webp", nil
+	}
+
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return "image/" + format, []string{fmt.Sprintf("%vx%v", config.Width, config.Height)}
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+
+	if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return "image/svg+xml", nil
+	}
+
+	return "", nil
+}
+
+// imageType normalizes the aliases of the image media types.
+func imageType(mediaType string) string {
+	switch mediaType = strings.ToLower(mediaType); mediaType {
+	case "image/vnd.microsoft.icon", "image/ico", "image/icon":
+		return "image/x-icon"
+	case "image/jpg":
+		return "image/jpeg"
+	}
+
+	return mediaType
+}
+
+// parseIconSizes parses the space separated sizes of an icon. It reports
+// whether every size is either "any" or WIDTHxHEIGHT.
+func parseIconSizes(sizes string) ([]string, bool) {
+	var (
+		out []string
+		ok  = true
+	)
+
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		switch {
+		case size == "any":
+		case reIconSize.MatchString(size):
+			out = append(out, size)
+		default:
+			ok = false
+		}
+	}
+
+	return out, ok
+}
+
+// containsString checks if the slice contains s.
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// mockPNG encodes an empty PNG image of the given size.
+func mockPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	if err := png.Encode(b, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+// mockICO is the header of an ICO file with a 16x16 and a 256x256 image.
+var mockICO = []byte{0, 0, 1, 0, 2, 0, 16, 16, 0, 0, 1, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 32, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+func TestExtractAppLinks(t *testing.T) {
+	contents, err := Page(strings.NewReader(`<html><head>
+		<link rel="manifest" href=" /app.webmanifest ">
+		<link rel="manifest" href="/other.webmanifest">
+		<link rel="Shortcut Icon" href="/favicon.png" type="image/PNG" sizes="32X32">
+		<link rel="apple-touch-icon" href="/apple.png">
+		<link rel="mask-icon" href="/mask.svg" color="#000">
+		<link rel="stylesheet" href="/style.css">
+	</head><body></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contents.Manifest != "/app.webmanifest" {
+		t.Errorf("Manifest = %q, want %q", contents.Manifest, "/app.webmanifest")
+	}
+
+	want := []Icon{
+		{Href: "/favicon.png", Rel: "shortcut icon", Type: "image/png", Sizes: "32x32"},
+		{Href: "/apple.png", Rel: "apple-touch-icon"},
+		{Href: "/mask.svg", Rel: "mask-icon"},
+	}
+
+	if diff := cmp.Diff(contents.Icons, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestManifestCheck(t *testing.T) {
+	base, err := url.Parse("https://www.example.com/app.webmanifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Manifest string
+		Want     []Finding
+	}{
+		{
+			Name: "ok",
+			Manifest: `{"name": "Example App", "short_name": "Example", "start_url": "/?source=pwa", "display": "standalone", "icons": [
+				{"src": "/icon-192.png", "sizes": "192x192", "type": "image/png"},
+				{"src": "/icon-512.png", "sizes": "512x512", "type": "image/png", "purpose": "any maskable"}
+			]}`,
+		},
+		{
+			Name:     "fail-empty",
+			Manifest: `{}`,
+			Want: []Finding{
+				{Severity: SeverityHigh, Check: "manifest", Message: "manifest has neither a name nor a short_name"},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no start_url"},
+				{Severity: SeverityLow, Check: "manifest", Message: "manifest has no display mode, the app opens in a browser tab"},
+				{Severity: SeverityHigh, Check: "manifest", Message: "manifest declares no icons"},
+			},
+		},
+		{
+			Name: "fail-invalid",
+			Manifest: `{"name": "Example App", "short_name": "Example Application", "start_url": "https://www.example.org/", "display": "browser", "icons": [
+				{"src": "/icon.png", "sizes": "192"},
+				{"sizes": "512x512"},
+				{"src": "/icon-512.png", "sizes": "512x512", "purpose": "maskable badge"}
+			]}`,
+			Want: []Finding{
+				{Severity: SeverityLow, Check: "manifest", Message: `short_name "Example Application" is longer than 12 characters and may be truncated`},
+				{Severity: SeverityMedium, Check: "manifest", Message: `start_url "https://www.example.org/" is not on the origin of the manifest and is ignored`},
+				{Severity: SeverityMedium, Check: "manifest", Message: `display mode "browser" prevents installing the app`},
+				{Severity: SeverityMedium, Check: "manifest", Message: `invalid sizes "192" of icon /icon.png`},
+				{Severity: SeverityMedium, Check: "manifest", Message: "icon without src"},
+				{Severity: SeverityMedium, Check: "manifest", Message: `invalid purpose "badge" of icon /icon-512.png`},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no square icon of at least 192x192"},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no square icon of at least 512x512, no splash screen can be generated"},
+			},
+		},
+		{
+			Name:     "fail-display",
+			Manifest: `{"name": "Example", "start_url": ".", "display": "window", "icons": [{"src": "/icon.svg", "sizes": "any"}]}`,
+			Want: []Finding{
+				{Severity: SeverityLow, Check: "manifest", Message: "manifest has no short_name"},
+				{Severity: SeverityMedium, Check: "manifest", Message: `invalid display mode "window"`},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no square icon of at least 192x192"},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no square icon of at least 512x512, no splash screen can be generated"},
+				{Severity: SeverityLow, Check: "manifest", Message: "manifest has no maskable icon"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			manifest, err := ParseManifest(strings.NewReader(tt.Manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(manifest.Check(*base), tt.Want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDetectImage(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Data      []byte
+		WantType  string
+		WantSizes []string
+	}{
+		{Name: "ok-png", Data: mockPNG(t, 32, 16), WantType: "image/png", WantSizes: []string{"32x16"}},
+		{Name: "ok-ico", Data: mockICO, WantType: "image/x-icon", WantSizes: []string{"16x16", "256x256"}},
+		{Name: "ok-svg", Data: []byte(`<?xml version="1.0"?><SVG xmlns="http://www.w3.org/2000/svg"></SVG>`), WantType: "image/svg+xml"},
+		{Name: "ok-webp", Data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), WantType: "image/webp"},
+		{Name: "fail-html", Data: []byte(`<html></html>`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			typ, sizes := detectImage(tt.Data)
+			if typ != tt.WantType {
+				t.Errorf("type = %q, want %q", typ, tt.WantType)
+			}
+
+			if diff := cmp.Diff(sizes, tt.WantSizes); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAuditApp(t *testing.T) {
+	icon192, icon512 := mockPNG(t, 192, 192), mockPNG(t, 512, 512)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/manifest.json":
+			rw.Header().Set("Content-Type", "text/plain")
+			rw.Write([]byte(`{"name": "App", "short_name": "App", "start_url": "/", "display": "standalone", "icons": [
+				{"src": "icon-192.png", "sizes": "192x192", "type": "image/png"},
+				{"src": "icon-512.png", "sizes": "192x192", "type": "image/png", "purpose": "maskable"}
+			]}`))
+		case "/app/icon-192.png":
+			rw.Write(icon192)
+		case "/app/icon-512.png":
+			rw.Write(icon512)
+		case "/favicon.ico":
+			rw.Header().Set("Content-Type", "image/x-icon")
+			rw.Write(mockICO)
+		case "/mask.svg":
+			rw.Header().Set("Content-Type", "text/html")
+			rw.Write([]byte(`<html></html>`))
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	contents := &PageContents{
+		Manifest: "/app/manifest.json",
+		Icons: []Icon{
+			{Href: "/favicon.ico", Rel: "icon", Type: "image/vnd.microsoft.icon", Sizes: "16x16 32x32"},
+			{Href: "/app/icon-192.png", Rel: "icon", Type: "image/svg+xml", Sizes: "any"},
+			{Href: "/mask.svg", Rel: "mask-icon"},
+		},
+	}
+
+	base, err := url.Parse(mockServer.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have := contents.AuditApp(context.Background(), NewFetcher(FetchOptions{}), *base)
+
+	want := &AppReport{
+		Manifest: &ManifestReport{
+			URL:      mockServer.URL + "/app/manifest.json",
+			Manifest: have.Manifest.Manifest,
+			Findings: []Finding{
+				{Severity: SeverityLow, Check: "manifest", Message: "manifest is served as text/plain instead of application/manifest+json"},
+				{Severity: SeverityMedium, Check: "manifest", Message: "manifest has no square icon of at least 512x512, no splash screen can be generated"},
+			},
+		},
+		Icons: []IconReport{
+			{
+				URL: mockServer.URL + "/favicon.ico", Source: "icon", DeclaredType: "image/vnd.microsoft.icon", DeclaredSizes: "16x16 32x32",
+				StatusCode: 200, Type: "image/x-icon", Sizes: []string{"16x16", "256x256"},
+				Findings: []Finding{{Severity: SeverityMedium, Check: "icons", Message: "declared size 32x32 doesn't match the image size 16x16 256x256"}},
+			},
+			{
+				URL: mockServer.URL + "/app/icon-192.png", Source: "icon", DeclaredType: "image/svg+xml", DeclaredSizes: "any",
+				StatusCode: 200, Type: "image/png", Sizes: []string{"192x192"},
+				Findings: []Finding{
+					{Severity: SeverityMedium, Check: "icons", Message: "declared type image/svg+xml doesn't match the image type image/png"},
+					{Severity: SeverityLow, Check: "icons", Message: "sizes any is only valid for scalable icons"},
+				},
+			},
+			{
+				URL: mockServer.URL + "/mask.svg", Source: "mask-icon", StatusCode: 200,
+				Findings: []Finding{{Severity: SeverityHigh, Check: "icons", Message: "icon is not an image (content-type: text/html)"}},
+			},
+			{
+				URL: mockServer.URL + "/app/icon-192.png", Source: IconSourceManifest, DeclaredType: "image/png", DeclaredSizes: "192x192",
+				StatusCode: 200, Type: "image/png", Sizes: []string{"192x192"},
+			},
+			{
+				URL: mockServer.URL + "/app/icon-512.png", Source: IconSourceManifest, DeclaredType: "image/png", DeclaredSizes: "192x192",
+				StatusCode: 200, Type: "image/png", Sizes: []string{"512x512"},
+				Findings: []Finding{{Severity: SeverityMedium, Check: "icons", Message: "declared size 192x192 doesn't match the image size 512x512"}},
+			},
+			{
+				URL: mockServer.URL + "/favicon.ico", Source: IconSourceFavicon,
+				StatusCode: 200, Type: "image/x-icon", Sizes: []string{"16x16", "256x256"},
+			},
+		},
+		Findings: []Finding{
+			{Severity: SeverityLow, Check: "icons", Message: "page declares no apple-touch-icon"},
+		},
+	}
+
+	if diff := cmp.Diff(have, want); diff != "" {
+		t.Error(diff)
+	}
+
+	none := (&PageContents{}).AuditApp(context.Background(), NewFetcher(FetchOptions{}), *base)
+
+	wantNone := []Finding{
+		{Severity: SeverityMedium, Check: "manifest", Message: "page doesn't link a web app manifest"},
+		{Severity: SeverityLow, Check: "icons", Message: "page declares no apple-touch-icon"},
+	}
+
+	if diff := cmp.Diff(none.Findings, wantNone); diff != "" {
+		t.Error(diff)
+	}
+}
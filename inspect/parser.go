@@ -4,9 +4,9 @@
 package inspect
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -39,6 +39,24 @@ var (
 // from the parsed HTML page.
 type InvalidLink struct{ URL, Reason string }
 
+// Anchor is an <a href> element of the HTML page.
+type Anchor struct {
+	Href string
+
+	// Text content of the element with collapsed whitespace,
+	// the alt text of the contained images if there is none.
+	Text string
+
+	// Lower-cased link types of the rel attribute.
+	Rel string
+}
+
+// Alternate is a <link rel="alternate" hreflang> element of the HTML page.
+type Alternate struct {
+	HrefLang string
+	Href     string
+}
+
 // PageContents contains the basic information
 // extracted from a HTML page.
 type PageContents struct {
@@ -55,25 +73,88 @@ type PageContents struct {
 	// Relative URL will be stored under the empty domain "".
 	Links map[string]map[string]struct{}
 
+	// Anchors of the page in document order.
+	Anchors []Anchor
+
 	// If the page contains a login form.
 	LoginForm bool
+
+	// Content-Security-Policy values declared by <meta http-equiv> elements.
+	MetaCSP []string
+
+	// Inline scripts, styles, event handlers and javascript: URLs.
+	Inline []InlineContent
+
+	// External resources loaded by the page.
+	Subresources []Subresource
+
+	// Directives of the <meta name="robots"> elements.
+	MetaRobots []string
+
+	// Value of the first <link rel="canonical"> element.
+	Canonical string
+
+	// Values of all <link rel="canonical"> elements.
+	Canonicals []string
+
+	// Language alternates declared by <link rel="alternate" hreflang> elements.
+	Alternates []Alternate
+
+	// Values of the first <link rel="next">, <link rel="prev"> and <link rel="amphtml"> elements.
+	Next    string
+	Prev    string
+	AMPHTML string
+
+	// Feeds referenced by <link rel="alternate"> elements.
+	Feeds []FeedLink
+
+	// Value of the first <link rel="manifest"> element.
+	Manifest string
+
+	// Icons declared by <link rel="icon">, apple-touch-icon and mask-icon elements.
+	Icons []Icon
+
+	// Number of bytes of the page read by Page or PageStream.
+	Size int64
+
+	// If the page exceeded the maximum size of PageStream
+	// and only its beginning was inspected.
+	Truncated bool
+
+	// Results of the extractors by their name, nil if no extractor was used.
+	Sections map[string]interface{}
 }
 
 // Page extracts general contents from a HTML page.
-func Page(page io.Reader) (*PageContents, error) {
-	root, err := html.Parse(page)
+// The results of the extractors are stored in the Sections.
+func Page(page io.Reader, extractors ...Extractor) (*PageContents, error) {
+	var size byteCounter
+
+	root, err := html.Parse(io.TeeReader(page, &size))
 	if err != nil {
 		return nil, fmt.Errorf("inspect.Page: unexpected parse error: %w", err)
 	}
 
 	pc := newPageContents()
-	if err := pc.traversePage(root); err != nil {
+	pc.Size = int64(size)
+
+	if err := pc.traversePage(root, extractors...); err != nil {
 		return nil, fmt.Errorf("inspect.Page: unexpected error: %w", err)
 	}
 
+	pc.addSections(extractors)
+
 	return pc, nil
 }
 
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	*c += byteCounter(len(b))
+	return len(b), nil
+}
+
 // newPageContents creates a default initialized *PageContents.
 func newPageContents() *PageContents {
 	return &PageContents{
@@ -86,7 +167,23 @@ func newPageContents() *PageContents {
 }
 
 // traversePage traverses the HTML document from the given node.
-func (p *PageContents) traversePage(node *html.Node) error {
+func (p *PageContents) traversePage(node *html.Node, extractors ...Extractor) error {
+	if err := p.visit(node, extractors); err != nil {
+		return err
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if err := p.traversePage(c, extractors...); err != nil {
+			return fmt.Errorf("inspect.traversePage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// visit extracts the contents of a single node of the HTML document
+// and passes the node to the extractors.
+func (p *PageContents) visit(node *html.Node, extractors []Extractor) error {
 	switch node.Type {
 	case html.DoctypeNode:
 		p.extractVersion(node)
@@ -120,10 +217,22 @@ func (p *PageContents) traversePage(node *html.Node) error {
 
 					// relative URLS will have an empty hostname
 					p.Links[u.Hostname()][node.Attr[i].Val] = struct{}{}
+
+					rel, _ := getAttribute(node, "rel")
+					p.Anchors = append(p.Anchors, Anchor{
+						Href: node.Attr[i].Val,
+						Text: anchorText(node),
+						Rel:  strings.Join(strings.Fields(strings.ToLower(rel)), " "),
+					})
 				}
 			}
 		}
 
+		p.extractCSPContent(node)
+		p.extractIndexing(node)
+		p.extractFeedLink(node)
+		p.extractAppLinks(node)
+
 		// we can check for a login form with an <input type="password">
 		if strings.ToLower(node.Data) == "input" {
 			for i := 0; i < len(node.Attr); i++ {
@@ -148,13 +257,7 @@ func (p *PageContents) traversePage(node *html.Node) error {
 		}
 	}
 
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if err := p.traversePage(c); err != nil {
-			return fmt.Errorf("inspect.traversePage: %w", err)
-		}
-	}
-
-	return nil
+	return visitExtractors(node, extractors)
 }
 
 // extractVersion extract the HTML version from a DocType HTML Node.
@@ -202,20 +305,48 @@ func (p *PageContents) extractVersion(node *html.Node) {
 
 // InvalidLinks checks every link extracted from the HTML page if it is
 // accessible. For relative links the baseURL parameter will be use to
-// create a full URL.
+// create a full URL. Links disallowed by the robots.txt of their host
+// are reported as inaccessible.
 func (p *PageContents) InvalidLinks(baseURL url.URL) map[string][]InvalidLink {
-	type data struct {
-		Domain string
-		Link   InvalidLink
-	}
+	return p.CheckLinks(context.Background(), baseURL, NewFetcher(FetchOptions{RespectRobots: true}))
+}
+
+// LinkResult is the result of checking a single link.
+type LinkResult struct {
+	Domain string
+	URL    string
+
+	// Reason the link is inaccessible, empty if the link is accessible.
+	Reason string
+}
+
+// LinkProgress is called after every link checked by CheckLinksProgress with the
+// number of checked links, the total number of links of the page and the result.
+type LinkProgress func(checked, total int, result LinkResult)
 
+// CheckLinks is like InvalidLinks but issues the requests through the fetcher.
+// With a fetcher respecting robots.txt the disallowed links are reported with
+// an ErrDisallowed reason instead of being requested.
+func (p *PageContents) CheckLinks(ctx context.Context, baseURL url.URL, f *Fetcher) map[string][]InvalidLink {
+	return p.CheckLinksProgress(ctx, baseURL, f, nil)
+}
+
+// CheckLinksProgress is like CheckLinks but reports the progress of the check.
+// The progress function is never called concurrently.
+func (p *PageContents) CheckLinksProgress(ctx context.Context, baseURL url.URL, f *Fetcher, progress LinkProgress) map[string][]InvalidLink {
 	var (
 		out = make(map[string][]InvalidLink)
-		ch  = make(chan data)
+		ch  = make(chan LinkResult)
 
 		wg = new(sync.WaitGroup)
+
+		total, checked int
 	)
 
+	for _, links := range p.Links {
+		total += len(links)
+	}
+
 	for domain, links := range p.Links {
 		wg.Add(1)
 
@@ -233,30 +364,26 @@ func (p *PageContents) InvalidLinks(baseURL url.URL) map[string][]InvalidLink {
 					link = Combine(baseURL.String(), link)
 				}
 
-				resp, err := http.Get(link)
+				resp, err := f.Get(ctx, link)
 				if err != nil {
-					ch <- data{
+					ch <- LinkResult{
 						Domain: domain,
-						Link: InvalidLink{
-							URL:    link,
-							Reason: err.Error(),
-						},
+						URL:    link,
+						Reason: err.Error(),
 					}
 
 					continue
 				}
 				resp.Body.Close()
 
+				result := LinkResult{Domain: domain, URL: link}
+
 				// server is having issues and the page is unreachable
 				if resp.StatusCode >= 500 && resp.StatusCode < 600 {
-					ch <- data{
-						Domain: domain,
-						Link: InvalidLink{
-							URL:    link,
-							Reason: fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode),
-						},
-					}
+					result.Reason = fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode)
 				}
+
+				ch <- result
 			}
 		}(domain, links)
 	}
@@ -267,12 +394,61 @@ func (p *PageContents) InvalidLinks(baseURL url.URL) map[string][]InvalidLink {
 	}()
 
 	for l := range ch {
-		out[l.Domain] = append(out[l.Domain], l.Link)
+		checked++
+
+		if l.Reason != "" {
+			out[l.Domain] = append(out[l.Domain], InvalidLink{URL: l.URL, Reason: l.Reason})
+		}
+
+		if progress != nil {
+			progress(checked, total, l)
+		}
 	}
 
 	return out
 }
 
+// getAttribute returns the value of the attribute with the given key.
+func getAttribute(node *html.Node, key string) (string, bool) {
+	for i := 0; i < len(node.Attr); i++ {
+		if strings.ToLower(node.Attr[i].Key) == key {
+			return node.Attr[i].Val, true
+		}
+	}
+
+	return "", false
+}
+
+// anchorText returns the text content of the node with collapsed whitespace,
+// or the alt text of the contained images if the node contains no text.
+func anchorText(node *html.Node) string {
+	var text, alt []string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text = append(text, n.Data)
+		case n.Type == html.ElementNode && strings.ToLower(n.Data) == "img":
+			if v, ok := getAttribute(n, "alt"); ok {
+				alt = append(alt, v)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(node)
+
+	if out := strings.Join(strings.Fields(strings.Join(text, " ")), " "); out != "" {
+		return out
+	}
+
+	return strings.Join(strings.Fields(strings.Join(alt, " ")), " ")
+}
+
 func isHeading(s string) bool {
 	switch s {
 	case "h1", "h2", "h3", "h4", "h5", "h6":
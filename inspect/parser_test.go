@@ -4,6 +4,7 @@
 package inspect
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,9 +13,21 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/net/html"
 )
 
+// mockAnchors are the anchors of the mock pages.
+var mockAnchors = []Anchor{
+	{Href: "#", Text: "link 1"},
+	{Href: "/some/relative/path/", Text: "link 2"},
+	{Href: "#test", Text: "link 3"},
+	{Href: "/some/relative/path/", Text: "link 4"},
+	{Href: "https://www.google.com", Text: "link 5"},
+	{Href: "https://www.facebook.com", Text: "link 6"},
+	{Href: "https://www.facebook.com", Text: "link 6"},
+}
+
 func TestCombine(t *testing.T) {
 	type args struct {
 		base     string
@@ -270,6 +283,7 @@ func TestTraversePage(t *testing.T) {
 						"https://www.facebook.com": struct{}{},
 					},
 				},
+				Anchors:   mockAnchors,
 				LoginForm: true,
 			},
 		},
@@ -350,6 +364,7 @@ func TestTraversePage(t *testing.T) {
 						"https://www.facebook.com": struct{}{},
 					},
 				},
+				Anchors:   mockAnchors,
 				LoginForm: true,
 			},
 		},
@@ -426,6 +441,7 @@ func TestTraversePage(t *testing.T) {
 						"https://www.facebook.com": struct{}{},
 					},
 				},
+				Anchors:   mockAnchors,
 				LoginForm: false,
 			},
 		},
@@ -490,6 +506,7 @@ func TestTraversePage(t *testing.T) {
 						"https://www.facebook.com": struct{}{},
 					},
 				},
+				Anchors:   mockAnchors,
 				LoginForm: false,
 			},
 		},
@@ -660,6 +677,7 @@ func TestPage(t *testing.T) {
 			wantContents: &PageContents{
 				Version: Version5,
 				Title:   "Some title",
+				Size:    990,
 				Headings: map[string]int{
 					"h1": 2,
 					"h3": 1,
@@ -678,6 +696,7 @@ func TestPage(t *testing.T) {
 						"https://www.facebook.com": struct{}{},
 					},
 				},
+				Anchors:   mockAnchors,
 				LoginForm: true,
 			},
 		},
@@ -743,6 +762,11 @@ func TestInvalidLinks(t *testing.T) {
 	}
 
 	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			rw.Write([]byte("User-agent: *\nDisallow: /private"))
+			return
+		}
+
 		rw.WriteHeader(http.StatusInternalServerError)
 	}))
 
@@ -831,6 +855,28 @@ func TestInvalidLinks(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "ok-disallowed",
+			args: args{
+				url: mustParse(mockServer.URL),
+			},
+			Contents: &PageContents{
+				Links: map[string]map[string]struct{}{
+					"": {
+						"/private": struct{}{},
+					},
+				},
+			},
+
+			Want: map[string][]InvalidLink{
+				mustParse(mockServer.URL).Hostname(): {
+					{
+						URL:    mockServer.URL + "/private",
+						Reason: `disallowed by robots.txt for user-agent "Go-http-client"`,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -844,3 +890,84 @@ func TestInvalidLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckLinksProgress(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	defer mockServer.Close()
+
+	base, err := url.Parse(mockServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := &PageContents{
+		Links: map[string]map[string]struct{}{
+			"":                {"/ok": struct{}{}, "/broken": struct{}{}},
+			base.Hostname():   {mockServer.URL + "/other": struct{}{}},
+			"www.example.com": {"mailto:someone@www.example.com": struct{}{}},
+		},
+	}
+
+	var (
+		checked []int
+		invalid []InvalidLink
+	)
+
+	have := contents.CheckLinksProgress(context.Background(), *base, NewFetcher(FetchOptions{}), func(n, total int, result LinkResult) {
+		if total != 4 {
+			t.Errorf("total = %v, want 4", total)
+		}
+
+		checked = append(checked, n)
+
+		if result.Reason != "" {
+			invalid = append(invalid, InvalidLink{URL: result.URL, Reason: result.Reason})
+		}
+	})
+
+	if diff := cmp.Diff(checked, []int{1, 2, 3, 4}); diff != "" {
+		t.Error(diff)
+	}
+
+	var want []InvalidLink
+	for _, links := range have {
+		want = append(want, links...)
+	}
+
+	sortLinks := cmpopts.SortSlices(func(a, b InvalidLink) bool { return a.URL < b.URL })
+
+	if diff := cmp.Diff(invalid, want, sortLinks); diff != "" {
+		t.Error(diff)
+	}
+
+	if len(invalid) != 2 {
+		t.Errorf("invalid links = %v, want 2", invalid)
+	}
+}
+
+func TestAnchorText(t *testing.T) {
+	contents, err := Page(strings.NewReader(`<html><body>
+		<a href="/a" rel="NoFollow  UGC"> Read
+			<b>more</b> </a>
+		<a href="/b"><img src="/logo.png" alt="Logo"></a>
+		<a href="/c"></a>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Anchor{
+		{Href: "/a", Text: "Read more", Rel: "nofollow ugc"},
+		{Href: "/b", Text: "Logo"},
+		{Href: "/c"},
+	}
+
+	if diff := cmp.Diff(contents.Anchors, want); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// RecordsSection is the name of the section of a RecordExtraction.
+const RecordsSection = "records"
+
+// Field types
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldURL    = "url"
+	FieldDate   = "date"
+)
+
+// reNumber matches a number, thousands may be separated by spaces or
+// apostrophes, decimals and thousands by "." or ",".
+var reNumber = regexp.MustCompile(`-?\d{1,3}(?:[' \x{00a0}]\d{3})+\b(?:[.,]\d+)?|-?\d+(?:[.,]\d+)*`)
+
+// dateLayouts are the date formats accepted by date fields, the layouts
+// without a time are reported as dates only.
+var dateLayouts = []struct {
+	layout string
+	time   bool
+}{
+	{time.RFC3339, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02T15:04", true},
+	{"2006-01-02 15:04", true},
+	{time.RFC1123Z, true},
+	{time.RFC1123, true},
+	{"2006-01-02", false},
+	{"January 2, 2006", false},
+	{"Jan 2, 2006", false},
+	{"2 January 2006", false},
+	{"2 Jan 2006", false},
+	{"Monday, January 2, 2006", false},
+}
+
+// RecordTemplate extracts a record from every element matching the CSS
+// selector or selected by the XPath expression, exactly one of them must
+// be set.
+type RecordTemplate struct {
+	Selector string
+	XPath    string
+	Fields   map[string]RecordField
+}
+
+// RecordField extracts the first value selected by the rule from the element
+// of the record, the rule selects the element itself if it has no selector or
+// expression. XPath expressions are evaluated with the element as the context
+// node.
+type RecordField struct {
+	ExtractRule
+
+	// Type the value is coerced to, FieldString if empty.
+	Type string
+
+	// Records without a value of a required field are skipped,
+	// missing optional fields are nil.
+	Required bool
+}
+
+// Record maps the names of the fields to their values.
+type Record map[string]interface{}
+
+// RecordExtraction extracts records from a page by named templates. Like the
+// Extraction it queries the whole document once it is visited. Its result maps
+// the names of the templates to the records in document order.
+type RecordExtraction struct {
+	base      url.URL
+	templates map[string]compiledTemplate
+	records   map[string][]Record
+}
+
+type compiledTemplate struct {
+	items  compiledRule
+	fields map[string]compiledField
+}
+
+type compiledField struct {
+	compiledRule
+	typ      string
+	required bool
+}
+
+// NewRecordExtraction validates the templates and compiles their selectors and
+// expressions. URL fields are resolved against base, or the <base> of the page.
+func NewRecordExtraction(templates map[string]RecordTemplate, base url.URL) (*RecordExtraction, error) {
+	out := &RecordExtraction{base: base, templates: make(map[string]compiledTemplate)}
+
+	for name, t := range templates {
+		if t.Selector == "" && t.XPath == "" {
+			return nil, fmt.Errorf("inspect.NewRecordExtraction: template %q: missing selector or xpath", name)
+		}
+
+		if len(t.Fields) == 0 {
+			return nil, fmt.Errorf("inspect.NewRecordExtraction: template %q: no fields", name)
+		}
+
+		items, err := compileRule(ExtractRule{Selector: t.Selector, XPath: t.XPath})
+		if err != nil {
+			return nil, fmt.Errorf("inspect.NewRecordExtraction: template %q: %w", name, err)
+		}
+
+		compiled := compiledTemplate{items: items, fields: make(map[string]compiledField)}
+
+		for field, f := range t.Fields {
+			switch f.Type {
+			case "":
+				f.Type = FieldString
+			case FieldString, FieldNumber, FieldURL, FieldDate:
+			default:
+				return nil, fmt.Errorf("inspect.NewRecordExtraction: template %q: field %q: unsupported type %q", name, field, f.Type)
+			}
+
+			rule, err := compileRule(f.ExtractRule)
+			if err != nil {
+				return nil, fmt.Errorf("inspect.NewRecordExtraction: template %q: field %q: %w", name, field, err)
+			}
+
+			compiled.fields[field] = compiledField{compiledRule: rule, typ: f.Type, required: f.Required}
+		}
+
+		out.templates[name] = compiled
+	}
+
+	return out, nil
+}
+
+func (e *RecordExtraction) Name() string { return RecordsSection }
+
+func (e *RecordExtraction) Visit(node *html.Node) error {
+	if node.Type != html.DocumentNode {
+		return nil
+	}
+
+	records, err := e.Extract(node)
+	if err != nil {
+		return err
+	}
+
+	e.records = records
+
+	return nil
+}
+
+func (e *RecordExtraction) Result() interface{} { return e.records }
+
+// Extract returns the records extracted by the templates from the descendants of root.
+func (e *RecordExtraction) Extract(root *html.Node) (map[string][]Record, error) {
+	base := e.base
+
+	if nodes, err := Select(root, "base[href]"); err == nil && len(nodes) > 0 {
+		href, _ := getAttribute(nodes[0], "href")
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = *u
+		}
+	}
+
+	out := make(map[string][]Record)
+
+	// the items and their fields are evaluated in the same document.
+	doc := newXPathDocument(root)
+
+	for name, t := range e.templates {
+		items, err := t.items.elements(root, doc)
+		if err != nil {
+			return nil, fmt.Errorf("inspect.RecordExtraction.Extract: template %q: %w", name, err)
+		}
+
+		records := make([]Record, 0)
+
+	items:
+		for _, item := range items {
+			record := make(Record)
+
+			for field, f := range t.fields {
+				values, err := f.values(item, doc)
+				if err != nil {
+					return nil, fmt.Errorf("inspect.RecordExtraction.Extract: template %q: field %q: %w", name, field, err)
+				}
+
+				var value interface{}
+				if len(values) > 0 {
+					value = coerceField(values[0], f.typ, base)
+				}
+
+				if value == nil && f.required {
+					continue items
+				}
+
+				record[field] = value
+			}
+
+			records = append(records, record)
+		}
+
+		out[name] = records
+	}
+
+	return out, nil
+}
+
+// elements returns the elements selected by the rule with node as the context
+// in the document.
+func (r compiledRule) elements(node *html.Node, doc *xpathDocument) ([]*html.Node, error) {
+	if r.selector != nil {
+		return r.selector.Select(node), nil
+	}
+
+	nodes, err := r.xpath.selectNodes(node, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*html.Node
+	for _, n := range nodes {
+		if n.Attr == nil && n.Node.Type == html.ElementNode {
+			out = append(out, n.Node)
+		}
+	}
+
+	return out, nil
+}
+
+// coerceField converts the value to the type, nil if it can't be converted
+// or is empty. Numbers are float64, URLs and dates are strings.
+func coerceField(value, typ string, base url.URL) interface{} {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	switch typ {
+	case FieldNumber:
+		if f, err := ParseNumber(value); err == nil {
+			return f
+		}
+	case FieldURL:
+		if u, err := base.Parse(value); err == nil {
+			return u.String()
+		}
+	case FieldDate:
+		if d, err := ParseDate(value); err == nil {
+			return d
+		}
+	default:
+		return value
+	}
+
+	return nil
+}
+
+// ParseNumber parses the first number in the text, which may be surrounded
+// by other text like a currency. Both "." and "," are accepted as decimal
+// separators, a single "," followed by three digits separates thousands.
+func ParseNumber(text string) (float64, error) {
+	match := reNumber.FindString(text)
+	if match == "" {
+		return 0, errors.New("inspect.ParseNumber: no number found")
+	}
+
+	number := strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(match)
+
+	dot, comma := strings.LastIndex(number, "."), strings.LastIndex(number, ",")
+
+	switch {
+	case dot >= 0 && comma >= 0:
+		// the last separator is the decimal one.
+		if dot > comma {
+			number = strings.ReplaceAll(number, ",", "")
+		} else {
+			number = strings.ReplaceAll(number, ".", "")
+			number = strings.Replace(number, ",", ".", 1)
+		}
+	case comma >= 0:
+		if strings.Count(number, ",") == 1 && len(number)-comma-1 != 3 {
+			number = strings.Replace(number, ",", ".", 1)
+		} else {
+			number = strings.ReplaceAll(number, ",", "")
+		}
+	case strings.Count(number, ".") > 1:
+		number = strings.ReplaceAll(number, ".", "")
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("inspect.ParseNumber: invalid number %q: %w", match, err)
+	}
+
+	return f, nil
+}
+
+// ParseDate parses a date in one of the common formats and returns it as
+// RFC 3339, or as YYYY-MM-DD if the text has no time.
+func ParseDate(text string) (string, error) {
+	text = strings.TrimSpace(text)
+
+	for _, l := range dateLayouts {
+		t, err := time.Parse(l.layout, text)
+		if err != nil {
+			continue
+		}
+
+		if !l.time {
+			return t.Format("2006-01-02"), nil
+		}
+
+		return t.Format(time.RFC3339), nil
+	}
+
+	return "", fmt.Errorf("inspect.ParseDate: unsupported date format %q", text)
+}
@@ -0,0 +1,254 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const recordsPage = `<!DOCTYPE html>
+<html>
+<head><base href="/shop/"></head>
+<body>
+	<div class="product" data-sku="A-1">
+		<h2>Lamp</h2>
+		<span class="price">1 299,90 EUR</span>
+		<a href="lamp.html">Details</a>
+		<time datetime="2021-03-04">March 4</time>
+	</div>
+	<div class="product" data-sku="B-2">
+		<h2>Chair</h2>
+		<span class="price">$20</span>
+		<a href="https://www.example.com/chair">Details</a>
+		<time datetime="soon">Soon</time>
+	</div>
+	<div class="product" data-sku="C-3">
+		<h2>Table</h2>
+		<span class="price">sold out</span>
+	</div>
+	<div class="product">
+		<span class="price">5 EUR</span>
+	</div>
+</body>
+</html>`
+
+func TestRecordExtraction(t *testing.T) {
+	base, err := url.Parse("https://shop.example.com/products/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name      string
+		Templates map[string]RecordTemplate
+		want      map[string][]Record
+		wantErr   bool
+	}{
+		{
+			Name: "ok",
+			Templates: map[string]RecordTemplate{
+				"products": {
+					Selector: ".product",
+					Fields: map[string]RecordField{
+						"name":  {ExtractRule: ExtractRule{Selector: "h2"}, Required: true},
+						"sku":   {ExtractRule: ExtractRule{Mode: ExtractAttr, Attr: "data-sku"}},
+						"price": {ExtractRule: ExtractRule{Selector: ".price"}, Type: FieldNumber},
+						"url":   {ExtractRule: ExtractRule{Selector: "a", Mode: ExtractAttr, Attr: "href"}, Type: FieldURL},
+						"date":  {ExtractRule: ExtractRule{XPath: "time/@datetime"}, Type: FieldDate},
+					},
+				},
+			},
+			want: map[string][]Record{
+				"products": {
+					{"name": "Lamp", "sku": "A-1", "price": 1299.9, "url": "https://shop.example.com/shop/lamp.html", "date": "2021-03-04"},
+					{"name": "Chair", "sku": "B-2", "price": 20.0, "url": "https://www.example.com/chair", "date": nil},
+					{"name": "Table", "sku": "C-3", "price": nil, "url": nil, "date": nil},
+				},
+			},
+		},
+		{
+			Name: "ok-required-number",
+			Templates: map[string]RecordTemplate{
+				"prices": {
+					XPath: "//span[@class='price']",
+					Fields: map[string]RecordField{
+						"price": {Type: FieldNumber, Required: true},
+					},
+				},
+			},
+			want: map[string][]Record{
+				"prices": {{"price": 1299.9}, {"price": 20.0}, {"price": 5.0}},
+			},
+		},
+		{
+			Name: "ok-no-items",
+			Templates: map[string]RecordTemplate{
+				"rows": {Selector: "tr", Fields: map[string]RecordField{"cell": {ExtractRule: ExtractRule{Selector: "td"}}}},
+			},
+			want: map[string][]Record{"rows": {}},
+		},
+		{
+			Name: "fail-missing-selector",
+			Templates: map[string]RecordTemplate{
+				"products": {Fields: map[string]RecordField{"name": {ExtractRule: ExtractRule{Selector: "h2"}}}},
+			},
+			wantErr: true,
+		},
+		{
+			Name:      "fail-no-fields",
+			Templates: map[string]RecordTemplate{"products": {Selector: ".product"}},
+			wantErr:   true,
+		},
+		{
+			Name: "fail-type",
+			Templates: map[string]RecordTemplate{
+				"products": {Selector: ".product", Fields: map[string]RecordField{"name": {Type: "int"}}},
+			},
+			wantErr: true,
+		},
+		{
+			Name: "fail-field-selector",
+			Templates: map[string]RecordTemplate{
+				"products": {Selector: ".product", Fields: map[string]RecordField{"name": {ExtractRule: ExtractRule{Selector: "h2 >"}}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			extraction, err := NewRecordExtraction(tt.Templates, *base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRecordExtraction() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			contents, err := Page(strings.NewReader(recordsPage), extraction)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(contents.Sections[RecordsSection], tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRecordExtractionManyItems(t *testing.T) {
+	const items = 3000
+
+	var page strings.Builder
+	page.WriteString("<html><body>")
+	for i := 0; i < items; i++ {
+		fmt.Fprintf(&page, `<div class="item"><p><span>%d</span></p></div>`, i)
+	}
+	page.WriteString("</body></html>")
+
+	extraction, err := NewRecordExtraction(map[string]RecordTemplate{
+		"items": {
+			XPath: "//div[@class='item']",
+			Fields: map[string]RecordField{
+				"value": {ExtractRule: ExtractRule{XPath: ".//span"}, Type: FieldNumber, Required: true},
+			},
+		},
+	}, url.URL{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	contents, err := Page(strings.NewReader(page.String()), extraction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// indexing the whole document for every item takes tens of seconds.
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("extracting %d items took %v", items, elapsed)
+	}
+
+	records := contents.Sections[RecordsSection].(map[string][]Record)["items"]
+	if len(records) != items {
+		t.Fatalf("have %d records, want %d", len(records), items)
+	}
+
+	if have := records[items-1]["value"]; have != float64(items-1) {
+		t.Errorf("last record value = %v, want %v", have, items-1)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Text    string
+		want    float64
+		wantErr bool
+	}{
+		{Name: "ok-integer", Text: "42", want: 42},
+		{Name: "ok-currency", Text: "$1,299.99", want: 1299.99},
+		{Name: "ok-decimal-comma", Text: "12,50 €", want: 12.5},
+		{Name: "ok-thousands-comma", Text: "1,299", want: 1299},
+		{Name: "ok-thousands-dots", Text: "1.299.000 Kč", want: 1299000},
+		{Name: "ok-european", Text: "1.299,99", want: 1299.99},
+		{Name: "ok-space-thousands", Text: "1 299 000,5 EUR", want: 1299000.5},
+		{Name: "ok-apostrophe-thousands", Text: "CHF 1'299.50", want: 1299.5},
+		{Name: "ok-first-number", Text: "Top 10 2021", want: 10},
+		{Name: "ok-negative", Text: "-3.5 °C", want: -3.5},
+		{Name: "fail-no-number", Text: "sold out", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := ParseNumber(tt.Text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumber() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if have != tt.want {
+				t.Errorf("ParseNumber() = %v, want %v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Text    string
+		want    string
+		wantErr bool
+	}{
+		{Name: "ok-date", Text: " 2021-03-04 ", want: "2021-03-04"},
+		{Name: "ok-rfc3339", Text: "2021-03-04T10:20:30+01:00", want: "2021-03-04T10:20:30+01:00"},
+		{Name: "ok-local-time", Text: "2021-03-04 10:20", want: "2021-03-04T10:20:00Z"},
+		{Name: "ok-long", Text: "March 4, 2021", want: "2021-03-04"},
+		{Name: "ok-day-first", Text: "4 Mar 2021", want: "2021-03-04"},
+		{Name: "ok-rfc1123", Text: "Thu, 04 Mar 2021 10:20:30 GMT", want: "2021-03-04T10:20:30Z"},
+		{Name: "fail-unknown", Text: "04/03/2021", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := ParseDate(tt.Text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if have != tt.want {
+				t.Errorf("ParseDate() = %v, want %v", have, tt.want)
+			}
+		})
+	}
+}
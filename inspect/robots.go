@@ -0,0 +1,283 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize is the maximum number of bytes of a robots.txt that are parsed.
+const maxRobotsSize = 500 << 10
+
+// ErrDisallowed is returned by the Fetcher for URLs disallowed by robots.txt.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// MaxCrawlDelay caps the Crawl-delay of a robots.txt, so a hostile
+// or misconfigured host can't stall the requests indefinitely.
+const MaxCrawlDelay = time.Minute
+
+// MajorCrawlers are the user-agents of the major search engine crawlers.
+var MajorCrawlers = []string{"Googlebot", "Bingbot", "DuckDuckBot", "YandexBot", "Baiduspider", "Applebot"}
+
+// Robots is a parsed robots.txt.
+type Robots struct {
+	groups []robotsGroup
+
+	// Sitemap URLs listed in the robots.txt.
+	Sitemaps []string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobots parses a robots.txt. Lines that can't be parsed are ignored.
+func ParseRobots(r io.Reader) (*Robots, error) {
+	var (
+		out     = new(Robots)
+		current *robotsGroup
+
+		// consecutive user-agent lines start a single group.
+		inAgents = false
+	)
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+
+		key, value := strings.ToLower(strings.TrimSpace(line[:i])), strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				out.groups = append(out.groups, robotsGroup{})
+				current = &out.groups[len(out.groups)-1]
+			}
+
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			// an empty disallow allows everything.
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && current != nil && seconds >= 0 {
+				// compare the seconds before converting, large values overflow time.Duration.
+				if seconds > MaxCrawlDelay.Seconds() {
+					seconds = MaxCrawlDelay.Seconds()
+				}
+
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				out.Sitemaps = append(out.Sitemaps, value)
+			}
+		}
+
+		inAgents = false
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("inspect.ParseRobots: failed to read robots.txt: %w", err)
+	}
+
+	return out, nil
+}
+
+// Allowed checks if the user-agent may fetch the link.
+// The link may be an absolute URL or a path.
+func (r *Robots) Allowed(userAgent, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	// robots.txt itself is always allowed.
+	if path == "/robots.txt" {
+		return true
+	}
+
+	var (
+		match   = -1
+		allowed = true
+	)
+
+	for _, group := range r.match(userAgent) {
+		for _, rule := range group.rules {
+			if !robotsPatternMatches(rule.pattern, path) {
+				continue
+			}
+
+			// the longest pattern wins, allow wins ties.
+			if l := len(rule.pattern); l > match || (l == match && rule.allow) {
+				match, allowed = l, rule.allow
+			}
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay for the user-agent, 0 if none.
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+
+	for _, group := range r.match(userAgent) {
+		if group.crawlDelay > delay {
+			delay = group.crawlDelay
+		}
+	}
+
+	return delay
+}
+
+// DisallowedFor returns the user-agents that may not fetch the link.
+func (r *Robots) DisallowedFor(link string, userAgents []string) []string {
+	var out []string
+
+	for _, agent := range userAgents {
+		if !r.Allowed(agent, link) {
+			out = append(out, agent)
+		}
+	}
+
+	return out
+}
+
+// match returns the groups that apply to the user-agent, the groups of the most
+// specific matching agent are used and the * groups if no agent matched.
+func (r *Robots) match(userAgent string) []robotsGroup {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var (
+		out      []robotsGroup
+		wildcard []robotsGroup
+		longest  = 0
+	)
+
+	for _, group := range r.groups {
+		specific, isWildcard := 0, false
+
+		for _, agent := range group.agents {
+			switch {
+			case agent == "*":
+				isWildcard = true
+			case agent != "" && strings.HasPrefix(token, agent) && len(agent) > specific:
+				specific = len(agent)
+			}
+		}
+
+		switch {
+		case specific > longest:
+			out, longest = []robotsGroup{group}, specific
+		case specific > 0 && specific == longest:
+			out = append(out, group)
+		case isWildcard:
+			wildcard = append(wildcard, group)
+		}
+	}
+
+	if len(out) == 0 {
+		return wildcard
+	}
+
+	return out
+}
+
+// robotsPatternMatches matches the path against a robots.txt pattern
+// supporting the * wildcard and the $ end anchor.
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		// with an anchor the last part has to match the end of the path.
+		if anchored && i == len(parts)-1 {
+			return strings.HasSuffix(path[pos:], parts[i])
+		}
+
+		j := strings.Index(path[pos:], parts[i])
+		if j < 0 {
+			return false
+		}
+
+		pos += j + len(parts[i])
+	}
+
+	return !anchored || pos == len(path)
+}
+
+// RobotsURL returns the URL of the robots.txt of the host of the URL.
+func RobotsURL(u url.URL) string {
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+}
+
+// FetchRobots fetches and parses the robots.txt of the host of the URL.
+// A missing robots.txt allows everything, while a server error disallows
+// everything. Network errors are returned.
+func FetchRobots(ctx context.Context, f *Fetcher, u url.URL) (*Robots, error) {
+	resp, err := f.do(ctx, RobotsURL(u))
+	if err != nil {
+		return nil, fmt.Errorf("inspect.FetchRobots: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &Robots{groups: []robotsGroup{{agents: []string{"*"}, rules: []robotsRule{{pattern: "/"}}}}}, nil
+	case resp.StatusCode >= 400:
+		return new(Robots), nil
+	}
+
+	return ParseRobots(resp.Body)
+}
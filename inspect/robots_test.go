@@ -0,0 +1,254 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const mockRobots = `# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?
+Crawl-delay: 1.5
+
+User-agent: Googlebot
+User-agent: Bingbot
+Disallow: /nogoogle
+Disallow:
+
+User-agent: Googlebot-Image
+Disallow: /
+
+Sitemap: https://www.example.com/sitemap.xml
+Sitemap: https://www.example.com/news.xml
+`
+
+func TestRobotsAllowed(t *testing.T) {
+	robots, err := ParseRobots(strings.NewReader(mockRobots))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name      string
+		UserAgent string
+		Link      string
+		Want      bool
+	}{
+		{Name: "ok-root", UserAgent: "htmlinspect", Link: "/", Want: true},
+		{Name: "ok-disallowed", UserAgent: "htmlinspect", Link: "/private/data", Want: false},
+		{Name: "ok-longest-allow", UserAgent: "htmlinspect", Link: "/private/public/page", Want: true},
+		{Name: "ok-wildcard-anchor", UserAgent: "htmlinspect", Link: "/docs/a.pdf", Want: false},
+		{Name: "ok-wildcard-anchor-not-end", UserAgent: "htmlinspect", Link: "/docs/a.pdf.html", Want: true},
+		{Name: "ok-query", UserAgent: "htmlinspect", Link: "https://www.example.com/search?q=go", Want: false},
+		{Name: "ok-absolute-url", UserAgent: "htmlinspect", Link: "https://www.example.com/private", Want: false},
+		{Name: "ok-robots-txt", UserAgent: "htmlinspect", Link: "/robots.txt", Want: true},
+		{Name: "ok-group-replaces-wildcard", UserAgent: "Googlebot", Link: "/private", Want: true},
+		{Name: "ok-shared-group", UserAgent: "bingbot/2.0", Link: "/nogoogle", Want: false},
+		{Name: "ok-user-agent-token", UserAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)", Link: "/nogoogle", Want: false},
+		{Name: "ok-most-specific-agent", UserAgent: "Googlebot-Image", Link: "/nogoogle", Want: false},
+		{Name: "ok-most-specific-agent-all", UserAgent: "Googlebot-Image", Link: "/", Want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := robots.Allowed(tt.UserAgent, tt.Link); have != tt.Want {
+				t.Errorf("Allowed(%q, %q) = %v, want %v", tt.UserAgent, tt.Link, have, tt.Want)
+			}
+		})
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots, err := ParseRobots(strings.NewReader(mockRobots))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(robots.Sitemaps, []string{"https://www.example.com/sitemap.xml", "https://www.example.com/news.xml"}); diff != "" {
+		t.Error(diff)
+	}
+
+	if have := robots.CrawlDelay("htmlinspect"); have != 1500*time.Millisecond {
+		t.Errorf("CrawlDelay() = %v, want %v", have, 1500*time.Millisecond)
+	}
+
+	if have := robots.CrawlDelay("Googlebot"); have != 0 {
+		t.Errorf("CrawlDelay() = %v, want 0", have)
+	}
+
+	if diff := cmp.Diff(robots.DisallowedFor("/nogoogle", MajorCrawlers), []string{"Googlebot", "Bingbot"}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParseRobotsCrawlDelayCap(t *testing.T) {
+	for _, delay := range []string{"86400", "1e300"} {
+		robots, err := ParseRobots(strings.NewReader("User-agent: *\nCrawl-delay: " + delay))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := robots.CrawlDelay("htmlinspect"); have != MaxCrawlDelay {
+			t.Errorf("CrawlDelay() for %v = %v, want %v", delay, have, MaxCrawlDelay)
+		}
+	}
+}
+
+func TestRobotsPatternMatches(t *testing.T) {
+	tests := []struct {
+		Pattern string
+		Path    string
+		Want    bool
+	}{
+		{Pattern: "/", Path: "/anything", Want: true},
+		{Pattern: "/fish", Path: "/fish.html", Want: true},
+		{Pattern: "/fish", Path: "/Fish", Want: false},
+		{Pattern: "/fish*", Path: "/fishheads/yummy", Want: true},
+		{Pattern: "/*.php", Path: "/folder/index.php?a=b", Want: true},
+		{Pattern: "/*.php$", Path: "/folder/index.php?a=b", Want: false},
+		{Pattern: "/*.php$", Path: "/index.php", Want: true},
+		{Pattern: "/fish$", Path: "/fish", Want: true},
+		{Pattern: "/fish$", Path: "/fish/", Want: false},
+		{Pattern: "/a*b*c", Path: "/axxbyyc", Want: true},
+		{Pattern: "/a*b*c", Path: "/axxcyyb", Want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Pattern+" "+tt.Path, func(t *testing.T) {
+			if have := robotsPatternMatches(tt.Pattern, tt.Path); have != tt.Want {
+				t.Errorf("robotsPatternMatches(%q, %q) = %v, want %v", tt.Pattern, tt.Path, have, tt.Want)
+			}
+		})
+	}
+}
+
+func TestFetchRobots(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Status  int
+		Body    string
+		Allowed bool
+	}{
+		{Name: "ok", Status: http.StatusOK, Body: "User-agent: *\nDisallow: /page", Allowed: false},
+		{Name: "ok-not-found", Status: http.StatusNotFound, Allowed: true},
+		{Name: "ok-server-error", Status: http.StatusServiceUnavailable, Allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(tt.Status)
+				rw.Write([]byte(tt.Body))
+			}))
+			defer mockServer.Close()
+
+			site, err := url.Parse(mockServer.URL + "/page")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			robots, err := FetchRobots(context.Background(), NewFetcher(FetchOptions{}), *site)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if have := robots.Allowed("htmlinspect", site.String()); have != tt.Allowed {
+				t.Errorf("Allowed() = %v, want %v", have, tt.Allowed)
+			}
+		})
+	}
+}
+
+func TestFetcherRobotsRetry(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer mockServer.Close()
+
+	u, err := url.Parse(mockServer.URL + "/private")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFetcher(FetchOptions{RespectRobots: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the robots.txt can't be fetched with a canceled context.
+	if robots := f.Robots(ctx, *u); !robots.Allowed(f.RobotsUserAgent(), u.String()) {
+		t.Errorf("Robots() with canceled context disallows %v", u)
+	}
+
+	if robots := f.Robots(context.Background(), *u); robots.Allowed(f.RobotsUserAgent(), u.String()) {
+		t.Errorf("Robots() allows %v after a failed fetch", u)
+	}
+}
+
+func TestFetcherRespectRobots(t *testing.T) {
+	var robotsRequests int32
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsRequests, 1)
+			rw.Write([]byte("User-agent: *\nDisallow: /private\n\nUser-agent: htmlinspect\nDisallow: /secret\n"))
+		}
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		Name    string
+		opts    FetchOptions
+		Link    string
+		wantErr bool
+	}{
+		{Name: "ok", opts: FetchOptions{RespectRobots: true}, Link: "/public"},
+		{Name: "fail-disallowed", opts: FetchOptions{RespectRobots: true}, Link: "/private", wantErr: true},
+		{Name: "ok-ignore-robots", opts: FetchOptions{}, Link: "/private"},
+		{Name: "ok-user-agent", opts: FetchOptions{RespectRobots: true, UserAgent: "htmlinspect/1.0"}, Link: "/private"},
+		{Name: "fail-robots-user-agent", opts: FetchOptions{RespectRobots: true, UserAgent: "Mozilla/5.0", RobotsUserAgent: "htmlinspect"}, Link: "/secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			f := NewFetcher(tt.opts)
+
+			for i := 0; i < 2; i++ {
+				resp, err := f.Get(context.Background(), mockServer.URL+tt.Link)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Get() err = %v, wantErr %v", err, tt.wantErr)
+				}
+
+				if err != nil {
+					if !errors.Is(err, ErrDisallowed) {
+						t.Errorf("Get() err = %v, want %v", err, ErrDisallowed)
+					}
+
+					continue
+				}
+
+				resp.Body.Close()
+			}
+		})
+	}
+
+	// robots.txt is fetched once per fetcher.
+	if have := atomic.LoadInt32(&robotsRequests); have != 4 {
+		t.Errorf("robots.txt requests = %v, want 4", have)
+	}
+}
@@ -0,0 +1,680 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled list of CSS selectors. It supports type, universal,
+// class, id and attribute selectors, the descendant, child, next-sibling and
+// subsequent-sibling combinators and the structural pseudo-classes including
+// :nth-child, :first-of-type and :not.
+type Selector struct {
+	list []complexSelector
+}
+
+// complexSelector is a sequence of compound selectors joined by combinators,
+// combinators[i] joins compounds[i] and compounds[i+1].
+type complexSelector struct {
+	compounds   []compoundSelector
+	combinators []byte
+}
+
+// compoundSelector matches an element satisfying all of its conditions.
+type compoundSelector []func(n *html.Node) bool
+
+// CompileSelector parses a comma separated list of CSS selectors.
+func CompileSelector(selector string) (*Selector, error) {
+	p := &selectorParser{s: selector}
+
+	list, err := p.parseList()
+	if err != nil {
+		return nil, fmt.Errorf("inspect.CompileSelector: invalid selector %q: %w", selector, err)
+	}
+
+	if p.i < len(p.s) {
+		return nil, fmt.Errorf("inspect.CompileSelector: invalid selector %q: unexpected %q at offset %v", selector, p.s[p.i], p.i)
+	}
+
+	return &Selector{list: list}, nil
+}
+
+// Select compiles the selector and returns the matching descendants of root.
+func Select(root *html.Node, selector string) ([]*html.Node, error) {
+	s, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Select(root), nil
+}
+
+// Match checks if the node is an element matching any selector of the list.
+func (s *Selector) Match(n *html.Node) bool {
+	for _, c := range s.list {
+		if c.match(n, len(c.compounds)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select returns the descendants of root matching the selector in document order.
+func (s *Selector) Select(root *html.Node) []*html.Node {
+	var out []*html.Node
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if s.Match(c) {
+				out = append(out, c)
+			}
+
+			walk(c)
+		}
+	}
+
+	walk(root)
+
+	return out
+}
+
+// match checks if the node matches the compound selector at index i and
+// the part of the complex selector on its left.
+func (c complexSelector) match(n *html.Node, i int) bool {
+	if !c.compounds[i].match(n) {
+		return false
+	}
+
+	if i == 0 {
+		return true
+	}
+
+	switch c.combinators[i-1] {
+	case ' ':
+		for a := n.Parent; a != nil; a = a.Parent {
+			if c.match(a, i-1) {
+				return true
+			}
+		}
+	case '>':
+		return n.Parent != nil && c.match(n.Parent, i-1)
+	case '+':
+		prev := previousElement(n)
+		return prev != nil && c.match(prev, i-1)
+	case '~':
+		for prev := previousElement(n); prev != nil; prev = previousElement(prev) {
+			if c.match(prev, i-1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	for _, cond := range c {
+		if !cond(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selectorParser is a recursive descent parser of CSS selectors.
+type selectorParser struct {
+	s string
+	i int
+}
+
+// parseList parses a comma separated list of complex selectors.
+func (p *selectorParser) parseList() ([]complexSelector, error) {
+	var out []complexSelector
+
+	for {
+		p.skipSpace()
+
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, c)
+
+		p.skipSpace()
+
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+
+		return out, nil
+	}
+}
+
+// parseComplex parses compound selectors joined by combinators.
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var out complexSelector
+
+	compound, err := p.parseCompound()
+	if err != nil {
+		return out, err
+	}
+
+	out.compounds = append(out.compounds, compound)
+
+	for {
+		space := p.skipSpace()
+
+		if p.i >= len(p.s) || p.s[p.i] == ',' || p.s[p.i] == ')' {
+			return out, nil
+		}
+
+		combinator := byte(' ')
+
+		switch p.s[p.i] {
+		case '>', '+', '~':
+			combinator = p.s[p.i]
+			p.i++
+			p.skipSpace()
+		default:
+			if !space {
+				return out, fmt.Errorf("unexpected %q at offset %v", p.s[p.i], p.i)
+			}
+		}
+
+		compound, err := p.parseCompound()
+		if err != nil {
+			return out, err
+		}
+
+		out.combinators = append(out.combinators, combinator)
+		out.compounds = append(out.compounds, compound)
+	}
+}
+
+// parseCompound parses an optional type selector followed by
+// id, class, attribute and pseudo-class selectors.
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var (
+		out   compoundSelector
+		start = p.i
+	)
+
+	switch {
+	case p.i < len(p.s) && p.s[p.i] == '*':
+		p.i++
+	case p.identStart():
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+
+		name = strings.ToLower(name)
+		out = append(out, func(n *html.Node) bool { return strings.ToLower(n.Data) == name })
+	}
+
+	for p.i < len(p.s) {
+		var (
+			cond func(n *html.Node) bool
+			err  error
+		)
+
+		switch p.s[p.i] {
+		case '#':
+			p.i++
+
+			var id string
+			if id, err = p.parseIdent(); err == nil {
+				cond = attributeMatcher("id", "=", id, false)
+			}
+		case '.':
+			p.i++
+
+			var class string
+			if class, err = p.parseIdent(); err == nil {
+				cond = attributeMatcher("class", "~=", class, false)
+			}
+		case '[':
+			cond, err = p.parseAttribute()
+		case ':':
+			cond, err = p.parsePseudo()
+		default:
+			if p.i == start {
+				return nil, fmt.Errorf("expected a selector at offset %v", p.i)
+			}
+
+			return out, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, cond)
+	}
+
+	if p.i == start {
+		return nil, fmt.Errorf("expected a selector at offset %v", p.i)
+	}
+
+	return out, nil
+}
+
+// parseAttribute parses an attribute selector like [name], [name=value] or [name^="value" i].
+func (p *selectorParser) parseAttribute() (func(n *html.Node) bool, error) {
+	p.i++ // [
+	p.skipSpace()
+
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+
+	if p.i < len(p.s) && p.s[p.i] == ']' {
+		p.i++
+		return attributeMatcher(strings.ToLower(name), "", "", false), nil
+	}
+
+	var op string
+
+	for _, o := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.i:], o) {
+			op = o
+			break
+		}
+	}
+
+	if op == "" {
+		return nil, fmt.Errorf("expected an attribute operator at offset %v", p.i)
+	}
+
+	p.i += len(op)
+	p.skipSpace()
+
+	var value string
+
+	if p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+		value, err = p.parseString()
+	} else {
+		value, err = p.parseIdent()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+
+	fold := false
+	if p.i < len(p.s) && (p.s[p.i] == 'i' || p.s[p.i] == 'I' || p.s[p.i] == 's' || p.s[p.i] == 'S') {
+		fold = p.s[p.i] == 'i' || p.s[p.i] == 'I'
+		p.i++
+		p.skipSpace()
+	}
+
+	if p.i >= len(p.s) || p.s[p.i] != ']' {
+		return nil, fmt.Errorf("expected ] at offset %v", p.i)
+	}
+
+	p.i++
+
+	return attributeMatcher(strings.ToLower(name), op, value, fold), nil
+}
+
+// parsePseudo parses a pseudo-class like :first-child, :nth-child(2n+1) or :not(.hidden).
+func (p *selectorParser) parsePseudo() (func(n *html.Node) bool, error) {
+	p.i++ // :
+
+	if p.i < len(p.s) && p.s[p.i] == ':' {
+		return nil, fmt.Errorf("pseudo-elements are not supported at offset %v", p.i-1)
+	}
+
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(name)
+
+	switch name {
+	case "root":
+		return func(n *html.Node) bool { return n.Parent != nil && n.Parent.Type == html.DocumentNode }, nil
+	case "empty":
+		return func(n *html.Node) bool {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode || (c.Type == html.TextNode && c.Data != "") {
+					return false
+				}
+			}
+
+			return true
+		}, nil
+	case "first-child":
+		return nthMatcher(0, 1, false, false), nil
+	case "last-child":
+		return nthMatcher(0, 1, false, true), nil
+	case "only-child":
+		return both(nthMatcher(0, 1, false, false), nthMatcher(0, 1, false, true)), nil
+	case "first-of-type":
+		return nthMatcher(0, 1, true, false), nil
+	case "last-of-type":
+		return nthMatcher(0, 1, true, true), nil
+	case "only-of-type":
+		return both(nthMatcher(0, 1, true, false), nthMatcher(0, 1, true, true)), nil
+	}
+
+	if p.i >= len(p.s) || p.s[p.i] != '(' {
+		return nil, fmt.Errorf("unsupported pseudo-class :%v", name)
+	}
+
+	p.i++ // (
+
+	switch name {
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		end := strings.IndexByte(p.s[p.i:], ')')
+		if end < 0 {
+			return nil, fmt.Errorf("expected ) after :%v", name)
+		}
+
+		a, b, err := parseNth(p.s[p.i : p.i+end])
+		if err != nil {
+			return nil, err
+		}
+
+		p.i += end + 1
+
+		return nthMatcher(a, b, strings.HasSuffix(name, "of-type"), strings.Contains(name, "last")), nil
+	case "not":
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.i >= len(p.s) || p.s[p.i] != ')' {
+			return nil, fmt.Errorf("expected ) at offset %v", p.i)
+		}
+
+		p.i++
+
+		inner := &Selector{list: list}
+
+		return func(n *html.Node) bool { return !inner.Match(n) }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported pseudo-class :%v()", name)
+}
+
+// skipSpace skips whitespace, returns true if any was skipped.
+func (p *selectorParser) skipSpace() bool {
+	start := p.i
+
+	for p.i < len(p.s) && strings.IndexByte(" \t\n\r\f", p.s[p.i]) >= 0 {
+		p.i++
+	}
+
+	return p.i > start
+}
+
+// identStart checks if an identifier starts at the current offset.
+func (p *selectorParser) identStart() bool {
+	if p.i >= len(p.s) {
+		return false
+	}
+
+	c := p.s[p.i]
+
+	return c == '-' || c == '_' || c == '\\' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// parseIdent parses a CSS identifier resolving its escapes.
+func (p *selectorParser) parseIdent() (string, error) {
+	var b strings.Builder
+
+loop:
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+
+		switch {
+		case c == '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteRune(r)
+		case c == '-' || c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9'):
+			b.WriteByte(c)
+			p.i++
+		default:
+			break loop
+		}
+	}
+
+	if b.Len() == 0 {
+		return "", fmt.Errorf("expected an identifier at offset %v", p.i)
+	}
+
+	return b.String(), nil
+}
+
+// parseString parses a quoted string resolving its escapes.
+func (p *selectorParser) parseString() (string, error) {
+	var (
+		b     strings.Builder
+		quote = p.s[p.i]
+	)
+
+	for p.i++; p.i < len(p.s); {
+		switch c := p.s[p.i]; c {
+		case quote:
+			p.i++
+			return b.String(), nil
+		case '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string")
+}
+
+// parseEscape parses a backslash escape, either up to six hex digits
+// followed by an optional whitespace or a single escaped character.
+func (p *selectorParser) parseEscape() (rune, error) {
+	p.i++ // \
+
+	if p.i >= len(p.s) {
+		return 0, fmt.Errorf("unterminated escape")
+	}
+
+	end := p.i
+	for end < len(p.s) && end-p.i < 6 && strings.IndexByte("0123456789abcdefABCDEF", p.s[end]) >= 0 {
+		end++
+	}
+
+	if end == p.i {
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		p.i += size
+
+		return r, nil
+	}
+
+	code, _ := strconv.ParseUint(p.s[p.i:end], 16, 32)
+	p.i = end
+
+	if p.i < len(p.s) && strings.IndexByte(" \t\n\r\f", p.s[p.i]) >= 0 {
+		p.i++
+	}
+
+	if code == 0 || code > utf8.MaxRune {
+		return utf8.RuneError, nil
+	}
+
+	return rune(code), nil
+}
+
+// attributeMatcher matches the elements whose attribute satisfies the operator,
+// an empty operator matches the elements having the attribute.
+func attributeMatcher(name, op, value string, fold bool) func(n *html.Node) bool {
+	if fold {
+		value = strings.ToLower(value)
+	}
+
+	return func(n *html.Node) bool {
+		v, ok := getAttribute(n, name)
+		if !ok {
+			return false
+		}
+
+		if fold {
+			v = strings.ToLower(v)
+		}
+
+		switch op {
+		case "":
+			return true
+		case "=":
+			return v == value
+		case "~=":
+			for _, f := range strings.Fields(v) {
+				if f == value {
+					return true
+				}
+			}
+		case "|=":
+			return v == value || strings.HasPrefix(v, value+"-")
+		case "^=":
+			return value != "" && strings.HasPrefix(v, value)
+		case "$=":
+			return value != "" && strings.HasSuffix(v, value)
+		case "*=":
+			return value != "" && strings.Contains(v, value)
+		}
+
+		return false
+	}
+}
+
+// nthMatcher matches the elements whose 1-based position among their sibling
+// elements, or the siblings of the same type, is a*n+b for some n >= 0. The
+// position is counted from the last sibling if fromEnd is set.
+func nthMatcher(a, b int, ofType, fromEnd bool) func(n *html.Node) bool {
+	return func(n *html.Node) bool {
+		if n.Parent == nil {
+			return false
+		}
+
+		pos := 1
+
+		sibling := previousElement
+		if fromEnd {
+			sibling = nextElement
+		}
+
+		for s := sibling(n); s != nil; s = sibling(s) {
+			if !ofType || strings.EqualFold(s.Data, n.Data) {
+				pos++
+			}
+		}
+
+		if a == 0 {
+			return pos == b
+		}
+
+		return (pos-b)/a >= 0 && (pos-b)%a == 0
+	}
+}
+
+// parseNth parses the an+b argument of the :nth-* pseudo-classes.
+func parseNth(s string) (a, b int, err error) {
+	expr := strings.ToLower(strings.Join(strings.Fields(s), ""))
+
+	switch expr {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	n := strings.IndexByte(expr, 'n')
+	if n < 0 {
+		if b, err = strconv.Atoi(expr); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+
+		return 0, b, nil
+	}
+
+	switch coefficient := expr[:n]; coefficient {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+	}
+
+	if offset := expr[n+1:]; offset != "" {
+		if offset[0] != '+' && offset[0] != '-' {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+
+		if b, err = strconv.Atoi(offset); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+	}
+
+	return a, b, nil
+}
+
+func both(x, y func(n *html.Node) bool) func(n *html.Node) bool {
+	return func(n *html.Node) bool { return x(n) && y(n) }
+}
+
+// previousElement returns the previous sibling element of the node.
+func previousElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// nextElement returns the next sibling element of the node.
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+
+	return nil
+}
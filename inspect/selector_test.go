@@ -0,0 +1,164 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+// selectorPage is the document the selectors are tested against,
+// the matched elements are identified by their id.
+const selectorPage = `<!DOCTYPE html>
+<html id="html">
+<body id="body">
+	<div id="products" class="list products">
+		<h2 id="title">Products</h2>
+		<div id="p1" class="product featured" data-sku="A-1">
+			<span id="p1-name" class="name">Lamp</span>
+			<span id="p1-price" class="price">10 EUR</span>
+		</div>
+		<div id="p2" class="product" data-sku="B-2" lang="en-US">
+			<span id="p2-name" class="name">Chair</span>
+			<span id="p2-price" class="price sale">20 EUR</span>
+		</div>
+		<p id="note">Prices include VAT.</p>
+		<div id="p3" class="Product" data-sku="c-3">
+			<span id="p3-name" class="name"></span>
+		</div>
+	</div>
+	<ul id="menu">
+		<li id="li1"><a id="a1" href="/home">Home</a></li>
+		<li id="li2"><a id="a2" href="https://www.example.com/shop" rel="external">Shop</a></li>
+		<li id="li3"><a id="a3" href="/about.html">About</a></li>
+		<li id="li4"></li>
+	</ul>
+</body>
+</html>`
+
+func TestSelector(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(selectorPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Selector string
+		want     []string
+		wantErr  bool
+	}{
+		{Name: "ok-type", Selector: "span", want: []string{"p1-name", "p1-price", "p2-name", "p2-price", "p3-name"}},
+		{Name: "ok-type-case", Selector: "UL", want: []string{"menu"}},
+		{Name: "ok-universal", Selector: "#menu > *", want: []string{"li1", "li2", "li3", "li4"}},
+		{Name: "ok-id", Selector: "#note", want: []string{"note"}},
+		{Name: "ok-class", Selector: ".price", want: []string{"p1-price", "p2-price"}},
+		{Name: "ok-class-compound", Selector: "span.price.sale", want: []string{"p2-price"}},
+		{Name: "ok-class-case-sensitive", Selector: ".Product", want: []string{"p3"}},
+		{Name: "ok-attribute-exists", Selector: "[data-sku]", want: []string{"p1", "p2", "p3"}},
+		{Name: "ok-attribute-equals", Selector: `[data-sku="B-2"]`, want: []string{"p2"}},
+		{Name: "ok-attribute-equals-ident", Selector: `[data-sku=B-2]`, want: []string{"p2"}},
+		{Name: "ok-attribute-fold", Selector: `[data-sku^='C' i]`, want: []string{"p3"}},
+		{Name: "ok-attribute-word", Selector: `[class~=featured]`, want: []string{"p1"}},
+		{Name: "ok-attribute-dash", Selector: `[lang|=en]`, want: []string{"p2"}},
+		{Name: "ok-attribute-prefix", Selector: `a[href^="https://"]`, want: []string{"a2"}},
+		{Name: "ok-attribute-suffix", Selector: `a[href$=".html"]`, want: []string{"a3"}},
+		{Name: "ok-attribute-substring", Selector: `a[href*=shop]`, want: []string{"a2"}},
+		{Name: "ok-attribute-empty-substring", Selector: `a[href*=""]`},
+		{Name: "ok-descendant", Selector: "#products span.name", want: []string{"p1-name", "p2-name", "p3-name"}},
+		{Name: "ok-child", Selector: "body > div > h2", want: []string{"title"}},
+		{Name: "ok-child-none", Selector: "body > span"},
+		{Name: "ok-next-sibling", Selector: "h2 + div", want: []string{"p1"}},
+		{Name: "ok-subsequent-sibling", Selector: "#p1 ~ div", want: []string{"p2", "p3"}},
+		{Name: "ok-combinators", Selector: "body div>.product + .product .price", want: []string{"p2-price"}},
+		{Name: "ok-list", Selector: "#note, h2", want: []string{"title", "note"}},
+		{Name: "ok-first-child", Selector: "li:first-child", want: []string{"li1"}},
+		{Name: "ok-last-child", Selector: "li:last-child", want: []string{"li4"}},
+		{Name: "ok-only-child", Selector: "a:only-child", want: []string{"a1", "a2", "a3"}},
+		{Name: "ok-nth-child", Selector: "li:nth-child(2)", want: []string{"li2"}},
+		{Name: "ok-nth-child-odd", Selector: "li:nth-child(odd)", want: []string{"li1", "li3"}},
+		{Name: "ok-nth-child-even", Selector: "li:nth-child(even)", want: []string{"li2", "li4"}},
+		{Name: "ok-nth-child-formula", Selector: "li:nth-child(2n+3)", want: []string{"li3"}},
+		{Name: "ok-nth-child-negative", Selector: "li:nth-child( -n + 2 )", want: []string{"li1", "li2"}},
+		{Name: "ok-nth-last-child", Selector: "li:nth-last-child(1)", want: []string{"li4"}},
+		{Name: "ok-first-of-type", Selector: "#products > div:first-of-type", want: []string{"p1"}},
+		{Name: "ok-last-of-type", Selector: "#products > div:last-of-type", want: []string{"p3"}},
+		{Name: "ok-nth-of-type", Selector: "#products > div:nth-of-type(2)", want: []string{"p2"}},
+		{Name: "ok-only-of-type", Selector: "#products > :only-of-type", want: []string{"title", "note"}},
+		{Name: "ok-not", Selector: ".product:not(.featured)", want: []string{"p2"}},
+		{Name: "ok-not-list", Selector: "li:not(:first-child, :last-child)", want: []string{"li2", "li3"}},
+		{Name: "ok-not-complex", Selector: "span:not(.product .price)", want: []string{"p1-name", "p2-name", "p3-name"}},
+		{Name: "ok-empty", Selector: "li:empty, span:empty", want: []string{"p3-name", "li4"}},
+		{Name: "ok-root", Selector: ":root", want: []string{"html"}},
+		{Name: "ok-escape", Selector: `#p1\-name`, want: []string{"p1-name"}},
+		{Name: "ok-hex-escape", Selector: `#\70 1`, want: []string{"p1"}},
+		{Name: "fail-empty", Selector: "", wantErr: true},
+		{Name: "fail-trailing-combinator", Selector: "div >", wantErr: true},
+		{Name: "fail-leading-combinator", Selector: "> div", wantErr: true},
+		{Name: "fail-trailing-comma", Selector: "div,", wantErr: true},
+		{Name: "fail-unclosed-attribute", Selector: "[data-sku", wantErr: true},
+		{Name: "fail-operator", Selector: "[data-sku!=a]", wantErr: true},
+		{Name: "fail-unterminated-string", Selector: `[data-sku="a]`, wantErr: true},
+		{Name: "fail-pseudo-element", Selector: "p::before", wantErr: true},
+		{Name: "fail-unsupported-pseudo", Selector: "a:hover", wantErr: true},
+		{Name: "fail-nth", Selector: "li:nth-child(x)", wantErr: true},
+		{Name: "fail-unclosed-not", Selector: "li:not(.a", wantErr: true},
+		{Name: "fail-unexpected", Selector: "div)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			nodes, err := Select(root, tt.Selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() err = %v, want %v", err, tt.wantErr)
+			}
+
+			var have []string
+			for _, n := range nodes {
+				id, _ := getAttribute(n, "id")
+				have = append(have, id)
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseNth(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Expr    string
+		wantA   int
+		wantB   int
+		wantErr bool
+	}{
+		{Name: "ok-odd", Expr: "odd", wantA: 2, wantB: 1},
+		{Name: "ok-even", Expr: " EVEN ", wantA: 2},
+		{Name: "ok-number", Expr: "3", wantB: 3},
+		{Name: "ok-n", Expr: "n", wantA: 1},
+		{Name: "ok-minus-n", Expr: "-n+3", wantA: -1, wantB: 3},
+		{Name: "ok-formula", Expr: "3n - 2", wantA: 3, wantB: -2},
+		{Name: "fail-offset", Expr: "2n3", wantErr: true},
+		{Name: "fail-coefficient", Expr: "xn+1", wantErr: true},
+		{Name: "fail-empty", Expr: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			a, b, err := parseNth(tt.Expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNth() err = %v, want %v", err, tt.wantErr)
+			}
+
+			if a != tt.wantA || b != tt.wantB {
+				t.Errorf("parseNth() = %v, %v, want %v, %v", a, b, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
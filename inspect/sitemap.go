@@ -0,0 +1,559 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sitemap limits
+const (
+	// MaxSitemapURLs is the maximum number of URLs of a single sitemap.
+	MaxSitemapURLs = 50000
+
+	// MaxSitemapSize is the maximum uncompressed size of a single sitemap in bytes.
+	MaxSitemapSize = 50 << 20
+
+	// MaxSitemaps is the maximum number of sitemaps fetched for a site.
+	MaxSitemaps = 50
+
+	// MaxSitemapChecks is the maximum number of sitemap URLs
+	// not visited by the crawl that are requested.
+	MaxSitemapChecks = 500
+)
+
+// lastModLayouts are the W3C Datetime formats allowed for lastmod.
+var lastModLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	time.RFC3339, // also accepts fractions of a second.
+}
+
+// Sitemap is a parsed XML sitemap or sitemap index.
+type Sitemap struct {
+	URL string
+
+	// If the sitemap is a sitemap index listing other sitemaps.
+	Index bool
+
+	// Pages listed in a urlset.
+	URLs []SitemapURL
+
+	// Sitemaps listed in a sitemap index.
+	Sitemaps []SitemapURL
+
+	// Reason the sitemap could not be fetched or parsed.
+	Error string
+}
+
+// SitemapURL is a single entry of a sitemap.
+type SitemapURL struct {
+	Loc        string
+	LastMod    string
+	ChangeFreq string
+	Priority   string
+}
+
+// SitemapIssue is a problem with an URL listed in a sitemap.
+type SitemapIssue struct {
+	URL string
+
+	// URL of the sitemap that lists the URL.
+	Sitemap string
+
+	Reason string
+}
+
+// SitemapReport is the result of cross-checking the sitemaps of a site against a crawl.
+type SitemapReport struct {
+	// Fetched sitemaps, including the sitemap indexes.
+	Sitemaps []*Sitemap
+
+	// Number of distinct URLs in the sitemaps.
+	URLs int
+
+	// Number of URLs that were neither crawled nor requested
+	// because the limit of MaxSitemapChecks was reached.
+	Unchecked int
+
+	// URLs in the sitemaps that responded with an error.
+	Broken []SitemapIssue
+
+	// Crawled indexable pages that are not listed in any sitemap.
+	Missing []string
+
+	// URLs in the sitemaps with an invalid lastmod value.
+	InvalidLastMod []SitemapIssue
+
+	// URLs in the sitemaps that are not the canonical URL of the page.
+	NonCanonical []SitemapIssue
+}
+
+type xmlSitemap struct {
+	XMLName  xml.Name
+	URLs     []xmlSitemapEntry `xml:"url"`
+	Sitemaps []xmlSitemapEntry `xml:"sitemap"`
+}
+
+type xmlSitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// ParseSitemap parses a sitemap urlset or a sitemap index.
+// Gzip compressed sitemaps are decompressed.
+func ParseSitemap(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("inspect.ParseSitemap: failed to decompress sitemap: %w", err)
+		}
+
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var doc xmlSitemap
+	if err := xml.NewDecoder(io.LimitReader(r, MaxSitemapSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("inspect.ParseSitemap: failed to parse sitemap: %w", err)
+	}
+
+	out := new(Sitemap)
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, e := range doc.URLs {
+			out.URLs = append(out.URLs, e.sitemapURL())
+		}
+	case "sitemapindex":
+		out.Index = true
+		for _, e := range doc.Sitemaps {
+			out.Sitemaps = append(out.Sitemaps, e.sitemapURL())
+		}
+	default:
+		return nil, fmt.Errorf("inspect.ParseSitemap: not a sitemap, root element: %v", doc.XMLName.Local)
+	}
+
+	return out, nil
+}
+
+func (e xmlSitemapEntry) sitemapURL() SitemapURL {
+	return SitemapURL{
+		Loc:        strings.TrimSpace(e.Loc),
+		LastMod:    strings.TrimSpace(e.LastMod),
+		ChangeFreq: strings.TrimSpace(e.ChangeFreq),
+		Priority:   strings.TrimSpace(e.Priority),
+	}
+}
+
+// DiscoverSitemaps returns the sitemaps listed in the robots.txt
+// of the site, or the /sitemap.xml of the site if none are listed.
+func DiscoverSitemaps(ctx context.Context, f *Fetcher, site url.URL) []string {
+	if sitemaps := f.Robots(ctx, site).Sitemaps; len(sitemaps) > 0 {
+		return sitemaps
+	}
+
+	return []string{(&url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/sitemap.xml"}).String()}
+}
+
+// FetchSitemaps discovers and fetches the sitemaps of the site following
+// the sitemap indexes. At most MaxSitemaps sitemaps are fetched.
+func FetchSitemaps(ctx context.Context, f *Fetcher, site url.URL) []*Sitemap {
+	var (
+		out   []*Sitemap
+		queue = DiscoverSitemaps(ctx, f, site)
+		seen  = make(map[string]struct{})
+	)
+
+	for len(queue) > 0 && len(out) < MaxSitemaps {
+		link := queue[0]
+		queue = queue[1:]
+
+		if _, ok := seen[link]; ok {
+			continue
+		}
+
+		seen[link] = struct{}{}
+
+		sitemap := fetchSitemap(ctx, f, link)
+		out = append(out, sitemap)
+
+		for _, s := range sitemap.Sitemaps {
+			queue = append(queue, s.Loc)
+		}
+	}
+
+	return out
+}
+
+// fetchSitemap fetches and parses a single sitemap.
+func fetchSitemap(ctx context.Context, f *Fetcher, link string) *Sitemap {
+	resp, err := f.Get(ctx, link)
+	if err != nil {
+		return &Sitemap{URL: link, Error: err.Error()}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return &Sitemap{URL: link, Error: fmt.Sprintf("endpoint responded with code: %v", resp.StatusCode)}
+	}
+
+	sitemap, err := ParseSitemap(resp.Body)
+	if err != nil {
+		return &Sitemap{URL: link, Error: err.Error()}
+	}
+
+	sitemap.URL = link
+
+	return sitemap
+}
+
+// ValidLastMod checks if the lastmod value is a W3C Datetime not in the future.
+func ValidLastMod(lastMod string, now time.Time) error {
+	for _, layout := range lastModLayouts {
+		t, err := time.Parse(layout, lastMod)
+		if err != nil {
+			continue
+		}
+
+		if t.After(now) {
+			return fmt.Errorf("lastmod %q is in the future", lastMod)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("lastmod %q is not a W3C Datetime", lastMod)
+}
+
+// CheckSitemaps fetches the sitemaps of the crawled site and cross-checks them
+// against the crawled pages. Sitemap URLs not visited by the crawl are requested
+// through the fetcher.
+func (s *Site) CheckSitemaps(ctx context.Context, f *Fetcher) *SitemapReport {
+	seed, err := url.Parse(s.Seed)
+	if err != nil {
+		return new(SitemapReport)
+	}
+
+	return s.checkSitemaps(ctx, f, FetchSitemaps(ctx, f, *seed), time.Now())
+}
+
+// sitemapEntry is an URL of a sitemap together with the sitemap listing it.
+type sitemapEntry struct {
+	SitemapURL
+	sitemap string
+}
+
+func (s *Site) checkSitemaps(ctx context.Context, f *Fetcher, sitemaps []*Sitemap, now time.Time) *SitemapReport {
+	var (
+		out     = &SitemapReport{Sitemaps: sitemaps}
+		entries []sitemapEntry
+		listed  = make(map[string]struct{})
+	)
+
+	for _, sitemap := range sitemaps {
+		for _, u := range sitemap.URLs {
+			key := normalizeURL(u.Loc)
+			if _, ok := listed[key]; ok {
+				continue
+			}
+
+			listed[key] = struct{}{}
+			entries = append(entries, sitemapEntry{SitemapURL: u, sitemap: sitemap.URL})
+		}
+	}
+
+	out.URLs = len(entries)
+
+	crawled := make(map[string]*CrawledPage)
+	for _, page := range s.Pages {
+		crawled[normalizeURL(page.URL)] = page
+	}
+
+	var unchecked []sitemapEntry
+
+	for _, e := range entries {
+		if e.LastMod != "" {
+			if err := ValidLastMod(e.LastMod, now); err != nil {
+				out.InvalidLastMod = append(out.InvalidLastMod, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: err.Error()})
+			}
+		}
+
+		if reason := nonCanonicalLoc(e.Loc, e.sitemap); reason != "" {
+			out.NonCanonical = append(out.NonCanonical, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: reason})
+			continue
+		}
+
+		page, ok := crawled[normalizeURL(e.Loc)]
+		if !ok {
+			unchecked = append(unchecked, e)
+			continue
+		}
+
+		out.addPageIssues(e, page)
+	}
+
+	if len(unchecked) > MaxSitemapChecks {
+		out.Unchecked = len(unchecked) - MaxSitemapChecks
+		unchecked = unchecked[:MaxSitemapChecks]
+	}
+
+	for i, page := range checkSitemapURLs(ctx, f, unchecked) {
+		out.addPageIssues(unchecked[i], page)
+	}
+
+	for _, page := range s.Pages {
+		if page.SitemapExclusion() != "" {
+			continue
+		}
+
+		if _, ok := listed[normalizeURL(page.URL)]; !ok {
+			out.Missing = append(out.Missing, page.URL)
+		}
+	}
+
+	sort.Strings(out.Missing)
+	sortSitemapIssues(out.Broken)
+	sortSitemapIssues(out.InvalidLastMod)
+	sortSitemapIssues(out.NonCanonical)
+
+	return out
+}
+
+// addPageIssues reports the sitemap entry if the page responded with an
+// error, redirected to another URL or declares another canonical URL.
+func (r *SitemapReport) addPageIssues(e sitemapEntry, page *CrawledPage) {
+	switch {
+	case page.StatusCode == 0 && page.Error != "":
+		r.Broken = append(r.Broken, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: page.Error})
+	case page.StatusCode >= 400:
+		r.Broken = append(r.Broken, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: fmt.Sprintf("endpoint responded with code: %v", page.StatusCode)})
+	case page.FinalURL != page.URL:
+		r.NonCanonical = append(r.NonCanonical, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: fmt.Sprintf("redirects to %v", page.FinalURL)})
+	case page.Contents != nil:
+		u, err := url.Parse(page.FinalURL)
+		if err != nil {
+			return
+		}
+
+		if canonical := page.Contents.CanonicalURL(*u); canonical != "" && normalizeURL(canonical) != normalizeURL(page.URL) {
+			r.NonCanonical = append(r.NonCanonical, SitemapIssue{URL: e.Loc, Sitemap: e.sitemap, Reason: fmt.Sprintf("canonical URL is %v", canonical)})
+		}
+	}
+}
+
+// checkSitemapURLs concurrently requests the sitemap URLs,
+// at most DefaultParallelism at a time.
+func checkSitemapURLs(ctx context.Context, f *Fetcher, entries []sitemapEntry) []*CrawledPage {
+	out := make([]*CrawledPage, len(entries))
+
+	forEach(len(entries), DefaultParallelism, func(i int) {
+		page := &CrawledPage{URL: entries[i].Loc, FinalURL: entries[i].Loc}
+		out[i] = page
+
+		resp, err := f.Get(ctx, page.URL)
+		if err != nil {
+			page.Error = err.Error()
+			return
+		}
+
+		resp.Body.Close()
+
+		page.StatusCode = resp.StatusCode
+		page.FinalURL = resp.Request.URL.String()
+	})
+
+	return out
+}
+
+// nonCanonicalLoc checks that the sitemap URL is an absolute URL without
+// a fragment on the host of the sitemap and returns the reason if not.
+func nonCanonicalLoc(loc, sitemap string) string {
+	u, err := url.Parse(loc)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return "not an absolute http(s) URL"
+	}
+
+	if u.Fragment != "" {
+		return "contains a fragment"
+	}
+
+	if s, err := url.Parse(sitemap); err == nil && !strings.EqualFold(s.Host, u.Host) {
+		return fmt.Sprintf("not on the host of the sitemap %v", s.Host)
+	}
+
+	return ""
+}
+
+// normalizeURL lower-cases the scheme and host of the URL and removes the fragment.
+func normalizeURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment, u.RawFragment = "", ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
+
+func sortSitemapIssues(issues []SitemapIssue) {
+	sort.Slice(issues, func(i, j int) bool { return issues[i].URL < issues[j].URL })
+}
+
+// sitemapNamespace is the XML namespace of the sitemap protocol.
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapFile is a generated sitemap or sitemap index.
+type SitemapFile struct {
+	// Name of the file, sitemap.xml or sitemap-N.xml for the parts of an index.
+	Name string
+
+	// Number of URLs or sitemaps listed in the file.
+	URLs int
+
+	Content []byte
+}
+
+// SitemapExclusion is a crawled page left out of the generated sitemap.
+type SitemapExclusion struct{ URL, Reason string }
+
+// GeneratedSitemap is a sitemap generated from a crawl.
+type GeneratedSitemap struct {
+	// Sitemap index listing the Sitemaps, nil if
+	// all URLs fit into a single sitemap.
+	Index *SitemapFile
+
+	Sitemaps []SitemapFile
+
+	// Crawled pages that are not listed in the sitemap.
+	Excluded []SitemapExclusion
+}
+
+// GenerateSitemap generates a sitemap from the indexable canonical pages
+// of the crawl that responded with 200. If the pages don't fit into a single
+// sitemap of MaxSitemapURLs URLs and MaxSitemapSize bytes the sitemap is split
+// and a sitemap index is generated, listing the parts relative to baseURL.
+func (s *Site) GenerateSitemap(baseURL url.URL) *GeneratedSitemap {
+	return s.generateSitemap(baseURL, MaxSitemapURLs, MaxSitemapSize)
+}
+
+func (s *Site) generateSitemap(baseURL url.URL, maxURLs, maxSize int) *GeneratedSitemap {
+	var (
+		out   = new(GeneratedSitemap)
+		seen  = make(map[string]struct{})
+		parts [][]string
+		part  []string
+		size  int
+	)
+
+	header := xml.Header + `<urlset xmlns="` + sitemapNamespace + `">` + "\n"
+	footer := "</urlset>\n"
+
+	for _, page := range s.Pages {
+		if reason := page.SitemapExclusion(); reason != "" {
+			out.Excluded = append(out.Excluded, SitemapExclusion{URL: page.URL, Reason: reason})
+			continue
+		}
+
+		key := normalizeURL(page.URL)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		entry := sitemapEntryXML("url", page.URL, page.Header.Get("Last-Modified"))
+
+		if len(part) > 0 && (len(part) >= maxURLs || size+len(entry) > maxSize) {
+			parts, part = append(parts, part), nil
+		}
+
+		if len(part) == 0 {
+			size = len(header) + len(footer)
+		}
+
+		part = append(part, entry)
+		size += len(entry)
+	}
+
+	if len(part) > 0 || len(parts) == 0 {
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 1 {
+		out.Sitemaps = []SitemapFile{newSitemapFile("sitemap.xml", header, parts[0], footer)}
+		return out
+	}
+
+	var index []string
+
+	for i, part := range parts {
+		name := fmt.Sprintf("sitemap-%v.xml", i+1)
+		out.Sitemaps = append(out.Sitemaps, newSitemapFile(name, header, part, footer))
+
+		loc, err := baseURL.Parse(name)
+		if err != nil {
+			continue
+		}
+
+		index = append(index, sitemapEntryXML("sitemap", loc.String(), ""))
+	}
+
+	indexFile := newSitemapFile("sitemap.xml", xml.Header+`<sitemapindex xmlns="`+sitemapNamespace+`">`+"\n", index, "</sitemapindex>\n")
+	out.Index = &indexFile
+
+	return out
+}
+
+// sitemapEntryXML renders a single <url> or <sitemap> element. The lastmod
+// is taken from a Last-Modified header value and omitted if it can't be parsed.
+func sitemapEntryXML(element, loc, lastModified string) string {
+	buf := new(strings.Builder)
+
+	buf.WriteString("  <" + element + "><loc>")
+	xml.EscapeText(buf, []byte(loc))
+	buf.WriteString("</loc>")
+
+	if t, err := http.ParseTime(lastModified); err == nil {
+		buf.WriteString("<lastmod>" + t.UTC().Format("2006-01-02") + "</lastmod>")
+	}
+
+	buf.WriteString("</" + element + ">\n")
+
+	return buf.String()
+}
+
+func newSitemapFile(name, header string, entries []string, footer string) SitemapFile {
+	return SitemapFile{
+		Name:    name,
+		URLs:    len(entries),
+		Content: []byte(header + strings.Join(entries, "") + footer),
+	}
+}
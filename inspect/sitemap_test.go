@@ -0,0 +1,423 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const mockURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url>
+		<loc> https://www.example.com/ </loc>
+		<lastmod>2021-05-01</lastmod>
+		<changefreq>daily</changefreq>
+		<priority>1.0</priority>
+	</url>
+	<url>
+		<loc>https://www.example.com/about</loc>
+	</url>
+</urlset>`
+
+const mockSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap>
+		<loc>https://www.example.com/sitemap-1.xml.gz</loc>
+		<lastmod>2021-05-01T10:00:00+00:00</lastmod>
+	</sitemap>
+</sitemapindex>`
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParseSitemap(t *testing.T) {
+	urlset := &Sitemap{
+		URLs: []SitemapURL{
+			{Loc: "https://www.example.com/", LastMod: "2021-05-01", ChangeFreq: "daily", Priority: "1.0"},
+			{Loc: "https://www.example.com/about"},
+		},
+	}
+
+	tests := []struct {
+		Name    string
+		Input   []byte
+		Want    *Sitemap
+		wantErr bool
+	}{
+		{
+			Name:  "ok-urlset",
+			Input: []byte(mockURLSet),
+			Want:  urlset,
+		},
+		{
+			Name:  "ok-gzip",
+			Input: gzipped(t, mockURLSet),
+			Want:  urlset,
+		},
+		{
+			Name:  "ok-index",
+			Input: []byte(mockSitemapIndex),
+			Want: &Sitemap{
+				Index: true,
+				Sitemaps: []SitemapURL{
+					{Loc: "https://www.example.com/sitemap-1.xml.gz", LastMod: "2021-05-01T10:00:00+00:00"},
+				},
+			},
+		},
+		{
+			Name:    "fail-not-a-sitemap",
+			Input:   []byte(`<html><body></body></html>`),
+			wantErr: true,
+		},
+		{
+			Name:    "fail-invalid-xml",
+			Input:   []byte(`<urlset><url>`),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := ParseSitemap(bytes.NewReader(tt.Input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSitemap() err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(have, tt.Want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidLastMod(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		LastMod string
+		wantErr bool
+	}{
+		{LastMod: "2021"},
+		{LastMod: "2021-05"},
+		{LastMod: "2021-05-01"},
+		{LastMod: "2021-05-01T10:00+02:00"},
+		{LastMod: "2021-05-01T10:00:00Z"},
+		{LastMod: "2021-05-01T10:00:00.123+02:00"},
+		{LastMod: "05/01/2021", wantErr: true},
+		{LastMod: "2021-13-01", wantErr: true},
+		{LastMod: "2021-05-01 10:00:00", wantErr: true},
+		{LastMod: "2021-07-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.LastMod, func(t *testing.T) {
+			if err := ValidLastMod(tt.LastMod, now); (err != nil) != tt.wantErr {
+				t.Errorf("ValidLastMod() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSitemaps(t *testing.T) {
+	mux := http.NewServeMux()
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+
+	mux.HandleFunc("/robots.txt", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("User-agent: *\nDisallow: /private\n\nSitemap: " + mockServer.URL + "/sitemap-index.xml\n"))
+	})
+
+	mux.HandleFunc("/sitemap-index.xml", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`<sitemapindex>
+			<sitemap><loc>` + mockServer.URL + `/sitemap-1.xml.gz</loc></sitemap>
+			<sitemap><loc>` + mockServer.URL + `/sitemap-2.xml</loc></sitemap>
+		</sitemapindex>`))
+	})
+
+	mux.HandleFunc("/sitemap-1.xml.gz", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/gzip")
+		rw.Write(gzipped(t, `<urlset>
+			<url><loc>`+mockServer.URL+`/</loc><lastmod>2021-05-01</lastmod></url>
+			<url><loc>`+mockServer.URL+`/a</loc><lastmod>yesterday</lastmod></url>
+			<url><loc>`+mockServer.URL+`/gone</loc></url>
+			<url><loc>`+mockServer.URL+`/old</loc></url>
+			<url><loc>`+mockServer.URL+`/unlinked</loc></url>
+			<url><loc>`+mockServer.URL+`/private</loc></url>
+			<url><loc>`+mockServer.URL+`/dup</loc></url>
+			<url><loc>/relative</loc></url>
+			<url><loc>https://www.example.com/other-host</loc></url>
+		</urlset>`))
+	})
+
+	mux.HandleFunc("/sitemap-2.xml", func(rw http.ResponseWriter, r *http.Request) {
+		http.NotFound(rw, r)
+	})
+
+	mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			rw.Write([]byte(`<html><body><a href="/a">a</a><a href="/b">b</a><a href="/old">old</a><a href="/dup">dup</a></body></html>`))
+		case "/a", "/b", "/new", "/unlinked":
+			rw.Write([]byte(`<html><body></body></html>`))
+		case "/dup":
+			rw.Write([]byte(`<html><head><link rel="canonical" href="/a"></head><body></body></html>`))
+		case "/old":
+			http.Redirect(rw, r, "/new", http.StatusMovedPermanently)
+		default:
+			http.NotFound(rw, r)
+		}
+	})
+
+	seed, err := url.Parse(mockServer.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFetcher(FetchOptions{RespectRobots: true})
+
+	site, err := Crawl(context.Background(), *seed, f, CrawlOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := site.checkSitemaps(context.Background(), f, FetchSitemaps(context.Background(), f, *seed), time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	var sitemaps []Sitemap
+	for _, s := range report.Sitemaps {
+		sitemaps = append(sitemaps, Sitemap{URL: s.URL, Index: s.Index, Error: s.Error})
+	}
+
+	wantSitemaps := []Sitemap{
+		{URL: mockServer.URL + "/sitemap-index.xml", Index: true},
+		{URL: mockServer.URL + "/sitemap-1.xml.gz"},
+		{URL: mockServer.URL + "/sitemap-2.xml", Error: "endpoint responded with code: 404"},
+	}
+
+	if diff := cmp.Diff(sitemaps, wantSitemaps); diff != "" {
+		t.Error(diff)
+	}
+
+	sitemap := mockServer.URL + "/sitemap-1.xml.gz"
+	host := strings.TrimPrefix(mockServer.URL, "http://")
+
+	want := &SitemapReport{
+		Sitemaps: report.Sitemaps,
+		URLs:     9,
+		Broken: []SitemapIssue{
+			{URL: mockServer.URL + "/gone", Sitemap: sitemap, Reason: "endpoint responded with code: 404"},
+			{URL: mockServer.URL + "/private", Sitemap: sitemap, Reason: `disallowed by robots.txt for user-agent "Go-http-client"`},
+		},
+		Missing: []string{mockServer.URL + "/b"},
+		InvalidLastMod: []SitemapIssue{
+			{URL: mockServer.URL + "/a", Sitemap: sitemap, Reason: `lastmod "yesterday" is not a W3C Datetime`},
+		},
+		NonCanonical: []SitemapIssue{
+			{URL: "/relative", Sitemap: sitemap, Reason: "not an absolute http(s) URL"},
+			{URL: mockServer.URL + "/dup", Sitemap: sitemap, Reason: "canonical URL is " + mockServer.URL + "/a"},
+			{URL: mockServer.URL + "/old", Sitemap: sitemap, Reason: "redirects to " + mockServer.URL + "/new"},
+			{URL: "https://www.example.com/other-host", Sitemap: sitemap, Reason: "not on the host of the sitemap " + host},
+		},
+	}
+
+	if diff := cmp.Diff(report, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCheckSitemapURLsParallelism(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer mockServer.Close()
+
+	var entries []sitemapEntry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, sitemapEntry{SitemapURL: SitemapURL{Loc: fmt.Sprintf("%v/%d", mockServer.URL, i)}})
+	}
+
+	pages := checkSitemapURLs(context.Background(), NewFetcher(FetchOptions{}), entries)
+
+	for i, page := range pages {
+		if page.URL != entries[i].Loc || page.StatusCode != http.StatusOK {
+			t.Errorf("page %d = %v %v, want %v 200", i, page.URL, page.StatusCode, entries[i].Loc)
+		}
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > DefaultParallelism {
+		t.Errorf("%d concurrent requests, want at most %d", max, DefaultParallelism)
+	}
+}
+
+func TestDiscoverSitemaps(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		http.NotFound(rw, r)
+	}))
+	defer mockServer.Close()
+
+	site, err := url.Parse(mockServer.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have := DiscoverSitemaps(context.Background(), NewFetcher(FetchOptions{}), *site)
+	if diff := cmp.Diff(have, []string{mockServer.URL + "/sitemap.xml"}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGenerateSitemap(t *testing.T) {
+	page := func(link string) *CrawledPage {
+		return &CrawledPage{URL: link, FinalURL: link, StatusCode: 200, Header: http.Header{}, Contents: &PageContents{}}
+	}
+
+	lastModified := page("https://www.example.com/a?x=1&y=2")
+	lastModified.Header.Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+
+	noindex := page("https://www.example.com/private")
+	noindex.Contents.MetaRobots = []string{"noindex"}
+
+	site := &Site{
+		Seed: "https://www.example.com/",
+		Pages: []*CrawledPage{
+			page("https://www.example.com/"),
+			lastModified,
+			noindex,
+			{URL: "https://www.example.com/gone", StatusCode: 404, Error: "endpoint responded with code: 404"},
+			page("https://www.example.com/b"),
+		},
+	}
+
+	base, err := url.Parse("https://www.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantExcluded := []SitemapExclusion{
+		{URL: "https://www.example.com/private", Reason: "page is marked noindex"},
+		{URL: "https://www.example.com/gone", Reason: "endpoint responded with code: 404"},
+	}
+
+	t.Run("ok-single", func(t *testing.T) {
+		have := site.GenerateSitemap(*base)
+
+		want := &GeneratedSitemap{
+			Sitemaps: []SitemapFile{{
+				Name: "sitemap.xml",
+				URLs: 3,
+				Content: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://www.example.com/</loc></url>
+  <url><loc>https://www.example.com/a?x=1&amp;y=2</loc><lastmod>2015-10-21</lastmod></url>
+  <url><loc>https://www.example.com/b</loc></url>
+</urlset>
+`),
+			}},
+			Excluded: wantExcluded,
+		}
+
+		if diff := cmp.Diff(have, want); diff != "" {
+			t.Error(diff)
+		}
+
+		if _, err := ParseSitemap(bytes.NewReader(have.Sitemaps[0].Content)); err != nil {
+			t.Errorf("ParseSitemap() err = %v", err)
+		}
+	})
+
+	t.Run("ok-index", func(t *testing.T) {
+		have := site.generateSitemap(*base, 2, MaxSitemapSize)
+
+		if have.Index == nil {
+			t.Fatal("generateSitemap() index = nil")
+		}
+
+		index, err := ParseSitemap(bytes.NewReader(have.Index.Content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantIndex := []SitemapURL{
+			{Loc: "https://www.example.com/sitemap-1.xml"},
+			{Loc: "https://www.example.com/sitemap-2.xml"},
+		}
+
+		if diff := cmp.Diff(index.Sitemaps, wantIndex); diff != "" {
+			t.Error(diff)
+		}
+
+		var names []string
+		var urls []int
+
+		for _, s := range have.Sitemaps {
+			names, urls = append(names, s.Name), append(urls, s.URLs)
+		}
+
+		if diff := cmp.Diff(names, []string{"sitemap-1.xml", "sitemap-2.xml"}); diff != "" {
+			t.Error(diff)
+		}
+
+		if diff := cmp.Diff(urls, []int{2, 1}); diff != "" {
+			t.Error(diff)
+		}
+
+		if diff := cmp.Diff(have.Excluded, wantExcluded); diff != "" {
+			t.Error(diff)
+		}
+	})
+
+	t.Run("ok-split-size", func(t *testing.T) {
+		have := site.generateSitemap(*base, MaxSitemapURLs, 250)
+
+		var urls []int
+		for _, s := range have.Sitemaps {
+			if len(s.Content) > 250 {
+				t.Errorf("generateSitemap() %v size = %v, want at most 250", s.Name, len(s.Content))
+			}
+
+			urls = append(urls, s.URLs)
+		}
+
+		if diff := cmp.Diff(urls, []int{2, 1}); diff != "" {
+			t.Error(diff)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// StreamOptions configures the streaming extraction of PageStream.
+type StreamOptions struct {
+	// Maximum number of bytes read from the page, DefaultMaxPageSize if 0.
+	MaxSize int64
+
+	// Extractors whose results are stored in the Sections.
+	Extractors []Extractor
+}
+
+// capturedElements are the elements whose contents are extracted from their
+// child nodes, their subtree is kept in memory until the end tag is reached.
+var capturedElements = map[string]bool{
+	"a":        true,
+	"title":    true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+}
+
+// voidElements are the elements that have no end tag.
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"keygen": true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// optionalEndElements are the elements whose end tag is implied
+// by the start tag of an element with the same name.
+var optionalEndElements = map[string]bool{
+	"p":      true,
+	"li":     true,
+	"dt":     true,
+	"dd":     true,
+	"option": true,
+	"tr":     true,
+	"td":     true,
+	"th":     true,
+}
+
+// PageStream extracts the same general contents from a HTML page as Page
+// without building the DOM of the whole document. The page is tokenized as
+// it is read, only the open elements and the subtrees of the elements whose
+// contents are needed are kept in memory. At most opts.MaxSize bytes are
+// read, if the page is larger its beginning is inspected and the Truncated
+// field of the result is set.
+func PageStream(ctx context.Context, page io.Reader, opts StreamOptions) (*PageContents, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxPageSize
+	}
+
+	r := &limitedReader{ctx: ctx, r: page, n: opts.MaxSize}
+	s := &pageStream{contents: newPageContents(), extractors: opts.Extractors}
+	z := html.NewTokenizer(r)
+
+	for {
+		if z.Next() == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return nil, fmt.Errorf("inspect.PageStream: failed to read page: %w", err)
+			}
+
+			break
+		}
+
+		if err := s.token(z.Token()); err != nil {
+			return nil, fmt.Errorf("inspect.PageStream: unexpected error: %w", err)
+		}
+	}
+
+	// the contents of elements left open at the end of the page are extracted as well.
+	if err := s.release(); err != nil {
+		return nil, fmt.Errorf("inspect.PageStream: unexpected error: %w", err)
+	}
+
+	s.contents.Size = opts.MaxSize - r.n
+	s.contents.Truncated = r.truncated
+	s.contents.addSections(opts.Extractors)
+
+	return s.contents, nil
+}
+
+// pageStream extracts the contents of a page from its tokens.
+type pageStream struct {
+	contents   *PageContents
+	extractors []Extractor
+
+	// elements that were not closed yet, the innermost last.
+	open []*html.Node
+
+	// outermost captured element that was not released yet,
+	// captureDepth is its index in open.
+	capture      *html.Node
+	captureDepth int
+}
+
+// token processes the next token of the page.
+func (s *pageStream) token(t html.Token) error {
+	switch t.Type {
+	case html.DoctypeToken:
+		if s.contents.Version == "" {
+			s.doctype(t.Data)
+		}
+	case html.StartTagToken, html.SelfClosingTagToken:
+		// a new link closes the previous one.
+		if t.Data == "a" {
+			if err := s.close("a"); err != nil {
+				return err
+			}
+		}
+
+		if current := s.current(); current != nil && current.Data == t.Data && optionalEndElements[t.Data] {
+			if err := s.close(t.Data); err != nil {
+				return err
+			}
+		}
+
+		node := &html.Node{Type: html.ElementNode, Data: t.Data, DataAtom: t.DataAtom, Attr: t.Attr}
+
+		switch {
+		case s.capture != nil:
+			s.current().AppendChild(node)
+		case capturedElements[t.Data]:
+			node.Parent = s.current()
+			s.capture, s.captureDepth = node, len(s.open)
+		default:
+			node.Parent = s.current()
+			if err := s.contents.visit(node, s.extractors); err != nil {
+				return err
+			}
+		}
+
+		if t.Type == html.StartTagToken && !voidElements[t.Data] {
+			s.open = append(s.open, node)
+		} else if node == s.capture {
+			return s.release()
+		}
+	case html.EndTagToken:
+		return s.close(t.Data)
+	case html.TextToken:
+		if s.capture != nil {
+			s.current().AppendChild(&html.Node{Type: html.TextNode, Data: t.Data})
+		}
+	}
+
+	return nil
+}
+
+// doctype extracts the HTML version from the contents of the doctype token.
+func (s *pageStream) doctype(data string) {
+	root, err := html.Parse(strings.NewReader("<!DOCTYPE " + data + ">"))
+	if err != nil {
+		return
+	}
+
+	if root.FirstChild != nil && root.FirstChild.Type == html.DoctypeNode {
+		s.contents.extractVersion(root.FirstChild)
+	}
+}
+
+// current returns the innermost open element, nil if there is none.
+func (s *pageStream) current() *html.Node {
+	if len(s.open) == 0 {
+		return nil
+	}
+
+	return s.open[len(s.open)-1]
+}
+
+// close closes the innermost open element with the given name and all the
+// elements opened after it. End tags without an open element are ignored.
+func (s *pageStream) close(name string) error {
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i].Data != name {
+			continue
+		}
+
+		s.open = s.open[:i]
+
+		if s.capture != nil && i <= s.captureDepth {
+			return s.release()
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// release extracts the contents of the captured element and its subtree.
+func (s *pageStream) release() error {
+	if s.capture == nil {
+		return nil
+	}
+
+	node := s.capture
+	s.capture = nil
+
+	return s.contents.traversePage(node, s.extractors...)
+}
+
+// limitedReader reads at most n bytes from r and records if r had more
+// data to read. Reads fail once the context is done.
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+
+	probed    bool
+	truncated bool
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if err := l.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if l.n <= 0 {
+		// read one byte past the limit to detect truncated pages.
+		if !l.probed {
+			var probe [1]byte
+
+			n, _ := io.ReadFull(l.r, probe[:])
+			l.probed, l.truncated = true, n > 0
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// streamPage is a page using every element the contents are extracted from.
+const streamPage = `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Stream &amp; Page</title>
+	<meta name="robots" content="noindex, nofollow">
+	<meta http-equiv="Content-Security-Policy" content="default-src 'self'">
+	<link rel="canonical" href="https://www.example.com/">
+	<link rel="alternate" hreflang="de" href="https://www.example.com/de/">
+	<link rel="alternate" type="application/rss+xml" title="News" href="/feed.xml">
+	<link rel="manifest" href="/manifest.json">
+	<link rel="icon" href="/favicon.png" sizes="32x32" type="image/png">
+	<link rel="stylesheet" href="https://cdn.example.com/site.css">
+	<style nonce="abc">body { color: red; }</style>
+	<script src="/app.js"></script>
+	<script>window.analytics = [];</script>
+</head>
+<body onload="init()">
+	<h1>Stream</h1>
+	<ul>
+		<li><a href="/one">one <img src="/one.png" alt="first"></a>
+		<li><a href="/two"><img src="/two.png" alt="second"></a>
+		<li><a href="javascript:void(0)" rel="Nofollow  External">script</a>
+	</ul>
+	<picture>
+		<source srcset="/hero.webp">
+		<img src="/hero.png">
+	</picture>
+	<p>Paragraph<p>Another <a href="https://www.google.com">google</a>
+	<form action="/login">
+		<div><input type="password" name="password"></div>
+	</form>
+	<h2>End</h2>
+	<svg><path d="M0 0"/></svg>
+	<a href="https://www.facebook.com">first<a href="https://www.facebook.com/about">second</a>
+</body>
+</html>`
+
+func TestPageStream(t *testing.T) {
+	tests := []struct {
+		Name string
+		in   string
+	}{
+		{Name: "ok", in: streamPage},
+		{Name: "ok-html5", in: `<!DOCTYPE html><title>Title</title><h1>Heading</h1>`},
+		{Name: "ok-no-doctype", in: `<html><body><a href="/"><span>home</span></a></body></html>`},
+		{Name: "ok-unclosed", in: `<!DOCTYPE html><title>Title</title><a href="/open"><span>open`},
+		{Name: "ok-login-form-outside", in: `<form></form><input type="password">`},
+		{Name: "ok-mock-page", in: `
+			<!DOCTYPE html>
+			<html>
+			<head>
+				<title>Some title</title>
+			</head>
+			<body>
+				<div>
+					<div>
+						<a href="#"><span>link 1</span></a>
+						<div>
+							<div>
+								<h1>
+									<p> test </p>
+								</h1>
+								<a href="/some/relative/path/"><span>link 2</span></a>
+							</div>
+						</div>
+						<div>
+							<a href="#test"><span>link 3</span></a>
+							<h1>
+								<p> test 2</p>
+							</h1>
+						</div>
+						<div>
+							<form>
+								<input type="text" name="email">
+								<input type="password" name="password">
+							</form>
+						</div>
+					</div>
+					<a href="/some/relative/path/"><span>link 4</span></a>
+					<div>
+						<div>
+							<a href="https://www.google.com"><span>link 5</span></a>
+							<h3>
+								<p> test 3</p>
+							</h3>
+							<a href="https://www.facebook.com"><span>link 6</span></a>
+							<a href="https://www.facebook.com"><span>link 6</span></a>
+						</div>
+					</div>
+				</div>
+			</body>
+			</html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			want, err := Page(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			have, err := PageStream(context.Background(), strings.NewReader(tt.in), StreamOptions{})
+			if err != nil {
+				t.Fatalf("PageStream() err = %v", err)
+			}
+
+			if diff := cmp.Diff(have, want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestPageStreamLimit(t *testing.T) {
+	page := `<!DOCTYPE html><title>Title</title><a href="/first">first</a><a href="/second">second</a>`
+
+	tests := []struct {
+		Name          string
+		MaxSize       int64
+		wantAnchors   []Anchor
+		wantTruncated bool
+	}{
+		{
+			Name:        "ok",
+			wantAnchors: []Anchor{{Href: "/first", Text: "first"}, {Href: "/second", Text: "second"}},
+		},
+		{
+			Name:        "ok-exact",
+			MaxSize:     int64(len(page)),
+			wantAnchors: []Anchor{{Href: "/first", Text: "first"}, {Href: "/second", Text: "second"}},
+		},
+		{
+			Name:          "ok-truncated-text",
+			MaxSize:       int64(strings.Index(page, "second</a>") + len("sec")),
+			wantAnchors:   []Anchor{{Href: "/first", Text: "first"}, {Href: "/second", Text: "sec"}},
+			wantTruncated: true,
+		},
+		{
+			Name:          "ok-truncated-tag",
+			MaxSize:       int64(strings.Index(page, `<a href="/second"`) + len(`<a hr`)),
+			wantAnchors:   []Anchor{{Href: "/first", Text: "first"}},
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, err := PageStream(context.Background(), strings.NewReader(page), StreamOptions{MaxSize: tt.MaxSize})
+			if err != nil {
+				t.Fatalf("PageStream() err = %v", err)
+			}
+
+			if have.Title != "Title" || have.Version != Version5 {
+				t.Errorf("PageStream() title = %q, version = %q", have.Title, have.Version)
+			}
+
+			if have.Truncated != tt.wantTruncated {
+				t.Errorf("PageStream() truncated = %v, want %v", have.Truncated, tt.wantTruncated)
+			}
+
+			wantSize := tt.MaxSize
+			if wantSize == 0 {
+				wantSize = int64(len(page))
+			}
+
+			if have.Size != wantSize {
+				t.Errorf("PageStream() size = %v, want %v", have.Size, wantSize)
+			}
+
+			if diff := cmp.Diff(have.Anchors, tt.wantAnchors); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestPageStreamErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := PageStream(ctx, strings.NewReader(streamPage), StreamOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("PageStream() err = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := PageStream(context.Background(), strings.NewReader(`<a href="%%2"></a>`), StreamOptions{}); err == nil {
+		t.Error("PageStream() expected an error for an invalid link")
+	}
+}
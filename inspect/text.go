@@ -0,0 +1,297 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// TextStatsSection is the name of the section of the text statistics.
+const TextStatsSection = "text"
+
+// Text statistics regexes
+var (
+	reWord         = regexp.MustCompile(`[\p{L}\p{N}]+(?:['’-][\p{L}\p{N}]+)*`)
+	reSentenceEnd  = regexp.MustCompile(`[.!?…]+["'”’)\]]*(?:\s+|$)`)
+	reSilentEnding = regexp.MustCompile(`(?:[^laeiouy]es|[^laeiouydt]ed|[^laeiouy]e)$`)
+	reEnglishVowel = regexp.MustCompile(`[aeiouy]{1,2}`)
+)
+
+// invisibleElements have no visible text.
+var invisibleElements = map[string]bool{
+	"head": true, "script": true, "style": true, "template": true, "noscript": true,
+	"iframe": true, "object": true, "svg": true, "canvas": true,
+}
+
+// Language configures the text statistics of a language.
+type Language struct {
+	// Syllables counts the syllables of a lower-cased word.
+	Syllables func(word string) int
+
+	// ReadingEase computes the reading ease from the average number of words per
+	// sentence and syllables per word, not reported if nil.
+	ReadingEase func(wordsPerSentence, syllablesPerWord float64) float64
+
+	// Grade computes the grade level from the average number of words per
+	// sentence and syllables per word, not reported if nil.
+	Grade func(wordsPerSentence, syllablesPerWord float64) float64
+}
+
+// languages holds the registered languages by their code.
+var languages = struct {
+	mu        sync.RWMutex
+	languages map[string]Language
+}{
+	languages: make(map[string]Language),
+}
+
+func init() {
+	RegisterLanguage("en", Language{
+		Syllables:   englishSyllables,
+		ReadingEase: Flesch(206.835, 1.015, 84.6),
+		Grade: func(wordsPerSentence, syllablesPerWord float64) float64 {
+			return 0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59
+		},
+	})
+
+	// the adaptations of the Flesch reading ease by Amstad, Szigriszt-Pazos,
+	// Kandel and Moles, Franchina and Vacca, Douma and Oborneva.
+	RegisterLanguage("de", Language{Syllables: VowelGroups("aeiouyäöü"), ReadingEase: Flesch(180, 1, 58.5)})
+	RegisterLanguage("es", Language{Syllables: VowelGroups("aeiouáéíóúü"), ReadingEase: Flesch(206.835, 1, 62.3)})
+	RegisterLanguage("fr", Language{Syllables: VowelGroups("aeiouyàâäéèêëîïôöùûüÿœæ"), ReadingEase: Flesch(207, 1.015, 73.6)})
+	RegisterLanguage("it", Language{Syllables: VowelGroups("aeiouàèéìíîòóùú"), ReadingEase: Flesch(206, 1, 65)})
+	RegisterLanguage("nl", Language{Syllables: VowelGroups("aeiouyáéíóúäëïöü"), ReadingEase: Flesch(206.835, 0.93, 77)})
+	RegisterLanguage("ru", Language{Syllables: VowelGroups("аеёиоуыэюя"), ReadingEase: Flesch(206.835, 1.3, 60.1)})
+}
+
+// RegisterLanguage makes the language available by its lower-cased code, like
+// "en" or "pt-br". If a language with the same code is already registered or
+// the code is empty, RegisterLanguage panics.
+func RegisterLanguage(code string, l Language) {
+	code = strings.ToLower(code)
+
+	languages.mu.Lock()
+	defer languages.mu.Unlock()
+
+	if code == "" {
+		panic("inspect.RegisterLanguage: language with an empty code")
+	}
+
+	if _, ok := languages.languages[code]; ok {
+		panic(fmt.Sprintf("inspect.RegisterLanguage: language %q registered twice", code))
+	}
+
+	languages.languages[code] = l
+}
+
+// lookupLanguage returns the language registered for the code or for its
+// primary subtag, "en-US" falls back to "en".
+func lookupLanguage(code string) (string, Language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	languages.mu.RLock()
+	defer languages.mu.RUnlock()
+
+	if l, ok := languages.languages[code]; ok {
+		return code, l, true
+	}
+
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		if l, ok := languages.languages[code[:i]]; ok {
+			return code[:i], l, true
+		}
+	}
+
+	return "", Language{}, false
+}
+
+// Flesch returns a Flesch reading ease formula, base minus the weighted
+// averages of words per sentence and syllables per word.
+func Flesch(base, sentenceWeight, syllableWeight float64) func(wordsPerSentence, syllablesPerWord float64) float64 {
+	return func(wordsPerSentence, syllablesPerWord float64) float64 {
+		return base - sentenceWeight*wordsPerSentence - syllableWeight*syllablesPerWord
+	}
+}
+
+// VowelGroups returns a syllable counter counting the groups of consecutive
+// vowels, every word has at least one syllable.
+func VowelGroups(vowels string) func(word string) int {
+	return func(word string) int {
+		count, previous := 0, false
+
+		for _, r := range word {
+			vowel := strings.ContainsRune(vowels, r)
+			if vowel && !previous {
+				count++
+			}
+
+			previous = vowel
+		}
+
+		if count == 0 {
+			return 1
+		}
+
+		return count
+	}
+}
+
+// englishSyllables counts the vowel groups of the word without the
+// silent endings like in "made", "jumped" or "makes".
+func englishSyllables(word string) int {
+	if utf8.RuneCountInString(word) <= 3 {
+		return 1
+	}
+
+	word = reSilentEnding.ReplaceAllString(word, "")
+	word = strings.TrimPrefix(word, "y")
+
+	if count := len(reEnglishVowel.FindAllString(word, -1)); count > 0 {
+		return count
+	}
+
+	return 1
+}
+
+// TextStats are the statistics of the visible text of a page.
+type TextStats struct {
+	// Language the syllables and the readability were computed for,
+	// empty if the language is not registered.
+	Language string
+
+	Words     int
+	Sentences int
+	Syllables int
+
+	WordsPerSentence float64
+	SyllablesPerWord float64
+
+	// Length in bytes of the visible text with collapsed whitespace,
+	// relative to PageContents.Size it is the text to HTML ratio.
+	TextLength int
+
+	// Readability of the text, nil if not defined for the language.
+	ReadingEase *float64
+	Grade       *float64
+}
+
+// TextStatistics computes the statistics of the visible text of the page,
+// without the <head>, scripts, styles, templates and hidden elements. Every
+// block element ends a sentence, sentences are also ended by ".", "!", "?" or
+// "…" followed by whitespace. The language is the code of a registered language,
+// the lang of the <html> element if empty.
+func TextStatistics(root *html.Node, language string) *TextStats {
+	if language == "" {
+		if nodes, err := Select(root, "html[lang]"); err == nil && len(nodes) > 0 {
+			language, _ = getAttribute(nodes[0], "lang")
+		}
+	}
+
+	code, lang, ok := lookupLanguage(language)
+
+	skip := func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			return true
+		}
+
+		return n.Type == html.ElementNode && (invisibleElements[strings.ToLower(n.Data)] || isHidden(n))
+	}
+
+	var text blockText
+
+	text.write(root, skip)
+	text.flush()
+
+	out := &TextStats{Language: code}
+
+	length := 0
+
+	for _, p := range text.paragraphs {
+		length += len(p)
+
+		for _, sentence := range reSentenceEnd.Split(p, -1) {
+			words := reWord.FindAllString(sentence, -1)
+			if len(words) == 0 {
+				continue
+			}
+
+			out.Sentences++
+			out.Words += len(words)
+
+			if ok {
+				for _, w := range words {
+					out.Syllables += lang.Syllables(strings.ToLower(w))
+				}
+			}
+		}
+	}
+
+	// the paragraphs are separated by a space or a newline.
+	if len(text.paragraphs) > 1 {
+		length += len(text.paragraphs) - 1
+	}
+
+	out.TextLength = length
+
+	if out.Sentences == 0 {
+		return out
+	}
+
+	out.WordsPerSentence = float64(out.Words) / float64(out.Sentences)
+
+	if !ok {
+		return out
+	}
+
+	out.SyllablesPerWord = float64(out.Syllables) / float64(out.Words)
+
+	if lang.ReadingEase != nil {
+		ease := lang.ReadingEase(out.WordsPerSentence, out.SyllablesPerWord)
+		out.ReadingEase = &ease
+	}
+
+	if lang.Grade != nil {
+		grade := lang.Grade(out.WordsPerSentence, out.SyllablesPerWord)
+		out.Grade = &grade
+	}
+
+	return out
+}
+
+// TextStatsExtractor reports the statistics of the visible text of the page.
+// Like the Extraction it needs the DOM built by Page.
+type TextStatsExtractor struct {
+	language string
+	stats    *TextStats
+}
+
+// NewTextStatsExtractor creates an extractor of the text statistics in the
+// language, the lang of the page if empty.
+func NewTextStatsExtractor(language string) (*TextStatsExtractor, error) {
+	if language != "" {
+		if _, _, ok := lookupLanguage(language); !ok {
+			return nil, fmt.Errorf("inspect.NewTextStatsExtractor: unsupported language %q", language)
+		}
+	}
+
+	return &TextStatsExtractor{language: language}, nil
+}
+
+func (e *TextStatsExtractor) Name() string { return TextStatsSection }
+
+func (e *TextStatsExtractor) Visit(node *html.Node) error {
+	if node.Type == html.DocumentNode {
+		e.stats = TextStatistics(node, e.language)
+	}
+
+	return nil
+}
+
+func (e *TextStatsExtractor) Result() interface{} { return e.stats }
@@ -0,0 +1,166 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/net/html"
+)
+
+func TestTextStatistics(t *testing.T) {
+	float := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		Name     string
+		Page     string
+		Language string
+		want     *TextStats
+	}{
+		{
+			Name: "ok-english",
+			Page: `<html lang="en-US"><head><title>Not visible</title><style>p { color: red; }</style></head><body>
+				<h1>The cat</h1>
+				<p>The cat sat on the mat. It was happy!</p>
+				<script>var hidden = "not counted.";</script>
+				<template><p>Not rendered either.</p></template>
+				<div hidden>Hidden words here.</div>
+				<p style="display: none">More hidden words.</p>
+				<!-- a comment. -->
+			</body></html>`,
+			want: &TextStats{
+				Language:         "en",
+				Words:            11,
+				Sentences:        3,
+				Syllables:        12,
+				WordsPerSentence: 11.0 / 3,
+				SyllablesPerWord: 12.0 / 11,
+				ReadingEase:      float(206.835 - 1.015*11/3 - 84.6*12/11),
+				Grade:            float(0.39*11/3 + 11.8*12/11 - 15.59),
+			},
+		},
+		{
+			Name:     "ok-language-option",
+			Page:     `<html lang="en"><body><p>Der Hund läuft schnell.</p></body></html>`,
+			Language: "de",
+			want: &TextStats{
+				Language:         "de",
+				Words:            4,
+				Sentences:        1,
+				Syllables:        4,
+				WordsPerSentence: 4,
+				SyllablesPerWord: 1,
+				ReadingEase:      float(180 - 4 - 58.5),
+			},
+		},
+		{
+			Name: "ok-unsupported-language",
+			Page: `<html lang="ja"><body><p>Hello there. General Kenobi.</p></body></html>`,
+			want: &TextStats{
+				Words:            4,
+				Sentences:        2,
+				WordsPerSentence: 2,
+			},
+		},
+		{
+			Name: "ok-no-text",
+			Page: `<html><body><script>x()</script></body></html>`,
+			want: &TextStats{Language: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.Page))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			have := TextStatistics(root, tt.Language)
+
+			if have.Words > 0 && (have.TextLength <= 0 || have.TextLength >= len(tt.Page)) {
+				t.Errorf("TextStatistics() text length = %v", have.TextLength)
+			}
+
+			if diff := cmp.Diff(have, tt.want, cmpopts.IgnoreFields(TextStats{}, "TextLength"), cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTextLength(t *testing.T) {
+	const page = `<p>abc</p><p>de</p>`
+
+	contents, err := Page(strings.NewReader(page), &TextStatsExtractor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contents.Size != int64(len(page)) {
+		t.Errorf("Page() size = %v, want %v", contents.Size, len(page))
+	}
+
+	// the paragraphs are separated by a single byte.
+	if have := contents.Sections[TextStatsSection].(*TextStats).TextLength; have != 6 {
+		t.Errorf("TextStatistics() text length = %v, want 6", have)
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	_, english, _ := lookupLanguage("en")
+
+	tests := []struct {
+		Name      string
+		Syllables func(string) int
+		Word      string
+		want      int
+	}{
+		{Name: "ok-english-short", Syllables: english.Syllables, Word: "the", want: 1},
+		{Name: "ok-english-silent-e", Syllables: english.Syllables, Word: "made", want: 1},
+		{Name: "ok-english-le", Syllables: english.Syllables, Word: "table", want: 2},
+		{Name: "ok-english-ed", Syllables: english.Syllables, Word: "jumped", want: 1},
+		{Name: "ok-english-ted", Syllables: english.Syllables, Word: "started", want: 2},
+		{Name: "ok-english-long", Syllables: english.Syllables, Word: "readability", want: 5},
+		{Name: "ok-english-number", Syllables: english.Syllables, Word: "2021", want: 1},
+		{Name: "ok-vowel-groups", Syllables: VowelGroups("aeiouäöü"), Word: "schnell", want: 1},
+		{Name: "ok-vowel-groups-diphthong", Syllables: VowelGroups("aeiouäöü"), Word: "läuft", want: 1},
+		{Name: "ok-vowel-groups-cyrillic", Syllables: VowelGroups("аеёиоуыэюя"), Word: "молоко", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := tt.Syllables(tt.Word); have != tt.want {
+				t.Errorf("Syllables(%q) = %v, want %v", tt.Word, have, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupLanguage(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Code   string
+		want   string
+		wantOk bool
+	}{
+		{Name: "ok", Code: "de", want: "de", wantOk: true},
+		{Name: "ok-region", Code: "en-GB", want: "en", wantOk: true},
+		{Name: "ok-underscore", Code: " FR_ca ", want: "fr", wantOk: true},
+		{Name: "fail-unknown", Code: "ja"},
+		{Name: "fail-empty", Code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have, _, ok := lookupLanguage(tt.Code)
+			if have != tt.want || ok != tt.wantOk {
+				t.Errorf("lookupLanguage(%q) = %q, %v, want %q, %v", tt.Code, have, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+// maxTLSProbes is the maximum number of hosts probed concurrently by ProbeTLSHosts.
+const maxTLSProbes = 8
+
+// Days before the expiry of a certificate from which it is reported.
+const (
+	certExpiryWarning  = 30
+	certExpiryCritical = 7
+)
+
+// TLSInfo contains the details of a TLS connection to a host.
+type TLSInfo struct {
+	Host string
+
+	// Negotiated protocol version and cipher suite.
+	Version     string
+	CipherSuite string
+
+	// Certificate chain as presented by the server, leaf first.
+	Certificates []Certificate
+
+	// Subject alternative names of the leaf certificate.
+	SANs []string
+
+	// Days until the leaf certificate expires, negative if expired.
+	ExpiresInDays int
+
+	// If the leaf certificate is valid for the host.
+	HostnameMatch bool
+
+	Findings []Finding
+
+	// Reason the connection could not be established.
+	Error string
+}
+
+// Certificate describes a certificate of the presented chain.
+type Certificate struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// TLS extracts the details of an established TLS connection to host.
+// The chain is verified against roots, if nil the system roots are used.
+func TLS(host string, state *tls.ConnectionState, roots *x509.CertPool) *TLSInfo {
+	out := &TLSInfo{
+		Host:        host,
+		Version:     tlsVersion(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	if state.Version < tls.VersionTLS12 {
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "tls-version", fmt.Sprintf("weak protocol %v negotiated", out.Version)})
+	}
+
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == state.CipherSuite {
+			out.Findings = append(out.Findings, Finding{SeverityMedium, "cipher-suite", fmt.Sprintf("insecure cipher suite %v negotiated", out.CipherSuite)})
+		}
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "certificate", "server presented no certificate"})
+		return out
+	}
+
+	for _, cert := range state.PeerCertificates {
+		out.Certificates = append(out.Certificates, Certificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+	}
+
+	leaf := state.PeerCertificates[0]
+
+	out.SANs = append(out.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		out.SANs = append(out.SANs, ip.String())
+	}
+
+	out.ExpiresInDays = expiresInDays(leaf.NotAfter, time.Now())
+	out.HostnameMatch = leaf.VerifyHostname(host) == nil
+
+	switch {
+	case time.Now().After(leaf.NotAfter):
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "certificate", fmt.Sprintf("certificate expired on %v", leaf.NotAfter.Format("2006-01-02"))})
+	case out.ExpiresInDays < certExpiryCritical:
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "certificate", fmt.Sprintf("certificate expires in %v days", out.ExpiresInDays)})
+	case out.ExpiresInDays < certExpiryWarning:
+		out.Findings = append(out.Findings, Finding{SeverityMedium, "certificate", fmt.Sprintf("certificate expires in %v days", out.ExpiresInDays)})
+	}
+
+	if !out.HostnameMatch {
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "certificate", fmt.Sprintf("certificate is not valid for %v", host)})
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+		out.Findings = append(out.Findings, Finding{SeverityHigh, "certificate", fmt.Sprintf("certificate chain is not trusted: %v", err)})
+	}
+
+	return out
+}
+
+// expiresInDays returns the number of whole days until notAfter, negative
+// once the certificate expired.
+func expiresInDays(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// ProbeTLS connects to the address and extracts the details of the TLS connection.
+// If the address has no port, 443 is used. The connection is established even if
+// the certificate can't be verified so that the issues can be reported. The timeout
+// covers both the connection and the handshake.
+func ProbeTLS(ctx context.Context, addr string, roots *x509.CertPool, timeout time.Duration) (*TLSInfo, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "443"
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true, // the chain is verified by TLS to report the issues.
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("inspect.ProbeTLS: failed to connect to %v: %w", addr, err)
+	}
+
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+
+	return TLS(host, &state, roots), nil
+}
+
+// ProbeTLSHosts concurrently probes every host with ProbeTLS, at most
+// maxTLSProbes at a time. Hosts that could not be probed are reported
+// with an Error. The result is sorted by host.
+func ProbeTLSHosts(ctx context.Context, hosts []string, roots *x509.CertPool, timeout time.Duration) []TLSInfo {
+	out := make([]TLSInfo, len(hosts))
+
+	forEach(len(hosts), maxTLSProbes, func(i int) {
+		info, err := ProbeTLS(ctx, hosts[i], roots, timeout)
+		if err != nil {
+			out[i] = TLSInfo{Host: hosts[i], Error: err.Error()}
+			return
+		}
+
+		info.Host = hosts[i]
+		out[i] = *info
+	})
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+
+	return out
+}
+
+func tlsVersion(version uint16) string {
+	switch version {
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionSSL30: //nolint:staticcheck // reported as a weak protocol.
+		return "SSL 3.0"
+	}
+
+	return fmt.Sprintf("0x%04x", version)
+}
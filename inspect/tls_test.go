@@ -0,0 +1,172 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestTLS(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer mockServer.Close()
+
+	resp, err := mockServer.Client().Get(mockServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(mockServer.Certificate())
+
+	certificates := []Certificate{
+		{
+			Subject:   mockServer.Certificate().Subject.String(),
+			Issuer:    mockServer.Certificate().Issuer.String(),
+			NotBefore: mockServer.Certificate().NotBefore,
+			NotAfter:  mockServer.Certificate().NotAfter,
+		},
+	}
+
+	// SANs of the httptest certificate.
+	sans := append([]string{}, mockServer.Certificate().DNSNames...)
+	for _, ip := range mockServer.Certificate().IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	tests := []struct {
+		Name  string
+		host  string
+		roots *x509.CertPool
+		want  *TLSInfo
+	}{
+		{
+			Name:  "ok",
+			host:  "127.0.0.1",
+			roots: trusted,
+			want: &TLSInfo{
+				Host:          "127.0.0.1",
+				Version:       "TLS 1.3",
+				CipherSuite:   tls.CipherSuiteName(resp.TLS.CipherSuite),
+				Certificates:  certificates,
+				SANs:          sans,
+				HostnameMatch: true,
+			},
+		},
+		{
+			Name:  "ok-hostname-mismatch",
+			host:  "www.foobar.com",
+			roots: trusted,
+			want: &TLSInfo{
+				Host:         "www.foobar.com",
+				Version:      "TLS 1.3",
+				CipherSuite:  tls.CipherSuiteName(resp.TLS.CipherSuite),
+				Certificates: certificates,
+				SANs:         sans,
+				Findings: []Finding{
+					{SeverityHigh, "certificate", "certificate is not valid for www.foobar.com"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			have := TLS(tt.host, resp.TLS, tt.roots)
+
+			if diff := cmp.Diff(have, tt.want, cmpopts.IgnoreFields(TLSInfo{}, "ExpiresInDays")); diff != "" {
+				t.Error(diff)
+				return
+			}
+
+			if have.ExpiresInDays <= certExpiryWarning {
+				t.Errorf("TLS() ExpiresInDays = %v, want > %v", have.ExpiresInDays, certExpiryWarning)
+			}
+		})
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer mockServer.Close()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(mockServer.Certificate())
+
+	addr := strings.TrimPrefix(mockServer.URL, "https://")
+
+	t.Run("ok", func(t *testing.T) {
+		have, err := ProbeTLS(context.Background(), addr, trusted, time.Second)
+		if err != nil {
+			t.Fatalf("ProbeTLS() err = %v", err)
+		}
+
+		if have.Host != "127.0.0.1" || !have.HostnameMatch || len(have.Findings) != 0 {
+			t.Errorf("ProbeTLS() = %+v, want trusted connection to 127.0.0.1", have)
+		}
+	})
+
+	t.Run("ok-untrusted", func(t *testing.T) {
+		have, err := ProbeTLS(context.Background(), addr, x509.NewCertPool(), time.Second)
+		if err != nil {
+			t.Fatalf("ProbeTLS() err = %v", err)
+		}
+
+		if len(have.Findings) != 1 || !strings.HasPrefix(have.Findings[0].Message, "certificate chain is not trusted") {
+			t.Errorf("ProbeTLS() findings = %v, want untrusted chain", have.Findings)
+		}
+	})
+
+	t.Run("ok-hosts", func(t *testing.T) {
+		have := ProbeTLSHosts(context.Background(), []string{addr, "127.0.0.1:1"}, trusted, time.Second)
+
+		if len(have) != 2 || have[0].Host != "127.0.0.1:1" || have[0].Error == "" || have[1].Host != addr || have[1].Error != "" {
+			t.Errorf("ProbeTLSHosts() = %+v, want one failed and one probed host", have)
+		}
+	})
+
+	t.Run("fail-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := ProbeTLS(ctx, addr, trusted, time.Second); !errors.Is(err, context.Canceled) {
+			t.Errorf("ProbeTLS() err = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestExpiresInDays(t *testing.T) {
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name     string
+		NotAfter time.Time
+		want     int
+	}{
+		{Name: "ok-days-left", NotAfter: now.Add(36 * time.Hour), want: 1},
+		{Name: "ok-less-than-a-day", NotAfter: now.Add(time.Hour), want: 0},
+		{Name: "ok-expired-today", NotAfter: now.Add(-time.Hour), want: -1},
+		{Name: "ok-expired", NotAfter: now.Add(-36 * time.Hour), want: -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if have := expiresInDays(tt.NotAfter, now); have != tt.want {
+				t.Errorf("expiresInDays() = %v, want %v", have, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,743 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// reXPathNumber matches the string representation of a number.
+var reXPathNumber = regexp.MustCompile(`^-?(\d+(\.\d*)?|\.\d+)$`)
+
+// XPath is a compiled XPath 1.0 expression. All axes except the namespace axis
+// and the core function library are supported, variable references are not.
+// Element and attribute names are matched case-insensitively.
+type XPath struct {
+	expr xpathExpr
+}
+
+// XPathNode is a node selected by an XPath expression. Attribute nodes have
+// no *html.Node of their own, they are the Attr of the element Node.
+type XPathNode struct {
+	Node *html.Node
+	Attr *html.Attribute
+}
+
+// String returns the string-value of the node.
+func (n XPathNode) String() string {
+	if n.Attr != nil {
+		return n.Attr.Val
+	}
+
+	switch n.Node.Type {
+	case html.TextNode, html.CommentNode:
+		return n.Node.Data
+	}
+
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(n.Node)
+
+	return b.String()
+}
+
+// CompileXPath parses an XPath 1.0 expression.
+func CompileXPath(expr string) (*XPath, error) {
+	tokens, err := lexXPath(expr)
+	if err != nil {
+		return nil, fmt.Errorf("inspect.CompileXPath: invalid expression %q: %w", expr, err)
+	}
+
+	p := &xpathParser{tokens: tokens}
+
+	e, err := p.parseExpr()
+	if err == nil && p.i < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.i].value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("inspect.CompileXPath: invalid expression %q: %w", expr, err)
+	}
+
+	return &XPath{expr: e}, nil
+}
+
+// Evaluate evaluates the expression with node as the context node. The result
+// is a []XPathNode in document order, a string, a float64 or a bool.
+func (x *XPath) Evaluate(node *html.Node) (interface{}, error) {
+	return x.evaluate(node, newXPathDocument(node))
+}
+
+// evaluate evaluates the expression in the document, so that the document
+// order built while sorting is shared by evaluations in the same document.
+func (x *XPath) evaluate(node *html.Node, doc *xpathDocument) (interface{}, error) {
+	v, err := x.expr.eval(xpathContext{
+		node:     XPathNode{Node: node},
+		position: 1,
+		size:     1,
+		doc:      doc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inspect.XPath.Evaluate: %w", err)
+	}
+
+	return v, nil
+}
+
+// Select returns the nodes selected by the expression in document order.
+func (x *XPath) Select(node *html.Node) ([]XPathNode, error) {
+	return x.selectNodes(node, newXPathDocument(node))
+}
+
+// selectNodes returns the nodes selected by the expression in the document.
+func (x *XPath) selectNodes(node *html.Node, doc *xpathDocument) ([]XPathNode, error) {
+	v, err := x.evaluate(node, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, ok := v.([]XPathNode)
+	if !ok {
+		return nil, fmt.Errorf("inspect.XPath.Select: expression evaluated to %v, not a node-set", xpathString(v))
+	}
+
+	return nodes, nil
+}
+
+// xpathDocument is the document an expression is evaluated in.
+type xpathDocument struct {
+	root *html.Node
+
+	// document order of the nodes, built on first use.
+	order map[XPathNode]int
+}
+
+// newXPathDocument returns the document the node belongs to.
+func newXPathDocument(node *html.Node) *xpathDocument {
+	root := node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return &xpathDocument{root: root}
+}
+
+// sort sorts the nodes in document order.
+func (d *xpathDocument) sort(nodes []XPathNode) {
+	if d.order == nil {
+		d.order = make(map[XPathNode]int)
+
+		var walk func(n *html.Node)
+		walk = func(n *html.Node) {
+			d.order[XPathNode{Node: n}] = len(d.order)
+
+			for i := range n.Attr {
+				d.order[XPathNode{Node: n, Attr: &n.Attr[i]}] = len(d.order)
+			}
+
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+
+		walk(d.root)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool { return d.order[nodes[i]] < d.order[nodes[j]] })
+}
+
+// xpathContext is the evaluation context of an expression.
+type xpathContext struct {
+	node     XPathNode
+	position int
+	size     int
+	doc      *xpathDocument
+}
+
+// xpathExpr is a parsed expression.
+type xpathExpr interface {
+	eval(c xpathContext) (interface{}, error)
+}
+
+type (
+	// literalExpr is a string or number literal.
+	literalExpr struct{ value interface{} }
+
+	// binaryExpr is a logical, comparison or arithmetic operation.
+	binaryExpr struct {
+		op          string
+		left, right xpathExpr
+	}
+
+	// negateExpr is the unary minus.
+	negateExpr struct{ expr xpathExpr }
+
+	// unionExpr merges two node-sets.
+	unionExpr struct{ left, right xpathExpr }
+
+	// filterExpr filters the node-set of a primary expression by predicates.
+	filterExpr struct {
+		primary    xpathExpr
+		predicates []xpathExpr
+	}
+
+	// pathExpr applies the steps to the node-set of the filter expression,
+	// the root node if absolute or the context node.
+	pathExpr struct {
+		filter   xpathExpr
+		absolute bool
+		steps    []*xpathStep
+	}
+
+	// xpathStep is a location step.
+	xpathStep struct {
+		axis       string
+		test       nodeTest
+		predicates []xpathExpr
+	}
+
+	// functionExpr is a function call.
+	functionExpr struct {
+		fn   xpathFunction
+		args []xpathExpr
+	}
+)
+
+// nodeTest is the node test of a location step, either a name test
+// or one of the node type tests "node", "text", "comment" and
+// "processing-instruction".
+type nodeTest struct {
+	name     string
+	nodeType string
+}
+
+func (e *literalExpr) eval(c xpathContext) (interface{}, error) { return e.value, nil }
+
+func (e *binaryExpr) eval(c xpathContext) (interface{}, error) {
+	left, err := e.left.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// the right operand is not evaluated if the result is known.
+	switch e.op {
+	case "or":
+		if xpathBool(left) {
+			return true, nil
+		}
+	case "and":
+		if !xpathBool(left) {
+			return false, nil
+		}
+	}
+
+	right, err := e.right.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "or", "and":
+		return xpathBool(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compareXPath(e.op, left, right), nil
+	}
+
+	l, r := xpathNumber(left), xpathNumber(right)
+
+	switch e.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "div":
+		return l / r, nil
+	case "mod":
+		return math.Mod(l, r), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %q", e.op)
+}
+
+func (e *negateExpr) eval(c xpathContext) (interface{}, error) {
+	v, err := e.expr.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return -xpathNumber(v), nil
+}
+
+func (e *unionExpr) eval(c xpathContext) (interface{}, error) {
+	var out []XPathNode
+
+	seen := make(map[XPathNode]struct{})
+
+	for _, expr := range []xpathExpr{e.left, e.right} {
+		v, err := expr.eval(c)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, ok := v.([]XPathNode)
+		if !ok {
+			return nil, fmt.Errorf("operand of | is not a node-set")
+		}
+
+		for _, n := range nodes {
+			if _, ok := seen[n]; !ok {
+				seen[n] = struct{}{}
+				out = append(out, n)
+			}
+		}
+	}
+
+	c.doc.sort(out)
+
+	return out, nil
+}
+
+func (e *filterExpr) eval(c xpathContext) (interface{}, error) {
+	v, err := e.primary.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, ok := v.([]XPathNode)
+	if !ok {
+		return nil, fmt.Errorf("predicate applied to a value that is not a node-set")
+	}
+
+	for _, p := range e.predicates {
+		if nodes, err = filterNodes(c, nodes, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+func (e *pathExpr) eval(c xpathContext) (interface{}, error) {
+	var nodes []XPathNode
+
+	switch {
+	case e.filter != nil:
+		v, err := e.filter.eval(c)
+		if err != nil {
+			return nil, err
+		}
+
+		ns, ok := v.([]XPathNode)
+		if !ok {
+			return nil, fmt.Errorf("location path applied to a value that is not a node-set")
+		}
+
+		nodes = ns
+	case e.absolute:
+		nodes = []XPathNode{{Node: c.doc.root}}
+	default:
+		nodes = []XPathNode{c.node}
+	}
+
+	for _, s := range e.steps {
+		var err error
+		if nodes, err = s.eval(c, nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+// eval applies the step to every node of the input.
+func (s *xpathStep) eval(c xpathContext, input []XPathNode) ([]XPathNode, error) {
+	var (
+		out  []XPathNode
+		seen = make(map[XPathNode]struct{})
+	)
+
+	for _, n := range input {
+		var selected []XPathNode
+
+		for _, candidate := range axisNodes(s.axis, n) {
+			if s.test.match(candidate, s.axis) {
+				selected = append(selected, candidate)
+			}
+		}
+
+		for _, p := range s.predicates {
+			var err error
+			if selected, err = filterNodes(c, selected, p); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, n := range selected {
+			if _, ok := seen[n]; !ok {
+				seen[n] = struct{}{}
+				out = append(out, n)
+			}
+		}
+	}
+
+	switch {
+	case len(input) > 1:
+		c.doc.sort(out)
+	case reverseAxes[s.axis]:
+		// the nodes of a single axis only need to be reversed.
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+
+	return out, nil
+}
+
+// reverseAxes are the axes whose nodes are in reverse document order.
+var reverseAxes = map[string]bool{
+	"ancestor":          true,
+	"ancestor-or-self":  true,
+	"parent":            true,
+	"preceding":         true,
+	"preceding-sibling": true,
+}
+
+// filterNodes keeps the nodes satisfying the predicate, a number is
+// compared with the position of the node in the given order.
+func filterNodes(c xpathContext, nodes []XPathNode, predicate xpathExpr) ([]XPathNode, error) {
+	var out []XPathNode
+
+	for i, n := range nodes {
+		v, err := predicate.eval(xpathContext{node: n, position: i + 1, size: len(nodes), doc: c.doc})
+		if err != nil {
+			return nil, err
+		}
+
+		keep := false
+		if f, ok := v.(float64); ok {
+			keep = f == float64(i+1)
+		} else {
+			keep = xpathBool(v)
+		}
+
+		if keep {
+			out = append(out, n)
+		}
+	}
+
+	return out, nil
+}
+
+// match checks if the node passes the node test, name tests only match
+// the principal node type of the axis.
+func (t nodeTest) match(n XPathNode, axis string) bool {
+	switch t.nodeType {
+	case "node":
+		return true
+	case "text":
+		return n.Attr == nil && n.Node.Type == html.TextNode
+	case "comment":
+		return n.Attr == nil && n.Node.Type == html.CommentNode
+	case "processing-instruction":
+		return false
+	}
+
+	if axis == "attribute" {
+		return n.Attr != nil && (t.name == "*" || strings.EqualFold(n.Attr.Key, t.name))
+	}
+
+	return n.Attr == nil && n.Node.Type == html.ElementNode && (t.name == "*" || strings.EqualFold(n.Node.Data, t.name))
+}
+
+// axisNodes returns the nodes of the axis from the node, the reverse
+// axes in reverse document order.
+func axisNodes(axis string, n XPathNode) []XPathNode {
+	var out []XPathNode
+
+	add := func(node *html.Node) {
+		if node.Type != html.DoctypeNode {
+			out = append(out, XPathNode{Node: node})
+		}
+	}
+
+	var descendants func(node *html.Node)
+	descendants = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			add(c)
+			descendants(c)
+		}
+	}
+
+	// the parent of an attribute is its element.
+	parent := n.Node.Parent
+	if n.Attr != nil {
+		parent = n.Node
+	}
+
+	switch axis {
+	case "self":
+		out = append(out, n)
+	case "child":
+		if n.Attr == nil {
+			for c := n.Node.FirstChild; c != nil; c = c.NextSibling {
+				add(c)
+			}
+		}
+	case "descendant", "descendant-or-self":
+		if axis == "descendant-or-self" {
+			out = append(out, n)
+		}
+
+		if n.Attr == nil {
+			descendants(n.Node)
+		}
+	case "parent":
+		if parent != nil {
+			add(parent)
+		}
+	case "ancestor", "ancestor-or-self":
+		if axis == "ancestor-or-self" {
+			out = append(out, n)
+		}
+
+		for a := parent; a != nil; a = a.Parent {
+			add(a)
+		}
+	case "attribute":
+		if n.Attr == nil {
+			for i := range n.Node.Attr {
+				out = append(out, XPathNode{Node: n.Node, Attr: &n.Node.Attr[i]})
+			}
+		}
+	case "following-sibling":
+		if n.Attr == nil {
+			for s := n.Node.NextSibling; s != nil; s = s.NextSibling {
+				add(s)
+			}
+		}
+	case "preceding-sibling":
+		if n.Attr == nil {
+			for s := n.Node.PrevSibling; s != nil; s = s.PrevSibling {
+				add(s)
+			}
+		}
+	case "following":
+		if n.Attr != nil {
+			// the descendants of the element follow its attributes.
+			descendants(n.Node)
+		}
+
+		for a := n.Node; a != nil; a = a.Parent {
+			for s := a.NextSibling; s != nil; s = s.NextSibling {
+				add(s)
+				descendants(s)
+			}
+		}
+	case "preceding":
+		// collected in document order and reversed, without the ancestors.
+		var ancestors []*html.Node
+		for a := n.Node; a != nil; a = a.Parent {
+			ancestors = append(ancestors, a)
+		}
+
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			var siblings []*html.Node
+			for s := ancestors[i].PrevSibling; s != nil; s = s.PrevSibling {
+				siblings = append(siblings, s)
+			}
+
+			for j := len(siblings) - 1; j >= 0; j-- {
+				add(siblings[j])
+				descendants(siblings[j])
+			}
+		}
+
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+
+	return out
+}
+
+// compareXPath compares two values, node-sets are compared by the
+// string-values of their nodes and match if any of the nodes does.
+func compareXPath(op string, left, right interface{}) bool {
+	ln, lok := left.([]XPathNode)
+	rn, rok := right.([]XPathNode)
+
+	switch {
+	case lok && rok:
+		for _, l := range ln {
+			for _, r := range rn {
+				if compareAtomic(op, l.String(), r.String()) {
+					return true
+				}
+			}
+		}
+
+		return false
+	case lok:
+		if b, ok := right.(bool); ok {
+			return compareAtomic(op, len(ln) > 0, b)
+		}
+
+		for _, l := range ln {
+			if compareAtomic(op, l.String(), right) {
+				return true
+			}
+		}
+
+		return false
+	case rok:
+		if b, ok := left.(bool); ok {
+			return compareAtomic(op, b, len(rn) > 0)
+		}
+
+		for _, r := range rn {
+			if compareAtomic(op, left, r.String()) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return compareAtomic(op, left, right)
+}
+
+// compareAtomic compares two values that are not node-sets.
+func compareAtomic(op string, left, right interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+
+		_, lb := left.(bool)
+		_, rb := right.(bool)
+		_, lf := left.(float64)
+		_, rf := right.(float64)
+
+		switch {
+		case lb || rb:
+			equal = xpathBool(left) == xpathBool(right)
+		case lf || rf:
+			equal = xpathNumber(left) == xpathNumber(right)
+		default:
+			equal = xpathString(left) == xpathString(right)
+		}
+
+		return equal == (op == "=")
+	}
+
+	l, r := xpathNumber(left), xpathNumber(right)
+
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+
+	return false
+}
+
+// xpathString converts a value to a string.
+func xpathString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == 0:
+			return "0"
+		}
+
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []XPathNode:
+		if len(v) == 0 {
+			return ""
+		}
+
+		return v[0].String()
+	}
+
+	return ""
+}
+
+// xpathNumber converts a value to a number.
+func xpathNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+
+		return 0
+	case string:
+		s := strings.TrimSpace(v)
+		if !reXPathNumber.MatchString(s) {
+			return math.NaN()
+		}
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return math.NaN()
+		}
+
+		return f
+	case []XPathNode:
+		return xpathNumber(xpathString(v))
+	}
+
+	return math.NaN()
+}
+
+// xpathBool converts a value to a boolean.
+func xpathBool(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []XPathNode:
+		return len(v) > 0
+	}
+
+	return false
+}
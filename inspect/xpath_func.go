@@ -0,0 +1,287 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// xpathFunction is a function of the core function library, maxArgs
+// is -1 for functions with any number of arguments.
+type xpathFunction struct {
+	minArgs int
+	maxArgs int
+	call    func(c xpathContext, args []interface{}) (interface{}, error)
+}
+
+// xpathFunctions is the core function library.
+var xpathFunctions = map[string]xpathFunction{
+	// node-set functions.
+	"last":          {0, 0, func(c xpathContext, args []interface{}) (interface{}, error) { return float64(c.size), nil }},
+	"position":      {0, 0, func(c xpathContext, args []interface{}) (interface{}, error) { return float64(c.position), nil }},
+	"count":         {1, 1, xpathCount},
+	"id":            {1, 1, xpathID},
+	"local-name":    {0, 1, xpathName},
+	"name":          {0, 1, xpathName},
+	"namespace-uri": {0, 1, func(c xpathContext, args []interface{}) (interface{}, error) { return "", nil }},
+
+	// string functions.
+	"string": {0, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return xpathString(contextArg(c, args)), nil
+	}},
+	"concat": {2, -1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(xpathString(arg))
+		}
+
+		return b.String(), nil
+	}},
+	"starts-with": {2, 2, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(xpathString(args[0]), xpathString(args[1])), nil
+	}},
+	"contains": {2, 2, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return strings.Contains(xpathString(args[0]), xpathString(args[1])), nil
+	}},
+	"substring-before": {2, 2, func(c xpathContext, args []interface{}) (interface{}, error) {
+		s, sep := xpathString(args[0]), xpathString(args[1])
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[:i], nil
+		}
+
+		return "", nil
+	}},
+	"substring-after": {2, 2, func(c xpathContext, args []interface{}) (interface{}, error) {
+		s, sep := xpathString(args[0]), xpathString(args[1])
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[i+len(sep):], nil
+		}
+
+		return "", nil
+	}},
+	"substring": {2, 3, xpathSubstring},
+	"string-length": {0, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return float64(utf8.RuneCountInString(xpathString(contextArg(c, args)))), nil
+	}},
+	"normalize-space": {0, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return strings.Join(strings.Fields(xpathString(contextArg(c, args))), " "), nil
+	}},
+	"translate": {3, 3, xpathTranslate},
+
+	// boolean functions.
+	"boolean": {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) { return xpathBool(args[0]), nil }},
+	"not":     {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) { return !xpathBool(args[0]), nil }},
+	"true":    {0, 0, func(c xpathContext, args []interface{}) (interface{}, error) { return true, nil }},
+	"false":   {0, 0, func(c xpathContext, args []interface{}) (interface{}, error) { return false, nil }},
+	"lang":    {1, 1, xpathLang},
+
+	// number functions.
+	"number": {0, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return xpathNumber(contextArg(c, args)), nil
+	}},
+	"sum": {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		nodes, ok := args[0].([]XPathNode)
+		if !ok {
+			return nil, errors.New("argument of sum() is not a node-set")
+		}
+
+		sum := 0.0
+		for _, n := range nodes {
+			sum += xpathNumber(n.String())
+		}
+
+		return sum, nil
+	}},
+	"floor": {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return math.Floor(xpathNumber(args[0])), nil
+	}},
+	"ceiling": {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return math.Ceil(xpathNumber(args[0])), nil
+	}},
+	"round": {1, 1, func(c xpathContext, args []interface{}) (interface{}, error) {
+		return xpathRound(xpathNumber(args[0])), nil
+	}},
+}
+
+func (e *functionExpr) eval(c xpathContext) (interface{}, error) {
+	args := make([]interface{}, 0, len(e.args))
+
+	for _, arg := range e.args {
+		v, err := arg.eval(c)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, v)
+	}
+
+	return e.fn.call(c, args)
+}
+
+// contextArg returns the optional argument, the context node if omitted.
+func contextArg(c xpathContext, args []interface{}) interface{} {
+	if len(args) == 0 {
+		return []XPathNode{c.node}
+	}
+
+	return args[0]
+}
+
+func xpathCount(c xpathContext, args []interface{}) (interface{}, error) {
+	nodes, ok := args[0].([]XPathNode)
+	if !ok {
+		return nil, errors.New("argument of count() is not a node-set")
+	}
+
+	return float64(len(nodes)), nil
+}
+
+// xpathID selects the elements by their id, the ids are the whitespace
+// separated tokens of the argument or of the string-values of its nodes.
+func xpathID(c xpathContext, args []interface{}) (interface{}, error) {
+	var ids []string
+
+	if nodes, ok := args[0].([]XPathNode); ok {
+		for _, n := range nodes {
+			ids = append(ids, strings.Fields(n.String())...)
+		}
+	} else {
+		ids = strings.Fields(xpathString(args[0]))
+	}
+
+	wanted := make(map[string]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var out []XPathNode
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if id, ok := getAttribute(n, "id"); ok && wanted[id] {
+				out = append(out, XPathNode{Node: n})
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(c.doc.root)
+
+	return out, nil
+}
+
+// xpathName returns the name of the first node of the argument, of the
+// context node if omitted. Names have no prefixes in HTML documents.
+func xpathName(c xpathContext, args []interface{}) (interface{}, error) {
+	nodes, ok := contextArg(c, args).([]XPathNode)
+	if !ok {
+		return nil, errors.New("argument of name() is not a node-set")
+	}
+
+	switch {
+	case len(nodes) == 0:
+		return "", nil
+	case nodes[0].Attr != nil:
+		return nodes[0].Attr.Key, nil
+	case nodes[0].Node.Type == html.ElementNode:
+		return nodes[0].Node.Data, nil
+	}
+
+	return "", nil
+}
+
+// xpathSubstring returns the characters from the rounded start position
+// up to the rounded length, the first character is at position 1.
+func xpathSubstring(c xpathContext, args []interface{}) (interface{}, error) {
+	s := []rune(xpathString(args[0]))
+
+	start := xpathRound(xpathNumber(args[1]))
+	end := math.Inf(1)
+
+	if len(args) == 3 {
+		end = start + xpathRound(xpathNumber(args[2]))
+	}
+
+	var b strings.Builder
+
+	for i, r := range s {
+		// comparisons with NaN are false, no characters are selected.
+		if p := float64(i + 1); p >= start && p < end {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// xpathTranslate replaces the characters of the second argument in the first
+// one with the characters at the same position in the third argument,
+// characters without a replacement are removed.
+func xpathTranslate(c xpathContext, args []interface{}) (interface{}, error) {
+	from, to := []rune(xpathString(args[1])), []rune(xpathString(args[2]))
+
+	replace := make(map[rune]rune)
+	for i, r := range from {
+		if _, ok := replace[r]; ok {
+			continue
+		}
+
+		replace[r] = -1
+		if i < len(to) {
+			replace[r] = to[i]
+		}
+	}
+
+	var b strings.Builder
+
+	for _, r := range xpathString(args[0]) {
+		to, ok := replace[r]
+
+		switch {
+		case !ok:
+			b.WriteRune(r)
+		case to >= 0:
+			b.WriteRune(to)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// xpathLang checks if the language of the context node given by the nearest
+// lang attribute is the argument or its sublanguage.
+func xpathLang(c xpathContext, args []interface{}) (interface{}, error) {
+	lang := xpathString(args[0])
+
+	for n := c.node.Node; n != nil; n = n.Parent {
+		if v, ok := getAttribute(n, "lang"); ok {
+			return strings.EqualFold(v, lang) || strings.HasPrefix(strings.ToLower(v), strings.ToLower(lang)+"-"), nil
+		}
+	}
+
+	return false, nil
+}
+
+// xpathRound rounds to the closest integer, halves towards positive infinity.
+func xpathRound(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+
+	// -0.5 <= f < 0 rounds to negative zero.
+	if f < 0 && f >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+
+	return math.Floor(f + 0.5)
+}
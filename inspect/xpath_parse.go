@@ -0,0 +1,560 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// xpathTokenKind is the kind of a lexical token of an expression.
+type xpathTokenKind int
+
+const (
+	tokenOperator xpathTokenKind = iota
+	tokenName
+	tokenLiteral
+	tokenNumber
+)
+
+type xpathToken struct {
+	kind   xpathTokenKind
+	value  string
+	number float64
+}
+
+// xpathOperators are the operator tokens, the longer ones first.
+var xpathOperators = []string{
+	"//", "::", "..", "!=", "<=", ">=",
+	"/", "|", "+", "-", "=", "<", ">", "(", ")", "[", "]", ".", "@", ",", "*", "$",
+}
+
+// xpathAxes are the supported axes.
+var xpathAxes = map[string]bool{
+	"ancestor":           true,
+	"ancestor-or-self":   true,
+	"attribute":          true,
+	"child":              true,
+	"descendant":         true,
+	"descendant-or-self": true,
+	"following":          true,
+	"following-sibling":  true,
+	"parent":             true,
+	"preceding":          true,
+	"preceding-sibling":  true,
+	"self":               true,
+}
+
+// xpathNodeTypes are the node type tests.
+var xpathNodeTypes = map[string]bool{
+	"node":                   true,
+	"text":                   true,
+	"comment":                true,
+	"processing-instruction": true,
+}
+
+// lexXPath splits an expression into tokens. Whether a name or a "*" is an
+// operator is decided by the parser.
+func lexXPath(expr string) ([]xpathToken, error) {
+	var (
+		tokens []xpathToken
+		s      = []rune(expr)
+	)
+
+	isNameStart := func(r rune) bool { return r == '_' || unicode.IsLetter(r) }
+	isName := func(r rune) bool {
+		return isNameStart(r) || unicode.IsDigit(r) || r == '-' || r == '.'
+	}
+
+outer:
+	for i := 0; i < len(s); {
+		r := s[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(s) && unicode.IsDigit(s[i+1])):
+			start := i
+			for i < len(s) && unicode.IsDigit(s[i]) {
+				i++
+			}
+
+			if i < len(s) && s[i] == '.' {
+				i++
+				for i < len(s) && unicode.IsDigit(s[i]) {
+					i++
+				}
+			}
+
+			f, err := strconv.ParseFloat(string(s[start:i]), 64)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, xpathToken{kind: tokenNumber, value: string(s[start:i]), number: f})
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(string(s[i+1:]), r)
+			if end < 0 {
+				return nil, errors.New("unterminated string literal")
+			}
+
+			value := string(s[i+1:])[:end]
+			tokens = append(tokens, xpathToken{kind: tokenLiteral, value: value})
+			i += 2 + len([]rune(value))
+		case isNameStart(r):
+			start := i
+			for i < len(s) && isName(s[i]) {
+				i++
+			}
+
+			// a qualified name or a prefix wildcard, but not an axis.
+			if i+1 < len(s) && s[i] == ':' && s[i+1] != ':' {
+				switch {
+				case s[i+1] == '*':
+					i += 2
+				case isNameStart(s[i+1]):
+					i++
+					for i < len(s) && isName(s[i]) {
+						i++
+					}
+				}
+			}
+
+			tokens = append(tokens, xpathToken{kind: tokenName, value: string(s[start:i])})
+		default:
+			for _, op := range xpathOperators {
+				if strings.HasPrefix(string(s[i:]), op) {
+					tokens = append(tokens, xpathToken{kind: tokenOperator, value: op})
+					i += len(op)
+					continue outer
+				}
+			}
+
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+// xpathParser is a recursive descent parser of the XPath 1.0 grammar.
+type xpathParser struct {
+	tokens []xpathToken
+	i      int
+}
+
+// peek returns the token at the offset from the current one.
+func (p *xpathParser) peek(offset int) (xpathToken, bool) {
+	if p.i+offset >= len(p.tokens) {
+		return xpathToken{}, false
+	}
+
+	return p.tokens[p.i+offset], true
+}
+
+// isOperator checks if the current token is one of the operators.
+func (p *xpathParser) isOperator(ops ...string) bool {
+	t, ok := p.peek(0)
+	if !ok || t.kind != tokenOperator {
+		return false
+	}
+
+	for _, op := range ops {
+		if t.value == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOperatorName checks if the current token is one of the operator names.
+func (p *xpathParser) isOperatorName(names ...string) bool {
+	t, ok := p.peek(0)
+	if !ok || t.kind != tokenName {
+		return false
+	}
+
+	for _, name := range names {
+		if t.value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expect consumes the operator or fails.
+func (p *xpathParser) expect(op string) error {
+	if !p.isOperator(op) {
+		if t, ok := p.peek(0); ok {
+			return fmt.Errorf("expected %q, got %q", op, t.value)
+		}
+
+		return fmt.Errorf("expected %q at the end", op)
+	}
+
+	p.i++
+
+	return nil
+}
+
+func (p *xpathParser) parseExpr() (xpathExpr, error) {
+	return p.parseBinary(0)
+}
+
+// xpathPrecedence are the binary operators from the lowest precedence.
+var xpathPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+// parseBinary parses the left associative operators of the level.
+func (p *xpathParser) parseBinary(level int) (xpathExpr, error) {
+	if level == len(xpathPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ops := xpathPrecedence[level]
+		if !p.isOperator(ops...) && !p.isOperatorName(ops...) {
+			return left, nil
+		}
+
+		op := p.tokens[p.i].value
+		p.i++
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.isOperator("-") {
+		p.i++
+
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &negateExpr{expr: e}, nil
+	}
+
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("|") {
+		p.i++
+
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &unionExpr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parsePath parses a location path or a filter expression
+// optionally followed by a relative location path.
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	t, ok := p.peek(0)
+	if !ok {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	if p.isOperator("/", "//") {
+		path := &pathExpr{absolute: true}
+
+		if p.isOperator("/") {
+			p.i++
+
+			// the root node alone.
+			if !p.startsStep() {
+				return path, nil
+			}
+		}
+
+		steps, err := p.parseRelativePath()
+		if err != nil {
+			return nil, err
+		}
+
+		path.steps = steps
+
+		return path, nil
+	}
+
+	if p.startsStep() {
+		steps, err := p.parseRelativePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &pathExpr{steps: steps}, nil
+	}
+
+	var primary xpathExpr
+
+	switch {
+	case t.kind == tokenLiteral:
+		p.i++
+		primary = &literalExpr{value: t.value}
+	case t.kind == tokenNumber:
+		p.i++
+		primary = &literalExpr{value: t.number}
+	case t.kind == tokenName:
+		e, err := p.parseFunction()
+		if err != nil {
+			return nil, err
+		}
+
+		primary = e
+	case p.isOperator("("):
+		p.i++
+
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		primary = e
+	case p.isOperator("$"):
+		return nil, errors.New("variable references are not supported")
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.value)
+	}
+
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(predicates) > 0 {
+		primary = &filterExpr{primary: primary, predicates: predicates}
+	}
+
+	if !p.isOperator("/", "//") {
+		return primary, nil
+	}
+
+	path := &pathExpr{filter: primary}
+
+	if p.isOperator("/") {
+		p.i++
+	}
+
+	steps, err := p.parseRelativePath()
+	if err != nil {
+		return nil, err
+	}
+
+	path.steps = steps
+
+	return path, nil
+}
+
+// startsStep checks if the current token starts a location step,
+// a name followed by "(" is a function call unless it's a node type.
+func (p *xpathParser) startsStep() bool {
+	if p.isOperator(".", "..", "@", "*") {
+		return true
+	}
+
+	t, ok := p.peek(0)
+	if !ok || t.kind != tokenName {
+		return false
+	}
+
+	if next, ok := p.peek(1); ok && next.kind == tokenOperator && next.value == "(" {
+		return xpathNodeTypes[t.value]
+	}
+
+	return true
+}
+
+// parseRelativePath parses the steps of a relative location path, a
+// leading "//" is expanded to the descendant-or-self::node() step.
+func (p *xpathParser) parseRelativePath() ([]*xpathStep, error) {
+	var steps []*xpathStep
+
+	for {
+		if p.isOperator("//") {
+			p.i++
+			steps = append(steps, &xpathStep{axis: "descendant-or-self", test: nodeTest{nodeType: "node"}})
+		}
+
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, step)
+
+		switch {
+		case p.isOperator("/"):
+			p.i++
+		case p.isOperator("//"):
+		default:
+			return steps, nil
+		}
+	}
+}
+
+func (p *xpathParser) parseStep() (*xpathStep, error) {
+	switch {
+	case p.isOperator("."):
+		p.i++
+		return &xpathStep{axis: "self", test: nodeTest{nodeType: "node"}}, nil
+	case p.isOperator(".."):
+		p.i++
+		return &xpathStep{axis: "parent", test: nodeTest{nodeType: "node"}}, nil
+	}
+
+	step := &xpathStep{axis: "child"}
+
+	if p.isOperator("@") {
+		p.i++
+		step.axis = "attribute"
+	} else if next, ok := p.peek(1); ok && next.kind == tokenOperator && next.value == "::" {
+		axis := p.tokens[p.i].value
+		if !xpathAxes[axis] {
+			return nil, fmt.Errorf("unsupported axis %q", axis)
+		}
+
+		step.axis = axis
+		p.i += 2
+	}
+
+	t, ok := p.peek(0)
+
+	switch {
+	case !ok:
+		return nil, errors.New("expected a node test at the end")
+	case p.isOperator("*"):
+		p.i++
+		step.test = nodeTest{name: "*"}
+	case t.kind == tokenName:
+		p.i++
+
+		if !p.isOperator("(") {
+			step.test = nodeTest{name: t.value}
+			break
+		}
+
+		if !xpathNodeTypes[t.value] {
+			return nil, fmt.Errorf("unexpected function %q in a location step", t.value)
+		}
+
+		p.i++
+
+		// the optional literal of processing-instruction() is ignored.
+		if next, ok := p.peek(0); ok && t.value == "processing-instruction" && next.kind == tokenLiteral {
+			p.i++
+		}
+
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		step.test = nodeTest{nodeType: t.value}
+	default:
+		return nil, fmt.Errorf("expected a node test, got %q", t.value)
+	}
+
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+
+	step.predicates = predicates
+
+	return step, nil
+}
+
+func (p *xpathParser) parsePredicates() ([]xpathExpr, error) {
+	var predicates []xpathExpr
+
+	for p.isOperator("[") {
+		p.i++
+
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+
+		predicates = append(predicates, e)
+	}
+
+	return predicates, nil
+}
+
+func (p *xpathParser) parseFunction() (xpathExpr, error) {
+	name := p.tokens[p.i].value
+
+	fn, ok := xpathFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	p.i++
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var args []xpathExpr
+
+	for !p.isOperator(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+
+	p.i++
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s()", name)
+	}
+
+	return &functionExpr{fn: fn, args: args}, nil
+}
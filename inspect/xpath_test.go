@@ -0,0 +1,269 @@
+// Copyright 2021 Matus Mrekaj. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+package inspect
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func TestXPathSelect(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(selectorPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name    string
+		Expr    string
+		want    []string
+		wantErr bool
+	}{
+		{Name: "ok-child-path", Expr: "/html/body/ul/li", want: []string{"li1", "li2", "li3", "li4"}},
+		{Name: "ok-descendant", Expr: "//span[@class='name']", want: []string{"p1-name", "p2-name", "p3-name"}},
+		{Name: "ok-name-case", Expr: "//UL", want: []string{"menu"}},
+		{Name: "ok-wildcard", Expr: "//ul/*", want: []string{"li1", "li2", "li3", "li4"}},
+		{Name: "ok-position", Expr: "//li[2]", want: []string{"li2"}},
+		{Name: "ok-last", Expr: "//li[last()]", want: []string{"li4"}},
+		{Name: "ok-position-function", Expr: "//li[position() > 1 and position() < last()]", want: []string{"li2", "li3"}},
+		{Name: "ok-position-per-parent", Expr: "//li/a[1]", want: []string{"a1", "a2", "a3"}},
+		{Name: "ok-position-of-set", Expr: "(//li/a)[1]", want: []string{"a1"}},
+		{Name: "ok-predicates", Expr: "//div[@data-sku][2]", want: []string{"p2"}},
+		{Name: "ok-nested-predicate", Expr: "//div[span[contains(., 'EUR')]]", want: []string{"p1", "p2"}},
+		{Name: "ok-starts-with", Expr: "//a[starts-with(@href, 'https://')]", want: []string{"a2"}},
+		{Name: "ok-not", Expr: "//li[not(a)]", want: []string{"li4"}},
+		{Name: "ok-attribute", Expr: "//a/@href", want: []string{"@href=/home", "@href=https://www.example.com/shop", "@href=/about.html"}},
+		{Name: "ok-attribute-wildcard", Expr: "//a[@rel]/@*", want: []string{"@id=a2", "@href=https://www.example.com/shop", "@rel=external"}},
+		{Name: "ok-attribute-axis", Expr: "//div[attribute::lang]", want: []string{"p2"}},
+		{Name: "ok-parent", Expr: "//span[@id='p2-price']/..", want: []string{"p2"}},
+		{Name: "ok-parent-of-attribute", Expr: "//@rel/..", want: []string{"a2"}},
+		{Name: "ok-self", Expr: "//div/self::*[@id='p1']", want: []string{"p1"}},
+		{Name: "ok-ancestor", Expr: "//a[@id='a1']/ancestor::*", want: []string{"html", "body", "menu", "li1"}},
+		{Name: "ok-ancestor-reverse-position", Expr: "//a[@id='a1']/ancestor::*[1]", want: []string{"li1"}},
+		{Name: "ok-ancestor-or-self", Expr: "//a[@id='a1']/ancestor-or-self::li", want: []string{"li1"}},
+		{Name: "ok-following-sibling", Expr: "//li[@id='li2']/following-sibling::li", want: []string{"li3", "li4"}},
+		{Name: "ok-preceding-sibling", Expr: "//li[@id='li3']/preceding-sibling::li[1]", want: []string{"li2"}},
+		{Name: "ok-following", Expr: "//div[@id='p2']/following::span", want: []string{"p3-name"}},
+		{Name: "ok-preceding", Expr: "//p/preceding::span", want: []string{"p1-name", "p1-price", "p2-name", "p2-price"}},
+		{Name: "ok-preceding-reverse-position", Expr: "//p/preceding::span[1]", want: []string{"p2-price"}},
+		{Name: "ok-descendant-or-self", Expr: "//div[@id='p1']/descendant-or-self::*", want: []string{"p1", "p1-name", "p1-price"}},
+		{Name: "ok-text", Expr: "//a/text()", want: []string{"text()=Home", "text()=Shop", "text()=About"}},
+		{Name: "ok-union", Expr: "//p | //h2", want: []string{"title", "note"}},
+		{Name: "ok-id", Expr: "id('li4 note')", want: []string{"note", "li4"}},
+		{Name: "ok-lang", Expr: "//span[lang('en')]", want: []string{"p2-name", "p2-price"}},
+		{Name: "ok-equality-node-sets", Expr: "//span[. = //a]"},
+		{Name: "ok-equality-number", Expr: "//li[count(a) = 0]", want: []string{"li4"}},
+		{Name: "ok-relative-to-filter", Expr: "id('menu')//a[. != 'Home']", want: []string{"a2", "a3"}},
+		{Name: "ok-operator-names", Expr: "//div[@data-sku and string-length(@data-sku) div 3 = 1]", want: []string{"p1", "p2", "p3"}},
+		{Name: "ok-root", Expr: "/", want: []string{"#document"}},
+		{Name: "ok-empty", Expr: "//table"},
+		{Name: "fail-not-node-set", Expr: "count(//li)", wantErr: true},
+		{Name: "fail-path-on-string", Expr: "'a'/b", wantErr: true},
+		{Name: "fail-count-of-string", Expr: "//li[count('a')]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			x, err := CompileXPath(tt.Expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			nodes, err := x.Select(root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() err = %v, want %v", err, tt.wantErr)
+			}
+
+			var have []string
+			for _, n := range nodes {
+				have = append(have, describeXPathNode(n))
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// describeXPathNode identifies elements by their id, attributes by their key and
+// value and text nodes by their text.
+func describeXPathNode(n XPathNode) string {
+	switch {
+	case n.Attr != nil:
+		return "@" + n.Attr.Key + "=" + n.Attr.Val
+	case n.Node.Type == html.TextNode:
+		return "text()=" + n.Node.Data
+	case n.Node.Type == html.DocumentNode:
+		return "#document"
+	}
+
+	id, _ := getAttribute(n.Node, "id")
+
+	return id
+}
+
+func TestXPathEvaluate(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(selectorPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name string
+		Expr string
+		want interface{}
+	}{
+		{Name: "ok-count", Expr: "count(//li)", want: 4.0},
+		{Name: "ok-arithmetic", Expr: "1 + 2 * 3 - -4 div 2", want: 9.0},
+		{Name: "ok-mod", Expr: "-5 mod 3", want: -2.0},
+		{Name: "ok-string", Expr: "string(//h2)", want: "Products"},
+		{Name: "ok-string-of-empty", Expr: "string(//table)", want: ""},
+		{Name: "ok-string-of-number", Expr: "string(1 div 0)", want: "Infinity"},
+		{Name: "ok-string-of-integer", Expr: "string(2.0)", want: "2"},
+		{Name: "ok-string-of-fraction", Expr: "string(0.5)", want: "0.5"},
+		{Name: "ok-concat", Expr: "concat(//h2, ': ', count(//span[@class='name']))", want: "Products: 3"},
+		{Name: "ok-substring", Expr: "substring('12345', 1.5, 2.6)", want: "234"},
+		{Name: "ok-substring-open", Expr: "substring('12345', 0)", want: "12345"},
+		{Name: "ok-substring-nan", Expr: "substring('12345', 0 div 0, 3)", want: ""},
+		{Name: "ok-substring-before", Expr: "substring-before(//span[@id='p1-price'], ' ')", want: "10"},
+		{Name: "ok-substring-after", Expr: "substring-after((//a)[2]/@href, '://')", want: "www.example.com/shop"},
+		{Name: "ok-normalize-space", Expr: "normalize-space('  a \n b ')", want: "a b"},
+		{Name: "ok-translate", Expr: "translate('bar', 'abc', 'AB')", want: "BAr"},
+		{Name: "ok-string-length", Expr: "string-length('čaj')", want: 3.0},
+		{Name: "ok-name", Expr: "name(//*[@rel])", want: "a"},
+		{Name: "ok-local-name-attribute", Expr: "local-name(//a/@rel)", want: "rel"},
+		{Name: "ok-number", Expr: "number(substring-before(//span[@class='price sale'], ' '))", want: 20.0},
+		{Name: "ok-number-invalid", Expr: "number('1e3') = number('1e3')", want: false},
+		{Name: "ok-sum", Expr: "sum(//li/@id[. = 'x'])", want: 0.0},
+		{Name: "ok-rounding", Expr: "concat(floor(-1.5), ceiling(-1.5), round(-1.5), round(2.5))", want: "-2-1-13"},
+		{Name: "ok-boolean", Expr: "boolean(//li) and not(//table) or false()", want: true},
+		{Name: "ok-comparison-string-number", Expr: "'10' = 10.0", want: true},
+		{Name: "ok-comparison-boolean", Expr: "//table = false()", want: true},
+		{Name: "ok-relational-node-set", Expr: "//li/@id > 'a'", want: false},
+		{Name: "ok-relational-numbers", Expr: "count(//li) >= 4 and 1 < 2 and 2 <= 2", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			x, err := CompileXPath(tt.Expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			have, err := x.Evaluate(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestCompileXPath(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Expr    string
+		wantErr bool
+	}{
+		{Name: "ok-abbreviations", Expr: ".//a/../@href"},
+		{Name: "ok-axes", Expr: "child::div/descendant::node()/following-sibling::comment()"},
+		{Name: "ok-processing-instruction", Expr: "//processing-instruction('x')"},
+		{Name: "ok-element-named-like-operator", Expr: "//div div //mod"},
+		{Name: "ok-number-start", Expr: ".5 + 1."},
+		{Name: "fail-empty", Expr: "", wantErr: true},
+		{Name: "fail-unknown-function", Expr: "matches(., 'a')", wantErr: true},
+		{Name: "fail-arguments", Expr: "contains('a')", wantErr: true},
+		{Name: "fail-axis", Expr: "namespace::*", wantErr: true},
+		{Name: "fail-variable", Expr: "$x", wantErr: true},
+		{Name: "fail-unclosed-predicate", Expr: "//a[1", wantErr: true},
+		{Name: "fail-unterminated-string", Expr: "//a[@href='x]", wantErr: true},
+		{Name: "fail-trailing-slash", Expr: "//a/", wantErr: true},
+		{Name: "fail-trailing-operator", Expr: "1 +", wantErr: true},
+		{Name: "fail-unexpected", Expr: "//a)", wantErr: true},
+		{Name: "fail-character", Expr: "//a#b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if _, err := CompileXPath(tt.Expr); (err != nil) != tt.wantErr {
+				t.Errorf("CompileXPath() err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestXPathRound(t *testing.T) {
+	if have := xpathRound(-0.5); have != 0 || !math.Signbit(have) {
+		t.Errorf("xpathRound(-0.5) = %v, want -0", have)
+	}
+
+	if have := xpathRound(math.NaN()); !math.IsNaN(have) {
+		t.Errorf("xpathRound(NaN) = %v, want NaN", have)
+	}
+}
+
+func TestXPathSingleContextOrder(t *testing.T) {
+	root, err := html.Parse(strings.NewReader(selectorPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name    string
+		Context string
+		Expr    string
+		want    []string
+	}{
+		{Name: "ok-child", Context: "#products", Expr: "div", want: []string{"p1", "p2", "p3"}},
+		{Name: "ok-descendant", Context: "#p1", Expr: "descendant::span", want: []string{"p1-name", "p1-price"}},
+		{Name: "ok-ancestor", Context: "#a1", Expr: "ancestor::*", want: []string{"html", "body", "menu", "li1"}},
+		{Name: "ok-ancestor-position", Context: "#a1", Expr: "ancestor::*[2]", want: []string{"menu"}},
+		{Name: "ok-preceding-sibling", Context: "#li3", Expr: "preceding-sibling::li", want: []string{"li1", "li2"}},
+		{Name: "ok-preceding", Context: "#note", Expr: "preceding::span", want: []string{"p1-name", "p1-price", "p2-name", "p2-price"}},
+		{Name: "ok-following", Context: "#p2", Expr: "following::span", want: []string{"p3-name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			x, err := CompileXPath(tt.Expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			context, err := Select(root, tt.Context)
+			if err != nil || len(context) != 1 {
+				t.Fatalf("context %q not found", tt.Context)
+			}
+
+			doc := newXPathDocument(context[0])
+
+			v, err := x.evaluate(context[0], doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var have []string
+			for _, n := range v.([]XPathNode) {
+				have = append(have, describeXPathNode(n))
+			}
+
+			if diff := cmp.Diff(have, tt.want); diff != "" {
+				t.Error(diff)
+			}
+
+			// a single context node selects its axes in order without indexing the document.
+			if doc.order != nil {
+				t.Errorf("document order built for %q", tt.Expr)
+			}
+		})
+	}
+}